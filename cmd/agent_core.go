@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"aurora-agent/config"
+	"aurora-agent/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentCore implements the provider independent parts of AIAgent:
+// the conversation history, the tool loop and streaming output.
+// Provider agents embed it and supply a ChatProvider.
+type agentCore struct {
+	provider     ChatProvider
+	conversation *Conversation
+	// usageHandler receives the usage of every request, an error stops the tool loop
+	usageHandler func(model string, usage Usage) error
+}
+
+// newAgentCore creates the shared agent state for a provider
+func newAgentCore(provider ChatProvider) agentCore {
+	return agentCore{
+		provider:     provider,
+		conversation: NewConversation(config.GetSystemPrompt()),
+	}
+}
+
+// setConversation replaces the conversation history, the agent manager uses it
+// to share one conversation between all agents
+func (a *agentCore) setConversation(conversation *Conversation) {
+	a.conversation = conversation
+}
+
+// setUsageHandler sets the function that accounts the usage of every request
+func (a *agentCore) setUsageHandler(handler func(model string, usage Usage) error) {
+	a.usageHandler = handler
+}
+
+// recordUsage passes the usage of a request to the usage handler.
+// It must be called before the response is added to the conversation,
+// so the usage can be estimated when the provider didn't report it.
+func (a *agentCore) recordUsage(conversation *Conversation, tools []ToolDefinition, response ChatResponse) error {
+	usage := response.Usage
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		model := a.provider.Model()
+		usage = Usage{
+			InputTokens:  estimateConversationTokens(model, conversation, tools),
+			OutputTokens: estimateMessageTokens(model, response.Message),
+			Estimated:    true,
+		}
+	}
+
+	if a.usageHandler == nil {
+		return nil
+	}
+	return a.usageHandler(a.provider.Model(), usage)
+}
+
+// Query sends a prompt to the provider and returns the response
+func (a *agentCore) Query(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	a.conversation.AddUserMessage(redactForModel(prompt, "your message"))
+	a.manageContext(ctx, nil)
+
+	response, err := a.provider.Complete(ctx, a.conversation, nil)
+	if err != nil {
+		return "", a.interruptTurn(ctx, nil, response, err)
+	}
+	if err := a.recordUsage(a.conversation, nil, response); err != nil {
+		fmt.Printf("\033[33mWarning: %v\033[0m\n", err)
+	}
+
+	a.conversation.AddAssistantMessage(response.Message)
+
+	return response.Message.Content, nil
+}
+
+// StreamQuery sends a prompt to the provider and streams the response to the writer
+func (a *agentCore) StreamQuery(ctx context.Context, prompt string, writer io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	// Add user message to history
+	a.conversation.AddUserMessage(redactForModel(prompt, "your message"))
+	a.manageContext(ctx, nil)
+
+	printer := utils.NewAnsiStreamPrinter(writer)
+	response, err := a.provider.Stream(ctx, a.conversation, nil, printer.Print)
+	printer.Flush()
+	if err != nil {
+		return a.interruptTurn(ctx, nil, response, err)
+	}
+	if err := a.recordUsage(a.conversation, nil, response); err != nil {
+		fmt.Printf("\n\033[33mWarning: %v\033[0m\n", err)
+	}
+
+	// Add assistant response to history
+	a.conversation.AddAssistantMessage(response.Message)
+
+	return nil
+}
+
+// StreamQueryWithFunctionCalls sends a prompt to the provider, handles tool calls, and streams the response
+func (a *agentCore) StreamQueryWithFunctionCalls(ctx context.Context, prompt string) error {
+	// Add user message to history
+	a.conversation.AddUserMessage(redactForModel(prompt, "your message"))
+
+	tools := getToolDefinitions()
+
+	// Main processing loop - allows multiple commands in sequence
+	for {
+		// Tool outputs can fill the context window within a single turn
+		a.manageContext(ctx, tools)
+
+		// Each request has its own timeout, the tools have theirs
+		requestCtx, cancelRequest := context.WithTimeout(ctx, modelRequestTimeout)
+		printer := utils.NewAnsiStreamPrinter(os.Stdout)
+		response, err := a.provider.Stream(requestCtx, a.conversation, tools, printer.Print)
+		printer.Flush()
+		if err != nil {
+			err = a.interruptTurn(requestCtx, tools, response, err)
+			cancelRequest()
+			return err
+		}
+		cancelRequest()
+		budgetErr := a.recordUsage(a.conversation, tools, response)
+
+		// Add assistant response, including requested tool calls, to history
+		a.conversation.AddAssistantMessage(response.Message)
+
+		// If there are no tool calls the model is done
+		if len(response.Message.ToolCalls) == 0 {
+			// Add a newline at the end of the response for better readability
+			fmt.Print("\n")
+			if budgetErr != nil {
+				fmt.Printf("\033[33mWarning: %v\033[0m\n", budgetErr)
+			}
+			break
+		}
+
+		// Stop when the usage budget is used up, every tool call still needs a result
+		if budgetErr != nil {
+			a.conversation.AddToolResults(skippedToolResults(response.Message.ToolCalls, budgetErr.Error()))
+			return budgetErr
+		}
+
+		// Run the tools and send their results back in the next request
+		a.conversation.AddToolResults(a.executeToolCalls(ctx, response.Message.ToolCalls))
+
+		// Calls that were not run already have a result, the turn ends here
+		if ctx.Err() != nil {
+			fmt.Print("\n")
+			return turnError(ctx)
+		}
+	}
+
+	return nil
+}
+
+// interruptTurn ends a turn whose request failed. When the turn was cancelled or
+// timed out, the partial answer is kept in the history, marked as interrupted and
+// without tool calls, so the conversation stays well-formed and can continue.
+func (a *agentCore) interruptTurn(ctx context.Context, tools []ToolDefinition, partial ChatResponse, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+
+	_ = a.recordUsage(a.conversation, tools, partial)
+
+	content := strings.TrimSpace(partial.Message.Content)
+	if content != "" {
+		content += "\n\n"
+	}
+	a.conversation.AddAssistantMessage(Message{Content: content + interruptedNote})
+
+	fmt.Print("\n")
+	return turnError(ctx)
+}
+
+// modelRequestTimeout limits a single request to the model, tools run under their own timeouts
+const modelRequestTimeout = 120 * time.Second
+
+// interruptedNote marks answers and tool calls that were cut short
+const interruptedNote = "[interrupted by the user]"
+
+// ErrTurnCancelled is returned when the user cancels a turn with Ctrl+C
+var ErrTurnCancelled = errors.New("interrupted by the user")
+
+// turnError returns the error for a turn whose context ended
+func turnError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ErrTurnCancelled
+	}
+	return fmt.Errorf("the request took too long: %w", ctx.Err())
+}
+
+// skippedToolResults returns results for tool calls that were not run
+func skippedToolResults(toolCalls []ToolCall, reason string) []ToolResult {
+	results := make([]ToolResult, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		results[i] = ToolResult{
+			CallID:  toolCall.ID,
+			Name:    toolCall.Name,
+			Content: "Not run: " + reason,
+			IsError: true,
+		}
+	}
+	return results
+}
+
+// executeToolCalls runs the requested tools and returns one result per call, in call order.
+// Consecutive read-only calls run concurrently, other calls run one at a time so
+// their side effects happen in the order the model asked for.
+func (a *agentCore) executeToolCalls(ctx context.Context, toolCalls []ToolCall) []ToolResult {
+	results := make([]ToolResult, len(toolCalls))
+
+	for i := 0; i < len(toolCalls); {
+		// Find the batch of consecutive read-only calls starting at i
+		end := i
+		for end < len(toolCalls) && isReadOnlyTool(toolCalls[end].Name) {
+			end++
+		}
+
+		if end-i > 1 {
+			var wg sync.WaitGroup
+			for j := i; j < end; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					results[j] = executeToolCall(ctx, toolCalls[j])
+				}(j)
+			}
+			wg.Wait()
+			i = end
+			continue
+		}
+
+		results[i] = executeToolCall(ctx, toolCalls[i])
+		i++
+	}
+
+	return results
+}
+
+// executeToolCall runs a single tool and converts its outcome into a tool result
+func executeToolCall(ctx context.Context, toolCall ToolCall) ToolResult {
+	// Calls after a cancellation are not started
+	if ctx.Err() != nil {
+		return skippedToolResults([]ToolCall{toolCall}, ErrTurnCancelled.Error())[0]
+	}
+
+	// The model refers to hidden secrets by their placeholders, the tool gets the secrets
+	result, err := runToolFunction(ctx, toolCall.Name, secrets.restoreArguments(toolCall.Arguments))
+	if err != nil {
+		// Keep history well-formed: every tool call needs a result
+		return ToolResult{
+			CallID:  toolCall.ID,
+			Name:    toolCall.Name,
+			Content: redactForModel(err.Error(), "the error of "+toolCall.Name),
+			IsError: true,
+		}
+	}
+
+	resultJSON, _ := json.Marshal(capToolOutput(redactResult(result)))
+
+	return ToolResult{
+		CallID:  toolCall.ID,
+		Name:    toolCall.Name,
+		Content: string(resultJSON),
+		IsError: !result.Success,
+	}
+}
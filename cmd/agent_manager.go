@@ -1,28 +1,148 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"sync"
+	"time"
+
+	"aurora-agent/config"
 )
 
+// conversationHolder is implemented by agents whose history can be replaced
+type conversationHolder interface {
+	setConversation(conversation *Conversation)
+}
+
+// usageReporter is implemented by agents that report the usage of their requests
+type usageReporter interface {
+	setUsageHandler(handler func(model string, usage Usage) error)
+}
+
+// contextManager is implemented by agents that track the context window of their model
+type contextManager interface {
+	contextUsage() ContextUsage
+	compactContext(ctx context.Context, tools []ToolDefinition, force bool) (int, int)
+}
+
 // AgentManager manages different AI agents
 type AgentManager struct {
 	activeAgent AIAgent
+	activeType  AgentType
 	agents      map[AgentType]AIAgent
+	// session holds the conversation shared by all agents, so switching
+	// agents or models keeps the history
+	session *Session
+	// turnUsage adds up the usage of the current or last turn
+	turnUsage UsageTotals
+	// cancelTurn cancels the turn in progress, it is nil between turns
+	cancelMu   sync.Mutex
+	cancelTurn context.CancelFunc
 }
 
 // NewAgentManager creates a new agent manager
 func NewAgentManager() *AgentManager {
-	// Create a default OpenAI agent
-	openAIAgent := NewOpenAIAgent("")
+	m := &AgentManager{
+		activeType: OpenAI,
+		session:    NewSession(OpenAI),
+	}
+	m.createAgents()
+
+	return m
+}
 
+// createAgents creates the agents from the current configuration and attaches the session conversation
+func (m *AgentManager) createAgents() {
 	agents := make(map[AgentType]AIAgent)
-	agents[OpenAI] = openAIAgent
+	agents[OpenAI] = NewOpenAIAgent("")
+	agents[Claude] = NewClaudeAgent("")
+
+	m.agents = agents
+	m.activeAgent = agents[m.activeType]
+	m.attachConversation()
+}
+
+// attachConversation makes every agent use the conversation of the current session
+// and report its usage to the manager
+func (m *AgentManager) attachConversation() {
+	for _, agent := range m.agents {
+		m.attachAgent(agent)
+	}
+}
+
+// attachAgent connects a single agent to the session
+func (m *AgentManager) attachAgent(agent AIAgent) {
+	if holder, ok := agent.(conversationHolder); ok {
+		holder.setConversation(m.session.Conversation)
+	}
+	if reporter, ok := agent.(usageReporter); ok {
+		reporter.setUsageHandler(m.recordUsage)
+	}
+}
+
+// recordUsage accounts the usage of a request and reports whether the budget is used up
+func (m *AgentManager) recordUsage(model string, usage Usage) error {
+	record := newUsageRecord(m.session.ID, m.activeType, model, usage)
+	m.turnUsage.Add(record)
+	m.session.Usage.Add(record)
+	if err := appendUsageRecord(record); err != nil {
+		fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+	}
+
+	return checkUsageBudget(m.session)
+}
+
+// TurnUsage returns the usage of the current or last turn
+func (m *AgentManager) TurnUsage() UsageTotals {
+	return m.turnUsage
+}
+
+// startTurn resets the turn usage and refuses to start when the budget is used up.
+// It returns the context of the turn, which CancelTurn cancels, and a function
+// that must be called when the turn ends. Files changed during the turn are
+// saved in a checkpoint named after the prompt.
+func (m *AgentManager) startTurn(prompt string) (context.Context, func(), error) {
+	if err := checkUsageBudget(m.session); err != nil {
+		return nil, nil, err
+	}
+	m.turnUsage = UsageTotals{}
+	checkpoints.begin(m.session.ID, prompt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelMu.Lock()
+	m.cancelTurn = cancel
+	m.cancelMu.Unlock()
 
-	return &AgentManager{
-		activeAgent: openAIAgent,
-		agents:      agents,
+	end := func() {
+		m.cancelMu.Lock()
+		m.cancelTurn = nil
+		m.cancelMu.Unlock()
+		cancel()
+		checkpoints.finish()
+		m.saveSession()
 	}
+	return ctx, end, nil
+}
+
+// CancelTurn cancels the request and the tools of the turn in progress.
+// It reports whether a turn was running.
+func (m *AgentManager) CancelTurn() bool {
+	m.cancelMu.Lock()
+	defer m.cancelMu.Unlock()
+
+	if m.cancelTurn == nil {
+		return false
+	}
+	m.cancelTurn()
+	return true
+}
+
+// ReloadAgents recreates the agents after their configuration changed.
+// The active agent and the conversation are kept, the system prompt is refreshed.
+func (m *AgentManager) ReloadAgents() {
+	m.session.Conversation.SystemPrompt = config.GetSystemPrompt()
+	m.createAgents()
 }
 
 // SetActiveAgent sets the active AI agent
@@ -33,12 +153,69 @@ func (m *AgentManager) SetActiveAgent(agentType AgentType) error {
 	}
 
 	m.activeAgent = agent
+	m.activeType = agentType
+	m.session.Agent = agentType
 	return nil
 }
 
 // AddAgent adds a new AI agent
 func (m *AgentManager) AddAgent(agentType AgentType, agent AIAgent) {
 	m.agents[agentType] = agent
+	m.attachAgent(agent)
+}
+
+// Session returns the current conversation session
+func (m *AgentManager) Session() *Session {
+	return m.session
+}
+
+// SwitchSession makes all agents continue the given session
+func (m *AgentManager) SwitchSession(session *Session) {
+	session.Conversation.SystemPrompt = config.GetSystemPrompt()
+	m.session = session
+	m.session.Agent = m.activeType
+	m.attachConversation()
+}
+
+// ContextUsage returns the context usage of the conversation with the active agent's model
+func (m *AgentManager) ContextUsage() (ContextUsage, bool) {
+	agent, ok := m.activeAgent.(contextManager)
+	if !ok {
+		return ContextUsage{}, false
+	}
+	return agent.contextUsage(), true
+}
+
+// CompactContext truncates and summarizes the older turns of the conversation now
+func (m *AgentManager) CompactContext() error {
+	agent, ok := m.activeAgent.(contextManager)
+	if !ok {
+		return fmt.Errorf("agent %s doesn't support context compaction", m.GetActiveAgentName())
+	}
+
+	ctx, end, err := m.startTurn("")
+	if err != nil {
+		return err
+	}
+	defer end()
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	before, after := agent.compactContext(ctx, getToolDefinitions(), true)
+	if after >= before {
+		fmt.Println("Nothing to compact")
+	}
+	return nil
+}
+
+// saveSession stores the current session once it has messages
+func (m *AgentManager) saveSession() {
+	if len(m.session.Conversation.Messages) == 0 {
+		return
+	}
+	if err := m.session.Save(); err != nil {
+		fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+	}
 }
 
 // Query sends a prompt to the active AI agent
@@ -46,8 +223,13 @@ func (m *AgentManager) Query(prompt string) (string, error) {
 	if m.activeAgent == nil {
 		return "", fmt.Errorf("no active agent set")
 	}
+	ctx, end, err := m.startTurn(prompt)
+	if err != nil {
+		return "", err
+	}
+	defer end()
 
-	return m.activeAgent.Query(prompt)
+	return m.activeAgent.Query(ctx, prompt)
 }
 
 // GetActiveAgentName returns the name of the active agent
@@ -64,8 +246,13 @@ func (m *AgentManager) StreamQuery(prompt string, writer io.Writer) error {
 	if m.activeAgent == nil {
 		return fmt.Errorf("no active agent set")
 	}
+	ctx, end, err := m.startTurn(prompt)
+	if err != nil {
+		return err
+	}
+	defer end()
 
-	return m.activeAgent.StreamQuery(prompt, writer)
+	return m.activeAgent.StreamQuery(ctx, prompt, writer)
 }
 
 // StreamQueryWithFunctionCalls sends a prompt to the active AI agent, handles function calls, and streams the response
@@ -73,6 +260,11 @@ func (m *AgentManager) StreamQueryWithFunctionCalls(prompt string) error {
 	if m.activeAgent == nil {
 		return fmt.Errorf("no active agent set")
 	}
+	ctx, end, err := m.startTurn(prompt)
+	if err != nil {
+		return err
+	}
+	defer end()
 
-	return m.activeAgent.StreamQueryWithFunctionCalls(prompt)
+	return m.activeAgent.StreamQueryWithFunctionCalls(ctx, prompt)
 }
@@ -5,7 +5,21 @@ package cmd
 // The implementation has been split into multiple files for better organization:
 //
 // - types.go: Contains type definitions and interfaces
-// - openai_agent.go: Contains the OpenAI agent implementation
+// - conversation.go: Contains the provider independent conversation model
+// - chat_provider.go: Contains the interface implemented by provider adapters
+// - agent_core.go: Contains the shared agent loop, history and tool handling
+// - openai_agent.go: Contains the OpenAI provider adapter
+// - claude_agent.go: Contains the Claude (Anthropic) provider adapter
+// - tool_functions.go: Contains the tools available to every agent
+// - file_reader.go: Contains the native file reading used by the read_file tool
+// - file_search.go: Contains the list_dir, glob and search tools
+// - gitignore.go: Contains the .gitignore rules followed by the search tools
+// - file_writer.go: Contains the write_file and edit_file tools
+// - file_diff.go: Contains the unified diff shown before file changes
+// - checkpoint.go: Contains the snapshots of changed files used to undo a turn
 // - agent_manager.go: Contains the agent manager implementation
+// - session.go: Contains conversation sessions stored on disk
+// - context_manager.go: Contains token estimation and context compaction
+// - usage.go: Contains token usage and cost accounting
 //
-// This modular approach improves code readability and maintainability.
\ No newline at end of file
+// This modular approach improves code readability and maintainability.
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aurora-agent/config"
+)
+
+// Origins of audited commands: the user typed it, the AI ran it with the
+// user's decision, or the AI ran it and the policy decided without asking
+const (
+	auditOriginUser   = "user"
+	auditOriginAgent  = "agent"
+	auditOriginPolicy = "policy"
+)
+
+// Approval decisions of audited commands of the AI
+const (
+	auditApproved = "approved"
+	auditDenied   = "denied"
+)
+
+// auditRedacted replaces secrets in audited commands
+const auditRedacted = "[REDACTED]"
+
+// AuditRecord is an entry of the audit log
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	Origin  string    `json:"origin"`
+	Command string    `json:"command"`
+	WorkDir string    `json:"cwd,omitempty"`
+	// ExitCode is nil for commands that didn't run or still run in the background
+	ExitCode   *int  `json:"exit_code,omitempty"`
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// Approval is approved or denied for commands of the AI, Reason says by whom or why
+	Approval string `json:"approval,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// Elevated is set for commands run as root through sudo
+	Elevated   bool `json:"elevated,omitempty"`
+	Background bool `json:"background,omitempty"`
+}
+
+// newAuditRecord fills in the time, session and working directory of a record.
+// It is made before the command runs, so the working directory is the one the
+// command started in.
+func newAuditRecord(origin string, command string) AuditRecord {
+	record := AuditRecord{
+		Time:    time.Now(),
+		Origin:  origin,
+		Command: command,
+	}
+	if AgentMgr != nil && AgentMgr.Session() != nil {
+		record.Session = AgentMgr.Session().ID
+	}
+	if workDir, err := os.Getwd(); err == nil {
+		record.WorkDir = workDir
+	}
+	return record
+}
+
+// newAgentAuditRecord starts the record of a command of the AI from the decision
+// on it
+func newAgentAuditRecord(approval commandApproval, elevated bool) AuditRecord {
+	origin := auditOriginAgent
+	if !approval.ByUser {
+		origin = auditOriginPolicy
+	}
+	record := newAuditRecord(origin, approval.Command)
+	record.Approval = auditDenied
+	if approval.Approved {
+		record.Approval = auditApproved
+	}
+	record.Reason = approval.Reason
+	record.Elevated = elevated
+	return record
+}
+
+// finish sets the exit code and the duration of the command
+func (r *AuditRecord) finish(exitCode *int, duration time.Duration) {
+	r.ExitCode = exitCode
+	r.DurationMs = duration.Milliseconds()
+}
+
+// redactCommand replaces passwords, tokens and keys in a command line, the ones
+// the secret detectors find and the ones hidden from the model
+func redactCommand(command string) string {
+	return secrets.hide(command, auditRedacted)
+}
+
+// appendAuditRecord appends a record to the audit log with the secrets of its
+// command redacted. The log is only ever appended to and only the user can read it.
+func appendAuditRecord(record AuditRecord) error {
+	path := config.GetAuditLogPath()
+	if path == "" {
+		return fmt.Errorf("audit log not available")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	record.Command = redactCommand(record.Command)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// recordAudit appends a record to the audit log and warns when that fails, a
+// missing entry must not go unnoticed
+func recordAudit(record AuditRecord) {
+	if err := appendAuditRecord(record); err != nil {
+		fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+	}
+}
+
+// readAuditRecords reads the records of the audit log made since a time
+func readAuditRecords(since time.Time) ([]AuditRecord, error) {
+	file, err := os.Open(config.GetAuditLogPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	// Commands can be long, allow lines of up to 1 MB
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Skip damaged lines, e.g. from a write that was interrupted
+			continue
+		}
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
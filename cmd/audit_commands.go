@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditDefaultLimit is how many entries the audit command shows by default
+const auditDefaultLimit = 20
+
+// auditUsage describes the arguments of the audit command
+const auditUsage = "audit [since <time>] [origin <user|agent|policy>] [limit <number>] [pattern]"
+
+// auditQuery selects entries of the audit log
+type auditQuery struct {
+	since   time.Time
+	origin  string
+	pattern *regexp.Regexp
+	limit   int
+}
+
+// processAuditCommand handles the audit command
+func processAuditCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) == 0 || words[0] != "audit" {
+		return false
+	}
+
+	query, err := parseAuditQuery(words[1:])
+	if err != nil {
+		fmt.Printf("\033[31mError: %v. Use: %s\033[0m\n", err, auditUsage)
+		return true
+	}
+	showAuditLog(query)
+	return true
+}
+
+// parseAuditQuery parses the arguments of the audit command. Words that are not
+// part of an option form the pattern, a case-insensitive regular expression
+// matched against the command.
+func parseAuditQuery(args []string) (auditQuery, error) {
+	query := auditQuery{limit: auditDefaultLimit}
+	var pattern []string
+	for i := 0; i < len(args); i++ {
+		option := args[i]
+		if option != "since" && option != "origin" && option != "limit" {
+			pattern = append(pattern, option)
+			continue
+		}
+		if i+1 >= len(args) {
+			return query, fmt.Errorf("%s needs a value", option)
+		}
+		i++
+		value := args[i]
+
+		switch option {
+		case "since":
+			since, err := parseAuditTime(value)
+			if err != nil {
+				return query, err
+			}
+			query.since = since
+		case "origin":
+			if value != auditOriginUser && value != auditOriginAgent && value != auditOriginPolicy {
+				return query, fmt.Errorf("unknown origin %s", value)
+			}
+			query.origin = value
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return query, fmt.Errorf("invalid limit %s", value)
+			}
+			query.limit = n
+		}
+	}
+
+	if len(pattern) > 0 {
+		re, err := regexp.Compile("(?i)" + strings.Join(pattern, " "))
+		if err != nil {
+			return query, fmt.Errorf("invalid pattern: %v", err)
+		}
+		query.pattern = re
+	}
+	return query, nil
+}
+
+// parseAuditTime parses the start of an audit query: a time ago like 30m, 2h or
+// 7d, a date like 2006-01-02, or a time like 2006-01-02T15:04:05Z07:00
+func parseAuditTime(value string) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if ago, err := time.ParseDuration(value); err == nil && ago >= 0 {
+		return time.Now().Add(-ago), nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s, use e.g. 2h, 7d or 2006-01-02", value)
+}
+
+// showAuditLog displays the last entries of the audit log that match a query
+func showAuditLog(query auditQuery) {
+	records, err := readAuditRecords(query.since)
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	var matches []AuditRecord
+	for _, record := range records {
+		if query.origin != "" && record.Origin != query.origin {
+			continue
+		}
+		if query.pattern != nil && !query.pattern.MatchString(record.Command) {
+			continue
+		}
+		matches = append(matches, record)
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No audit entries found")
+		return
+	}
+	shown := matches
+	if len(shown) > query.limit {
+		shown = shown[len(shown)-query.limit:]
+	}
+
+	fmt.Printf("\n\033[1mAudit log (%d of %d entries):\033[0m\n", len(shown), len(matches))
+	for _, record := range shown {
+		root := ""
+		if record.Elevated {
+			root = " \033[31m[root]\033[0m"
+		}
+		fmt.Printf("  \033[90m%s\033[0m  %-6s  %s%s  %s\n",
+			record.Time.Local().Format("2006-01-02 15:04:05"), record.Origin, auditOutcome(record), root, record.Command)
+
+		details := []string{record.WorkDir}
+		if record.DurationMs > 0 {
+			details = append(details, (time.Duration(record.DurationMs) * time.Millisecond).String())
+		}
+		if record.Reason != "" {
+			details = append(details, record.Reason)
+		}
+		if record.Session != "" {
+			details = append(details, "session "+record.Session)
+		}
+		fmt.Printf("  \033[90m%s\033[0m\n", strings.Join(details, " · "))
+	}
+	fmt.Println()
+}
+
+// auditOutcome describes how an audited command ended
+func auditOutcome(record AuditRecord) string {
+	switch {
+	case record.Approval == auditDenied:
+		return "\033[31mdenied\033[0m "
+	case record.Background:
+		return "\033[33mbg\033[0m     "
+	case record.ExitCode == nil:
+		return "\033[90m-\033[0m      "
+	case *record.ExitCode == 0:
+		return "\033[32mexit 0\033[0m "
+	default:
+		return fmt.Sprintf("\033[31mexit %-2d\033[0m", *record.ExitCode)
+	}
+}
@@ -7,17 +7,24 @@
 // - display_config.go: Display configuration functions
 // - help_commands.go: Help system commands
 // - shell_command_utils.go: Shell command utilities
+// - policy_commands.go: Command approval policy commands
+// - session_commands.go: Conversation session commands
+// - usage_commands.go: Token usage and cost commands
+// - checkpoint_commands.go: Undo and checkpoint commands
+// - sudo_commands.go: Sudo password commands
+// - audit_commands.go: Audit log commands
 package cmd
 
 import (
+	"errors"
 	"fmt"
 )
 
-// Global agent manager instance
+// Global agent manager instance, created by InitAgentManager once configuration is loaded
 var AgentMgr *AgentManager
 
-func init() {
-	// Initialize the agent manager
+// InitAgentManager creates the global agent manager from the current configuration
+func InitAgentManager() {
 	AgentMgr = NewAgentManager()
 }
 
@@ -28,6 +35,36 @@ func ProcessAuroraCommand(input string) bool {
 		return true
 	}
 
+	// Check approval policy commands
+	if processPolicyCommand(input) {
+		return true
+	}
+
+	// Check conversation session commands
+	if processSessionCommand(input) {
+		return true
+	}
+
+	// Check token usage commands
+	if processUsageCommand(input) {
+		return true
+	}
+
+	// Check undo and checkpoint commands
+	if processCheckpointCommand(input) {
+		return true
+	}
+
+	// Check sudo password commands
+	if processSudoCommand(input) {
+		return true
+	}
+
+	// Check audit log commands
+	if processAuditCommand(input) {
+		return true
+	}
+
 	// Check if input contains "aurora" or is not a shell command
 	if isAuroraCommand(input) || !isShellCommand(input) {
 		// Use streaming response
@@ -38,7 +75,9 @@ func ProcessAuroraCommand(input string) bool {
 
 		// Use function calls for natural language processing
 		err := AgentMgr.StreamQueryWithFunctionCalls(input)
-		if err != nil {
+		if errors.Is(err, ErrTurnCancelled) {
+			fmt.Println("\033[33m[Interrupted]\033[0m")
+		} else if err != nil {
 			fmt.Printf("\n\033[31mError querying AI agent: %v\033[0m\n", err) // Red error message
 		}
 
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"aurora-agent/utils"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the commands the AI runs in the background
+const (
+	// maxRunningJobs is the number of background jobs that may run at the same time
+	maxRunningJobs = 10
+	// jobOutputLimit is the number of bytes kept of the output of a job, older output is dropped
+	jobOutputLimit = 1024 * 1024
+	// jobReadLimit is the number of bytes returned by a single read of the output
+	jobReadLimit = 32 * 1024
+	// jobStartWait is how long starting a job waits for its first output or early exit
+	jobStartWait = time.Second
+	// maxJobWait is the longest a read may wait for new output
+	maxJobWait = 60 * time.Second
+	// jobPollInterval is how often a waiting read checks the job
+	jobPollInterval = 100 * time.Millisecond
+)
+
+// backgroundJob is a command started by the AI that runs while the conversation goes on
+type backgroundJob struct {
+	ID      int
+	Command string
+	WorkDir string
+	Started time.Time
+
+	cmd  *exec.Cmd
+	done chan struct{}
+
+	mu sync.Mutex
+	// output holds the stdout and stderr of the job, dropped counts the bytes removed from its start
+	output  []byte
+	dropped int64
+	// read is the position in the output up to which the AI has read
+	read     int64
+	ended    time.Time
+	exitCode int
+	killed   bool
+}
+
+// Write collects the output of the job, it implements io.Writer
+func (j *backgroundJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.output = append(j.output, p...)
+	// Compact only now and then, so a chatty job doesn't copy its output on every write
+	if len(j.output) > 2*jobOutputLimit {
+		drop := len(j.output) - jobOutputLimit
+		j.output = append([]byte{}, j.output[drop:]...)
+		j.dropped += int64(drop)
+	}
+	return len(p), nil
+}
+
+// running reports whether the job has not ended yet
+func (j *backgroundJob) running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// hasUnread reports whether there is output the AI has not read yet
+func (j *backgroundJob) hasUnread() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.read < j.dropped+int64(len(j.output))
+}
+
+// readNew returns the output written since the last read, at most jobReadLimit bytes.
+// It also returns the number of bytes that were dropped before they could be read
+// and whether more output is waiting.
+func (j *backgroundJob) readNew() (text string, skipped int64, more bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	start := max(j.read, j.dropped)
+	skipped = start - j.read
+	end := j.dropped + int64(len(j.output))
+	if end-start > jobReadLimit {
+		end = start + jobReadLimit
+		// Don't split a rune between two reads
+		for end > start && !utf8.RuneStart(j.output[end-j.dropped]) {
+			end--
+		}
+	}
+	j.read = end
+	return string(j.output[start-j.dropped : end-j.dropped]), skipped, end < j.dropped+int64(len(j.output))
+}
+
+// kill stops the job and the processes it started
+func (j *backgroundJob) kill() error {
+	if !j.running() {
+		return nil
+	}
+	j.mu.Lock()
+	j.killed = true
+	j.mu.Unlock()
+	return utils.KillProcessGroup(j.cmd)
+}
+
+// status describes the state of the job in a few words
+func (j *backgroundJob) status() string {
+	if j.running() {
+		return fmt.Sprintf("running for %s", time.Since(j.Started).Round(time.Second))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.killed {
+		return fmt.Sprintf("killed after %s", j.ended.Sub(j.Started).Round(time.Second))
+	}
+	return fmt.Sprintf("exited with code %d after %s", j.exitCode, j.ended.Sub(j.Started).Round(time.Second))
+}
+
+// result returns the state of the job as a tool result
+func (j *backgroundJob) result(functionName string) FunctionCallResult {
+	result := FunctionCallResult{
+		Name:    functionName,
+		Success: true,
+		JobID:   j.ID,
+		Running: j.running(),
+		WorkDir: j.WorkDir,
+	}
+	if result.Running {
+		result.DurationMs = time.Since(j.Started).Milliseconds()
+		return result
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	exitCode := j.exitCode
+	result.ExitCode = &exitCode
+	result.Success = exitCode == 0
+	result.DurationMs = j.ended.Sub(j.Started).Milliseconds()
+	return result
+}
+
+// jobManager keeps the background jobs of the AI
+type jobManager struct {
+	mu     sync.Mutex
+	jobs   []*backgroundJob
+	nextID int
+}
+
+// jobs are the background jobs started in this process
+var jobs = &jobManager{}
+
+// start runs a command in the background. Its output is collected instead of
+// shown, Ctrl+C doesn't stop it and it is killed when Aurora exits.
+func (m *jobManager) start(command string) (*backgroundJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running := 0
+	for _, job := range m.jobs {
+		if job.running() {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		return nil, fmt.Errorf("%d background jobs are already running, kill one with kill_job first", running)
+	}
+
+	m.nextID++
+	job := &backgroundJob{
+		ID:      m.nextID,
+		Command: command,
+		Started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	if workDir, err := os.Getwd(); err == nil {
+		job.WorkDir = workDir
+	}
+
+	shell := agentShell()
+	job.cmd = exec.Command(shell, "-c", sessionPrelude(shell)+command)
+	job.cmd.Env = agentEnviron(os.Environ())
+	utils.SetProcessGroup(job.cmd)
+	utils.DisablePagers(job.cmd)
+	job.cmd.Stdout = job
+	job.cmd.Stderr = job
+	if err := job.cmd.Start(); err != nil {
+		m.nextID--
+		return nil, err
+	}
+	m.jobs = append(m.jobs, job)
+
+	go func() {
+		job.cmd.Wait()
+		job.mu.Lock()
+		job.ended = time.Now()
+		job.exitCode = job.cmd.ProcessState.ExitCode()
+		job.mu.Unlock()
+		close(job.done)
+	}()
+	return job, nil
+}
+
+// get returns the job with the given ID
+func (m *jobManager) get(id int) (*backgroundJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("background job %d not found", id)
+}
+
+// list returns all jobs, the finished ones too
+func (m *jobManager) list() []*backgroundJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*backgroundJob{}, m.jobs...)
+}
+
+// waitForOutput waits until the job writes output the AI has not read, ends,
+// or the wait is over
+func waitForOutput(ctx context.Context, job *backgroundJob, wait time.Duration) {
+	deadline := time.After(wait)
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for job.running() && !job.hasUnread() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			return
+		case <-job.done:
+		case <-ticker.C:
+		}
+	}
+}
+
+// formatJobOutput returns the unread output of a job as plain text with notes
+// about dropped and remaining output
+func formatJobOutput(job *backgroundJob) string {
+	text, skipped, more := job.readNew()
+	text = utils.StripTerminalCodes(text)
+
+	var out strings.Builder
+	if skipped > 0 {
+		fmt.Fprintf(&out, "[%d bytes of older output were dropped before they were read]\n", skipped)
+	}
+	out.WriteString(text)
+	if more {
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString("[More output follows, call read_job_output again to read it]\n")
+	}
+	return out.String()
+}
+
+// KillBackgroundJobs stops the background jobs that are still running, Aurora calls it on exit
+func KillBackgroundJobs() {
+	var running []*backgroundJob
+	for _, job := range jobs.list() {
+		if job.running() {
+			running = append(running, job)
+		}
+	}
+	if len(running) == 0 {
+		return
+	}
+
+	fmt.Printf("Stopping %d background jobs\n", len(running))
+	for _, job := range running {
+		job.kill()
+	}
+}
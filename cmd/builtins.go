@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// builtins are the commands Aurora runs itself when a command line is just one
+// of them, so they work the same with every shell
+var builtins = map[string]func(args []string) (status int, exit bool){
+	"cd":    builtinCd,
+	"pushd": builtinPushd,
+	"popd":  builtinPopd,
+	"dirs":  builtinDirs,
+	"exit":  builtinExit,
+	"quit":  builtinExit,
+}
+
+// lastExitStatus is the exit code of the last command line of the user, $? in builtins
+var lastExitStatus int
+
+// dirStack holds the directories saved by pushd, the current directory is not part of it
+var dirStack []string
+
+// RunBuiltin runs a command line that is a single cd, pushd, popd, dirs, exit or
+// quit and reports whether it was one. exit is set when Aurora should exit with
+// ExitStatus. Lines that use anything Aurora can't expand itself, like command
+// substitutions or variables only the shell knows, are left to the shell. Like
+// other command lines of the user they are recorded in the audit log.
+func RunBuiltin(commandLine string) (handled bool, exit bool) {
+	args, ok := parseBuiltin(commandLine)
+	if !ok {
+		return false, false
+	}
+	record := newAuditRecord(auditOriginUser, commandLine)
+	started := time.Now()
+	status, exit := builtins[args[0]](args[1:])
+	lastExitStatus = status
+	record.finish(&status, time.Since(started))
+	recordAudit(record)
+	return true, exit
+}
+
+// ExitStatus returns the exit code of the last command line, Aurora exits with it
+func ExitStatus() int {
+	return lastExitStatus
+}
+
+// parseBuiltin parses a command line with shell rules and returns its expanded
+// words when it is a single simple command running a builtin
+func parseBuiltin(commandLine string) ([]string, bool) {
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(commandLine), "")
+	if err != nil || len(file.Stmts) != 1 {
+		return nil, false
+	}
+	stmt := file.Stmts[0]
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || stmt.Negated || stmt.Background || stmt.Coprocess || len(stmt.Redirs) > 0 ||
+		len(call.Assigns) > 0 || len(call.Args) == 0 {
+		return nil, false
+	}
+	if _, ok := builtins[call.Args[0].Lit()]; !ok {
+		return nil, false
+	}
+
+	cfg := &expand.Config{
+		Env:     expand.ListEnviron(append(os.Environ(), "?="+strconv.Itoa(lastExitStatus))...),
+		ReadDir: readDirInfo,
+		NoUnset: true,
+	}
+	args, err := expand.Fields(cfg, call.Args...)
+	if err != nil || len(args) == 0 {
+		return nil, false
+	}
+	return args, true
+}
+
+// readDirInfo lists a directory for glob expansion
+func readDirInfo(dir string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// builtinCd changes the working directory like the cd of bash: no argument goes
+// home, - goes back to $OLDPWD, relative names are looked up in $CDPATH and -P
+// resolves symbolic links
+func builtinCd(args []string) (int, bool) {
+	physical := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		switch option {
+		case "-P":
+			physical = true
+		case "-L":
+			physical = false
+		default:
+			return builtinError("cd", "%s: invalid option", option), false
+		}
+	}
+	if len(args) > 1 {
+		return builtinError("cd", "too many arguments"), false
+	}
+
+	var target string
+	show := false
+	switch {
+	case len(args) == 0:
+		target = os.Getenv("HOME")
+		if target == "" {
+			return builtinError("cd", "HOME not set"), false
+		}
+	case args[0] == "-":
+		target = os.Getenv("OLDPWD")
+		if target == "" {
+			return builtinError("cd", "OLDPWD not set"), false
+		}
+		show = true
+	default:
+		target = args[0]
+		if dir, ok := lookupCdPath(target); ok {
+			target = dir
+			show = true
+		}
+	}
+
+	dir, err := resolveDirectory(target, physical)
+	if err == nil {
+		err = changeDirectory(dir)
+	}
+	if err != nil {
+		return builtinError("cd", "%s: %v", target, err), false
+	}
+	if show {
+		fmt.Println(dir)
+	}
+	return 0, false
+}
+
+// lookupCdPath finds a relative directory in the directories of $CDPATH. Like in
+// bash it is only found there when an entry other than the current directory has it.
+func lookupCdPath(name string) (string, bool) {
+	cdPath := os.Getenv("CDPATH")
+	if cdPath == "" || filepath.IsAbs(name) || name == "." || name == ".." ||
+		strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	for _, entry := range filepath.SplitList(cdPath) {
+		if entry == "" || entry == "." {
+			if isDirectory(name) {
+				return "", false
+			}
+			continue
+		}
+		dir := filepath.Join(entry, name)
+		if isDirectory(dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// resolveDirectory returns the absolute path of a directory. Without physical
+// .. removes the last part of the path, like cd -L does, instead of following
+// the symbolic link back.
+func resolveDirectory(target string, physical bool) (string, error) {
+	dir := target
+	if !filepath.IsAbs(dir) {
+		current, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(current, dir)
+	}
+	dir = filepath.Clean(dir)
+	if physical {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return "", unwrapPathError(err)
+		}
+		dir = resolved
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", unwrapPathError(err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory")
+	}
+	return dir, nil
+}
+
+// changeDirectory makes dir the working directory of Aurora and keeps $PWD and
+// $OLDPWD up to date, for cd - and for the commands Aurora starts
+func changeDirectory(dir string) error {
+	previous, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		return unwrapPathError(err)
+	}
+	if previous != "" && previous != dir {
+		os.Setenv("OLDPWD", previous)
+	}
+	os.Setenv("PWD", dir)
+	return nil
+}
+
+// builtinPushd saves the working directory on the directory stack and changes to
+// another one. Without an argument it swaps the two top directories, +N and -N
+// rotate the stack so that the Nth directory is on top.
+func builtinPushd(args []string) (int, bool) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return builtinError("pushd", "too many arguments"), false
+	}
+
+	current, err := os.Getwd()
+	if err != nil {
+		return builtinError("pushd", "%v", err), false
+	}
+	stack := append([]string{current}, dirStack...)
+
+	switch {
+	case len(args) == 0:
+		if len(dirStack) == 0 {
+			return builtinError("pushd", "no other directory"), false
+		}
+		stack[0], stack[1] = stack[1], stack[0]
+	case isStackIndex(args[0]):
+		index, err := stackIndex(args[0], len(stack))
+		if err != nil {
+			return builtinError("pushd", "%s: %v", args[0], err), false
+		}
+		stack = append(append([]string{}, stack[index:]...), stack[:index]...)
+	default:
+		dir, err := resolveDirectory(args[0], false)
+		if err != nil {
+			return builtinError("pushd", "%s: %v", args[0], err), false
+		}
+		stack = append([]string{dir}, stack...)
+	}
+
+	if err := changeDirectory(stack[0]); err != nil {
+		return builtinError("pushd", "%s: %v", stack[0], err), false
+	}
+	dirStack = stack[1:]
+	printDirStack(false, false, false)
+	return 0, false
+}
+
+// builtinPopd removes the top directory from the directory stack and changes to
+// the next one, +N and -N remove the Nth directory instead
+func builtinPopd(args []string) (int, bool) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return builtinError("popd", "too many arguments"), false
+	}
+	if len(dirStack) == 0 {
+		return builtinError("popd", "directory stack empty"), false
+	}
+
+	current, err := os.Getwd()
+	if err != nil {
+		return builtinError("popd", "%v", err), false
+	}
+	stack := append([]string{current}, dirStack...)
+
+	index := 0
+	if len(args) == 1 {
+		if !isStackIndex(args[0]) {
+			return builtinError("popd", "%s: invalid argument", args[0]), false
+		}
+		index, err = stackIndex(args[0], len(stack))
+		if err != nil {
+			return builtinError("popd", "%s: %v", args[0], err), false
+		}
+	}
+
+	if index == 0 {
+		if err := changeDirectory(stack[1]); err != nil {
+			return builtinError("popd", "%s: %v", stack[1], err), false
+		}
+	}
+	stack = append(stack[:index], stack[index+1:]...)
+	dirStack = stack[1:]
+	printDirStack(false, false, false)
+	return 0, false
+}
+
+// builtinDirs shows the directory stack, -c clears it, -p shows a directory per
+// line, -v numbers them and -l doesn't abbreviate the home directory
+func builtinDirs(args []string) (int, bool) {
+	perLine, numbered, long := false, false, false
+	for _, arg := range args {
+		switch arg {
+		case "-c":
+			dirStack = nil
+			return 0, false
+		case "-p":
+			perLine = true
+		case "-v":
+			perLine, numbered = true, true
+		case "-l":
+			long = true
+		default:
+			return builtinError("dirs", "%s: invalid argument", arg), false
+		}
+	}
+	printDirStack(perLine, numbered, long)
+	return 0, false
+}
+
+// printDirStack prints the working directory followed by the directory stack
+func printDirStack(perLine bool, numbered bool, long bool) {
+	current, err := os.Getwd()
+	if err != nil {
+		current = "?"
+	}
+	stack := append([]string{current}, dirStack...)
+	home := os.Getenv("HOME")
+
+	names := make([]string, len(stack))
+	for i, dir := range stack {
+		names[i] = dir
+		if !long && home != "" && (dir == home || strings.HasPrefix(dir, home+string(os.PathSeparator))) {
+			names[i] = "~" + dir[len(home):]
+		}
+	}
+	if !perLine {
+		fmt.Println(strings.Join(names, " "))
+		return
+	}
+	for i, name := range names {
+		if numbered {
+			fmt.Printf("%2d  %s\n", i, name)
+		} else {
+			fmt.Println(name)
+		}
+	}
+}
+
+// isStackIndex reports whether an argument of pushd or popd is +N or -N
+func isStackIndex(arg string) bool {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return false
+	}
+	_, err := strconv.Atoi(arg[1:])
+	return err == nil
+}
+
+// stackIndex converts +N, counted from the top, or -N, counted from the bottom,
+// into a position in a stack of the given size
+func stackIndex(arg string, size int) (int, error) {
+	n, _ := strconv.Atoi(arg[1:])
+	if n < 0 || n >= size {
+		return 0, fmt.Errorf("directory stack index out of range")
+	}
+	if arg[0] == '-' {
+		return size - 1 - n, nil
+	}
+	return n, nil
+}
+
+// builtinExit asks Aurora to exit, with the given code or the exit code of the
+// last command. Like in shells a code that is not a number exits with 2.
+func builtinExit(args []string) (int, bool) {
+	if len(args) == 0 {
+		return lastExitStatus, true
+	}
+	code, err := strconv.Atoi(args[0])
+	if err != nil {
+		builtinError("exit", "%s: numeric argument required", args[0])
+		return 2, true
+	}
+	if len(args) > 1 {
+		return builtinError("exit", "too many arguments"), false
+	}
+	return code & 0xff, true
+}
+
+// builtinError prints an error of a builtin like shells do and returns exit code 1
+func builtinError(name string, format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", name, fmt.Sprintf(format, args...))
+	return 1
+}
+
+// isDirectory reports whether a path is an existing directory
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// unwrapPathError drops the operation and path from errors of the os package,
+// the builtins name the path themselves
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+)
+
+// Usage is the number of tokens a request used
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	// Estimated is set when the provider didn't report usage and it was estimated
+	Estimated bool
+}
+
+// ChatResponse is a single model response returned by a provider
+type ChatResponse struct {
+	Message Message
+	// Usage is empty when the provider didn't report it
+	Usage Usage
+}
+
+// ChatProvider is the thin adapter each AI provider implements.
+// Providers only translate the conversation to their wire format and back;
+// history, tools and the agent loop are shared by agentCore.
+type ChatProvider interface {
+	// Complete sends the conversation and returns the response without streaming
+	Complete(ctx context.Context, conversation *Conversation, tools []ToolDefinition) (ChatResponse, error)
+	// Stream sends the conversation and passes response text to onText as it arrives
+	Stream(ctx context.Context, conversation *Conversation, tools []ToolDefinition, onText func(string)) (ChatResponse, error)
+	// Model returns the model the provider sends requests to
+	Model() string
+	// MaxOutputTokens returns the response length the provider requests, 0 when not limited
+	MaxOutputTokens() int
+}
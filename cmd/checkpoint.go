@@ -0,0 +1,513 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aurora-agent/config"
+)
+
+// scanStoreLimit limits the bytes a working directory scan copies into the blob store
+const scanStoreLimit = 100 * 1024 * 1024
+
+// Checkpoint holds the files the agent changed during one turn, as they were before the turn
+type Checkpoint struct {
+	ID        int            `json:"id"`
+	Prompt    string         `json:"prompt,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []FileSnapshot `json:"files"`
+}
+
+// FileSnapshot is the state of a file before the agent first changed it in a turn
+type FileSnapshot struct {
+	Path string `json:"path"`
+	// Existed is false for files the agent created, restoring removes them
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	// Blob is the SHA-256 of the content in the blob store, empty when the file was too large to keep
+	Blob string `json:"blob,omitempty"`
+	// Source is the tool or command that changed the file
+	Source string `json:"source"`
+}
+
+// Restorable reports whether the snapshot holds enough to restore the file
+func (f FileSnapshot) Restorable() bool {
+	return !f.Existed || f.Blob != ""
+}
+
+// scanEntry is a file seen by a working directory scan
+type scanEntry struct {
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+	Blob    string
+}
+
+// dirScan is the state of the working directory before a command ran
+type dirScan struct {
+	root  string
+	files map[string]scanEntry
+	// complete is false when the scan stopped at the file limit
+	complete bool
+}
+
+// checkpointStore keeps the checkpoints of the current session
+type checkpointStore struct {
+	mu          sync.Mutex
+	sessionID   string
+	checkpoints []*Checkpoint
+	// current is the checkpoint of the turn in progress, nil between turns
+	current *Checkpoint
+	// scanned remembers the blobs of working directory files during a turn, so
+	// files that didn't change are not read again by the next command's scan
+	scanned map[string]scanEntry
+}
+
+// checkpoints holds the checkpoints of the current session
+var checkpoints = &checkpointStore{scanned: map[string]scanEntry{}}
+
+// checkpointsPath returns the file the checkpoints of a session are stored in
+func checkpointsPath(sessionID string) string {
+	return filepath.Join(config.GetCheckpointsDir(), sessionID+".json")
+}
+
+// blobPath returns the file the content with the given hash is stored in
+func blobPath(hash string) string {
+	return filepath.Join(config.GetCheckpointsDir(), "blobs", hash[:2], hash)
+}
+
+// storeBlob stores content in the blob store and returns its hash.
+// Equal content is stored once.
+func storeBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := blobPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	// Snapshots may contain anything the files contained, only the user can read them
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// loadCheckpoints reads the checkpoints of a session, a session without changes has none
+func loadCheckpoints(sessionID string) ([]*Checkpoint, error) {
+	data, err := os.ReadFile(checkpointsPath(sessionID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Checkpoint
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoints of session %s: %w", sessionID, err)
+	}
+	return list, nil
+}
+
+// deleteCheckpoints removes the checkpoints of a deleted session
+func deleteCheckpoints(sessionID string) {
+	if err := os.Remove(checkpointsPath(sessionID)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("\033[31mWarning: failed to delete checkpoints: %v\033[0m\n", err)
+	}
+}
+
+// useSession makes the store hold the checkpoints of a session. The caller holds the lock.
+func (s *checkpointStore) useSession(sessionID string) {
+	if s.sessionID == sessionID {
+		return
+	}
+	list, err := loadCheckpoints(sessionID)
+	if err != nil {
+		fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+	}
+	s.sessionID = sessionID
+	s.checkpoints = list
+}
+
+// save writes the checkpoints of the session. The caller holds the lock.
+func (s *checkpointStore) save() error {
+	if len(s.checkpoints) == 0 {
+		deleteCheckpoints(s.sessionID)
+		return nil
+	}
+	data, err := json.MarshalIndent(s.checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	if err := os.MkdirAll(config.GetCheckpointsDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoints directory: %w", err)
+	}
+	if err := writeFileAtomic(checkpointsPath(s.sessionID), data, 0600); err != nil {
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	return nil
+}
+
+// begin starts the checkpoint of a new turn
+func (s *checkpointStore) begin(sessionID string, prompt string) {
+	if !config.CurrentConfig.Checkpoints.Enabled || config.GetCheckpointsDir() == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.useSession(sessionID)
+	id := 1
+	if len(s.checkpoints) > 0 {
+		id = s.checkpoints[len(s.checkpoints)-1].ID + 1
+	}
+
+	s.current = &Checkpoint{ID: id, Prompt: shortenText(prompt, 60), CreatedAt: time.Now()}
+}
+
+// finish stores the checkpoint of the turn when the turn changed files. The
+// copies the scans of the turn made of files that didn't change are removed.
+func (s *checkpointStore) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.current
+	s.current = nil
+	if current == nil {
+		return
+	}
+
+	if len(current.Files) > 0 {
+		s.checkpoints = append(s.checkpoints, current)
+		if limit := config.CurrentConfig.Checkpoints.MaxCheckpoints; limit > 0 && len(s.checkpoints) > limit {
+			s.checkpoints = s.checkpoints[len(s.checkpoints)-limit:]
+		}
+		if err := s.save(); err != nil {
+			fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+		}
+	}
+	s.removeUnusedBlobs()
+}
+
+// record adds a snapshot to the current checkpoint unless the file already has one.
+// The caller holds the lock.
+func (s *checkpointStore) record(snapshot FileSnapshot) {
+	for _, file := range s.current.Files {
+		if file.Path == snapshot.Path {
+			return
+		}
+	}
+	s.current.Files = append(s.current.Files, snapshot)
+}
+
+// snapshotFile saves a file before a file tool changes it
+func (s *checkpointStore) snapshotFile(path string, source string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		s.record(FileSnapshot{Path: path, Source: source})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	hash, err := storeBlob(data)
+	if err != nil {
+		return fmt.Errorf("failed to save a checkpoint of %s: %w", path, err)
+	}
+	s.record(FileSnapshot{Path: path, Existed: true, Mode: info.Mode().Perm(), Blob: hash, Source: source})
+	return nil
+}
+
+// scanBefore saves the files of the working directory before a command may change them.
+// It returns nil when checkpoints or command scans are disabled.
+func (s *checkpointStore) scanBefore() *dirScan {
+	settings := config.CurrentConfig.Checkpoints
+	if !settings.ScanCommands {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return nil
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	// Scanning the whole home directory or file system would take too long
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(root) == filepath.Clean(home) {
+		return nil
+	}
+	if filepath.Dir(root) == root {
+		return nil
+	}
+
+	return scanDirectory(root, s.scanned, true)
+}
+
+// recordScanChanges compares the working directory with the scan taken before a command
+// and adds the files the command changed, created or deleted to the current checkpoint
+func (s *checkpointStore) recordScanChanges(before *dirScan, source string) {
+	if before == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return
+	}
+	after := scanDirectory(before.root, nil, false)
+
+	var changed []string
+	for path, entry := range before.files {
+		now, exists := after.files[path]
+		if exists && now.Size == entry.Size && now.ModTime.Equal(entry.ModTime) {
+			continue
+		}
+		s.record(FileSnapshot{Path: path, Existed: true, Mode: entry.Mode, Blob: entry.Blob, Source: source})
+		changed = append(changed, path)
+	}
+	// Without a complete scan a file missing before may just have been past the limit
+	if before.complete && after.complete {
+		for path := range after.files {
+			if _, existed := before.files[path]; !existed {
+				s.record(FileSnapshot{Path: path, Source: source})
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	if len(changed) > 0 {
+		fmt.Printf("\033[90m(Checkpoint: %d files changed by the command)\033[0m\n", len(changed))
+	}
+}
+
+// scanDirectory lists the regular files below root, leaving out the paths the
+// path policy denies so their secrets are never copied. With store set the content of
+// files up to the configured size is saved in the blob store, cache avoids reading
+// files that didn't change since the last scan.
+func scanDirectory(root string, cache map[string]scanEntry, store bool) *dirScan {
+	settings := config.CurrentConfig.Checkpoints
+	scan := &dirScan{root: root, files: map[string]scanEntry{}, complete: true}
+
+	ignored := make(map[string]bool, len(settings.IgnoreDirs))
+	for _, name := range settings.IgnoreDirs {
+		ignored[name] = true
+	}
+	checkpointsDir := config.GetCheckpointsDir()
+
+	var stored int64
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && (ignored[entry.Name()] || path == checkpointsDir || isScanDenied(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || isScanDenied(path) {
+			return nil
+		}
+		if settings.MaxFiles > 0 && len(scan.files) >= settings.MaxFiles {
+			scan.complete = false
+			return filepath.SkipAll
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		file := scanEntry{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode().Perm()}
+
+		if store && file.Size <= settings.MaxFileSize && stored+file.Size <= scanStoreLimit {
+			if cached, ok := cache[path]; ok && cached.Blob != "" && cached.Size == file.Size && cached.ModTime.Equal(file.ModTime) {
+				file.Blob = cached.Blob
+			} else if data, err := os.ReadFile(path); err == nil {
+				if hash, err := storeBlob(data); err == nil {
+					file.Blob = hash
+				}
+			}
+			stored += file.Size
+			if cache != nil {
+				cache[path] = file
+			}
+		}
+
+		scan.files[path] = file
+		return nil
+	})
+
+	return scan
+}
+
+// isScanDenied reports whether a deny glob of the path policy matches a path
+// found by a scan
+func isScanDenied(path string) bool {
+	_, denied := deniedPathGlob(path)
+	return denied
+}
+
+// restore puts back the files of the checkpoints from id on, newest first,
+// and removes those checkpoints. It returns the restored files.
+func (s *checkpointStore) restore(sessionID string, id int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.useSession(sessionID)
+	index := -1
+	for i, checkpoint := range s.checkpoints {
+		if checkpoint.ID >= id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("checkpoint %d not found", id)
+	}
+
+	var restored []string
+	var failed []string
+	seen := map[string]bool{}
+	for i := len(s.checkpoints) - 1; i >= index; i-- {
+		for _, file := range s.checkpoints[i].Files {
+			if err := restoreFile(file); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", file.Path, err))
+				continue
+			}
+			if !seen[file.Path] {
+				seen[file.Path] = true
+				restored = append(restored, file.Path)
+			}
+		}
+	}
+
+	s.checkpoints = s.checkpoints[:index]
+	if err := s.save(); err != nil {
+		return restored, err
+	}
+	s.removeUnusedBlobs()
+
+	if len(failed) > 0 {
+		return restored, fmt.Errorf("some files could not be restored:\n  %s", strings.Join(failed, "\n  "))
+	}
+	return restored, nil
+}
+
+// restoreFile puts a single file back to its snapshot
+func restoreFile(file FileSnapshot) error {
+	if !file.Existed {
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if file.Blob == "" {
+		return fmt.Errorf("the file was too large to keep a copy")
+	}
+
+	data, err := os.ReadFile(blobPath(file.Blob))
+	if err != nil {
+		return fmt.Errorf("the saved copy is missing: %w", err)
+	}
+	mode := file.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	return writeFileAtomic(file.Path, data, mode)
+}
+
+// list returns the checkpoints of a session, oldest first
+func (s *checkpointStore) list(sessionID string) []*Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.useSession(sessionID)
+	return append([]*Checkpoint(nil), s.checkpoints...)
+}
+
+// removeUnusedBlobs deletes blobs no checkpoint of any session refers to, like
+// the copies of files commands didn't change, and forgets the blobs of the scans.
+// The caller holds the lock.
+func (s *checkpointStore) removeUnusedBlobs() {
+	clear(s.scanned)
+	dir := config.GetCheckpointsDir()
+
+	used := map[string]bool{}
+	for _, checkpoint := range s.checkpoints {
+		for _, file := range checkpoint.Files {
+			used[file.Blob] = true
+		}
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range paths {
+		list, err := loadCheckpoints(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			// Keep the blobs of checkpoints that can't be read
+			return
+		}
+		for _, checkpoint := range list {
+			for _, file := range checkpoint.Files {
+				used[file.Blob] = true
+			}
+		}
+	}
+
+	blobsDir := filepath.Join(dir, "blobs")
+	_ = filepath.WalkDir(blobsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if !used[entry.Name()] {
+			os.Remove(path)
+		}
+		return nil
+	})
+
+	// Remove the directories that became empty, others fail to be removed
+	subdirs, _ := os.ReadDir(blobsDir)
+	for _, subdir := range subdirs {
+		if subdir.IsDir() {
+			os.Remove(filepath.Join(blobsDir, subdir.Name()))
+		}
+	}
+}
+
+// sortedFiles returns the paths of a checkpoint in order
+func (c *Checkpoint) sortedFiles() []FileSnapshot {
+	files := append([]FileSnapshot(nil), c.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
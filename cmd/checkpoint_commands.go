@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// processCheckpointCommand handles the undo and checkpoints commands
+func processCheckpointCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) == 0 {
+		return false
+	}
+
+	switch words[0] {
+	case "undo":
+		if len(words) > 1 {
+			fmt.Println("\033[31mError: Wrong format. Use: undo\033[0m")
+			return true
+		}
+		list := checkpoints.list(AgentMgr.Session().ID)
+		if len(list) == 0 {
+			fmt.Println("No file changes to undo in this session")
+			return true
+		}
+		restoreCheckpoint(list, list[len(list)-1].ID)
+		return true
+
+	case "checkpoints":
+	default:
+		return false
+	}
+
+	list := checkpoints.list(AgentMgr.Session().ID)
+	if len(words) == 1 || words[1] == "list" {
+		listCheckpoints(list)
+		return true
+	}
+
+	if len(words) != 3 {
+		fmt.Println("\033[31mError: Wrong format. Use: checkpoints [list], checkpoints show <id>, checkpoints restore <id>\033[0m")
+		return true
+	}
+	id, err := strconv.Atoi(words[2])
+	if err != nil {
+		fmt.Printf("\033[31mError: '%s' is not a checkpoint number\033[0m\n", words[2])
+		return true
+	}
+	checkpoint := findCheckpoint(list, id)
+	if checkpoint == nil {
+		fmt.Printf("\033[31mError: checkpoint %d not found\033[0m\n", id)
+		return true
+	}
+
+	switch words[1] {
+	case "show":
+		showCheckpoint(checkpoint)
+
+	case "restore":
+		restoreCheckpoint(list, id)
+
+	default:
+		fmt.Println("\033[31mUnknown checkpoints command. Available commands: list, show, restore\033[0m")
+	}
+	return true
+}
+
+// findCheckpoint returns the checkpoint with the given ID
+func findCheckpoint(list []*Checkpoint, id int) *Checkpoint {
+	for _, checkpoint := range list {
+		if checkpoint.ID == id {
+			return checkpoint
+		}
+	}
+	return nil
+}
+
+// listCheckpoints prints the checkpoints of the session, newest first
+func listCheckpoints(list []*Checkpoint) {
+	if len(list) == 0 {
+		fmt.Println("No checkpoints in this session, they are created when the AI changes files")
+		return
+	}
+
+	fmt.Println("\n\033[1mCheckpoints of this session:\033[0m")
+	for i := len(list) - 1; i >= 0; i-- {
+		checkpoint := list[i]
+		prompt := checkpoint.Prompt
+		if prompt == "" {
+			prompt = "(no prompt)"
+		}
+		fmt.Printf("  \033[32m%3d\033[0m  %s  %2d files  %s\n",
+			checkpoint.ID, checkpoint.CreatedAt.Format("2006-01-02 15:04"), len(checkpoint.Files), prompt)
+	}
+	fmt.Println("\nUse `\033[32mcheckpoints restore <id>\033[0m` to undo that turn and every later one, or `\033[32mundo\033[0m` for the last turn.")
+	fmt.Println()
+}
+
+// showCheckpoint prints the files saved in a checkpoint
+func showCheckpoint(checkpoint *Checkpoint) {
+	fmt.Printf("\n\033[1mCheckpoint %d\033[0m (%s)\n", checkpoint.ID, checkpoint.CreatedAt.Format("2006-01-02 15:04:05"))
+	if checkpoint.Prompt != "" {
+		fmt.Printf("  Prompt: %s\n", checkpoint.Prompt)
+	}
+	for _, file := range checkpoint.sortedFiles() {
+		fmt.Printf("  %s  %s \033[90m(%s)\033[0m\n", snapshotAction(file), file.Path, file.Source)
+	}
+	fmt.Println()
+}
+
+// snapshotAction describes what restoring a file does
+func snapshotAction(file FileSnapshot) string {
+	switch {
+	case !file.Existed:
+		return "\033[31mremove \033[0m"
+	case !file.Restorable():
+		return "\033[90mtoo big\033[0m"
+	default:
+		return "\033[32mrestore\033[0m"
+	}
+}
+
+// restoreCheckpoint asks for confirmation and puts back the files changed
+// from the checkpoint with the given ID on
+func restoreCheckpoint(list []*Checkpoint, id int) {
+	turns := 0
+	files := map[string]FileSnapshot{}
+	for _, checkpoint := range list {
+		if checkpoint.ID < id {
+			continue
+		}
+		turns++
+		for _, file := range checkpoint.Files {
+			// The oldest snapshot of a file is the one that stays
+			if _, seen := files[file.Path]; !seen {
+				files[file.Path] = file
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Printf("\n\033[1mUndo the file changes of %d turn(s):\033[0m\n", turns)
+	for _, path := range paths {
+		fmt.Printf("  %s  %s\n", snapshotAction(files[path]), path)
+	}
+	fmt.Println("\033[33mChanges made to these files after the checkpoint are lost.\033[0m")
+
+	answer, err := askUser("\033[33mRestore? [y/N]: \033[0m", "")
+	if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		fmt.Println("Nothing restored")
+		return
+	}
+
+	restored, err := checkpoints.restore(AgentMgr.Session().ID, id)
+	if len(restored) > 0 {
+		fmt.Printf("\033[32mRestored %d files\033[0m\n", len(restored))
+	}
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"aurora-agent/config"
+	"fmt"
+	"os"
+)
+
+// ClaudeAgent implements the AIAgent interface for Anthropic's Claude
+type ClaudeAgent struct {
+	agentCore
+	client    *claudeClient
+	model     string
+	maxTokens int
+}
+
+// NewClaudeAgent creates a new Claude agent
+func NewClaudeAgent(apiKey string) *ClaudeAgent {
+	// If apiKey is empty, try to get it from config first, then from environment variable.
+	// A missing key is reported when the agent is used, so OpenAI-only setups keep working.
+	if apiKey == "" {
+		apiKey = config.CurrentConfig.Anthropic.APIKey
+		if apiKey == "" {
+			apiKey = os.Getenv("ANTHROPIC_API_KEY")
+		}
+	}
+
+	// Get model from config, use default if empty
+	model := config.CurrentConfig.Anthropic.Model
+	if model == "" {
+		model = config.DefaultAnthropicModel
+	}
+
+	maxTokens := config.CurrentConfig.Anthropic.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = config.DefaultAnthropicMaxTokens
+	}
+
+	agent := &ClaudeAgent{
+		client:    newClaudeClient(apiKey, config.CurrentConfig.Anthropic.BaseURL),
+		model:     model,
+		maxTokens: maxTokens,
+	}
+	agent.agentCore = newAgentCore(agent)
+
+	return agent
+}
+
+// Name returns the name of the agent
+func (a *ClaudeAgent) Name() string {
+	return string(Claude)
+}
+
+// SetModel sets the Claude model to use
+func (a *ClaudeAgent) SetModel(model string) {
+	a.model = model
+}
+
+// Model returns the model the agent uses
+func (a *ClaudeAgent) Model() string {
+	return a.model
+}
+
+// MaxOutputTokens returns the response length requested from the Messages API
+func (a *ClaudeAgent) MaxOutputTokens() int {
+	return a.maxTokens
+}
+
+// checkAPIKey returns an error if no API key is configured
+func (a *ClaudeAgent) checkAPIKey() error {
+	if a.client.apiKey == "" {
+		return fmt.Errorf("Anthropic API key not found in config or environment variable (ANTHROPIC_API_KEY)")
+	}
+	return nil
+}
+
+// newRequest builds a Messages API request from the conversation
+func (a *ClaudeAgent) newRequest(conversation *Conversation, tools []ToolDefinition) claudeRequest {
+	return claudeRequest{
+		Model:     a.model,
+		MaxTokens: a.maxTokens,
+		System:    conversation.SystemPrompt,
+		Messages:  toClaudeMessages(conversation),
+		Tools:     toClaudeTools(tools),
+	}
+}
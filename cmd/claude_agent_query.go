@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+)
+
+// Complete sends the conversation to Claude and returns the response
+func (a *ClaudeAgent) Complete(ctx context.Context, conversation *Conversation, tools []ToolDefinition) (ChatResponse, error) {
+	if err := a.checkAPIKey(); err != nil {
+		return ChatResponse{}, err
+	}
+
+	resp, err := a.client.createMessage(ctx, a.newRequest(conversation, tools))
+	if err != nil {
+		return ChatResponse{}, fmt.Errorf("Anthropic API error: %v", err)
+	}
+
+	message := fromClaudeContent(resp.Content)
+	if message.Content == "" && len(message.ToolCalls) == 0 {
+		return ChatResponse{}, fmt.Errorf("no response from Anthropic")
+	}
+
+	return ChatResponse{
+		Message: message,
+		Usage: Usage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+		},
+	}, nil
+}
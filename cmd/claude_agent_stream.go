@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+)
+
+// Stream creates a message stream and processes the response
+func (a *ClaudeAgent) Stream(ctx context.Context, conversation *Conversation, tools []ToolDefinition, onText func(string)) (ChatResponse, error) {
+	if err := a.checkAPIKey(); err != nil {
+		return ChatResponse{}, err
+	}
+
+	stream, err := a.client.createMessageStream(ctx, a.newRequest(conversation, tools))
+	if err != nil {
+		return ChatResponse{}, fmt.Errorf("Anthropic API stream error: %v", err)
+	}
+	defer stream.Close()
+
+	return a.processStream(stream, onText)
+}
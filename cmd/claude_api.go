@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultClaudeBaseURL is the Anthropic API endpoint used when no base URL is configured
+	defaultClaudeBaseURL = "https://api.anthropic.com"
+	// claudeAPIVersion is the Messages API version sent with every request
+	claudeAPIVersion = "2023-06-01"
+)
+
+// claudeContentBlock is a single block of a Claude message (text, tool_use or tool_result)
+type claudeContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// claudeMessage is a message in the Claude conversation history
+type claudeMessage struct {
+	Role    string               `json:"role"`
+	Content []claudeContentBlock `json:"content"`
+}
+
+// claudeTool describes a tool the model is allowed to use
+type claudeTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// claudeRequest is the body of a Messages API request
+type claudeRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	System    string          `json:"system,omitempty"`
+	Messages  []claudeMessage `json:"messages"`
+	Tools     []claudeTool    `json:"tools,omitempty"`
+	Stream    bool            `json:"stream,omitempty"`
+}
+
+// claudeUsage holds token usage reported by the API
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// claudeResponse is the body of a non-streaming Messages API response
+type claudeResponse struct {
+	ID         string               `json:"id"`
+	Role       string               `json:"role"`
+	Content    []claudeContentBlock `json:"content"`
+	StopReason string               `json:"stop_reason"`
+	Usage      claudeUsage          `json:"usage"`
+}
+
+// claudeAPIError is the error object returned by the API
+type claudeAPIError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// claudeStreamEvent is a single server-sent event of a streaming response
+type claudeStreamEvent struct {
+	Type         string              `json:"type"`
+	Index        int                 `json:"index"`
+	Message      *claudeResponse     `json:"message,omitempty"`
+	ContentBlock *claudeContentBlock `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *claudeUsage    `json:"usage,omitempty"`
+	Error *claudeAPIError `json:"error,omitempty"`
+}
+
+// claudeClient is a minimal HTTP client for the Anthropic Messages API
+type claudeClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newClaudeClient creates a new Messages API client
+func newClaudeClient(apiKey string, baseURL string) *claudeClient {
+	if baseURL == "" {
+		baseURL = defaultClaudeBaseURL
+	}
+
+	return &claudeClient{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+}
+
+// createMessage sends a non-streaming request and returns the decoded response
+func (c *claudeClient) createMessage(ctx context.Context, request claudeRequest) (*claudeResponse, error) {
+	request.Stream = false
+
+	resp, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response claudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	return &response, nil
+}
+
+// createMessageStream sends a streaming request and returns the event stream
+func (c *claudeClient) createMessageStream(ctx context.Context, request claudeRequest) (*claudeStream, error) {
+	request.Stream = true
+
+	resp, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claudeStream{
+		body:   resp.Body,
+		reader: bufio.NewReader(resp.Body),
+	}, nil
+}
+
+// send performs the HTTP request and converts API errors into Go errors
+func (c *claudeClient) send(ctx context.Context, request claudeRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		var errorBody struct {
+			Error claudeAPIError `json:"error"`
+		}
+		if json.Unmarshal(data, &errorBody) == nil && errorBody.Error.Message != "" {
+			return nil, fmt.Errorf("status %d, %s: %s", resp.StatusCode, errorBody.Error.Type, errorBody.Error.Message)
+		}
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return resp, nil
+}
+
+// claudeStream reads server-sent events from a streaming response
+type claudeStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// stopped is set by message_stop, a body that ends before it was cut off
+	stopped bool
+}
+
+// Recv returns the next event of the stream, or io.EOF when the message is
+// complete. A stream that ends without message_stop returns an error.
+func (s *claudeStream) Recv() (claudeStreamEvent, error) {
+	if s.stopped {
+		return claudeStreamEvent{}, io.EOF
+	}
+	var data strings.Builder
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF && data.Len() > 0 {
+				return s.decode(data.String())
+			}
+			if err == io.EOF {
+				return claudeStreamEvent{}, fmt.Errorf("the stream ended before the message was complete: %w", io.ErrUnexpectedEOF)
+			}
+			return claudeStreamEvent{}, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		// An empty line terminates the current event
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			return s.decode(data.String())
+		}
+
+		// Only data lines carry the payload, the event name is repeated inside it
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+}
+
+// decode parses an event payload
+func (s *claudeStream) decode(payload string) (claudeStreamEvent, error) {
+	var event claudeStreamEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return claudeStreamEvent{}, fmt.Errorf("failed to decode stream event: %v", err)
+	}
+
+	switch event.Type {
+	case "message_stop":
+		s.stopped = true
+		return event, io.EOF
+	case "error":
+		if event.Error != nil {
+			return event, fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+		}
+		return event, fmt.Errorf("unknown stream error")
+	}
+
+	return event, nil
+}
+
+// Close closes the underlying response body
+func (s *claudeStream) Close() error {
+	return s.body.Close()
+}
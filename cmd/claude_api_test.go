@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeClaudeServer starts a server that checks the request headers and answers
+// every request with handler
+func newFakeClaudeServer(t *testing.T, handler func(w http.ResponseWriter, request claudeRequest)) *claudeClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s, want /v1/messages", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != claudeAPIVersion {
+			t.Errorf("anthropic-version = %q, want %s", got, claudeAPIVersion)
+		}
+		var request claudeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		handler(w, request)
+	}))
+	t.Cleanup(server.Close)
+	return newClaudeClient("test-key", server.URL+"/")
+}
+
+// writeEvents writes server-sent events, each one a name and a JSON payload
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for i := 0; i+1 < len(events); i += 2 {
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", events[i], events[i+1])
+	}
+}
+
+func TestCreateMessage(t *testing.T) {
+	client := newFakeClaudeServer(t, func(w http.ResponseWriter, request claudeRequest) {
+		if request.Stream {
+			t.Error("createMessage sent a streaming request")
+		}
+		if request.Model != "claude-test" || len(request.Messages) != 1 {
+			t.Errorf("unexpected request: %+v", request)
+		}
+		io.WriteString(w, `{"id":"msg_1","role":"assistant","content":[
+			{"type":"text","text":"Listing files"},
+			{"type":"tool_use","id":"toolu_1","name":"execute_command","input":{"command":"ls"}}
+		],"stop_reason":"tool_use","usage":{"input_tokens":12,"output_tokens":7}}`)
+	})
+
+	response, err := client.createMessage(context.Background(), claudeRequest{
+		Model:     "claude-test",
+		MaxTokens: 100,
+		Messages:  []claudeMessage{{Role: "user", Content: []claudeContentBlock{{Type: "text", Text: "hi"}}}},
+	})
+	if err != nil {
+		t.Fatalf("createMessage: %v", err)
+	}
+	if response.StopReason != "tool_use" || response.Usage.InputTokens != 12 || response.Usage.OutputTokens != 7 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	message := fromClaudeContent(response.Content)
+	if message.Content != "Listing files" {
+		t.Errorf("text = %q", message.Content)
+	}
+	if len(message.ToolCalls) != 1 || message.ToolCalls[0].Name != "execute_command" || message.ToolCalls[0].Arguments != `{"command":"ls"}` {
+		t.Errorf("tool calls = %+v", message.ToolCalls)
+	}
+}
+
+func TestSendMapsAPIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"api error", http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, "status 401, authentication_error: invalid x-api-key"},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "status 529, overloaded_error: Overloaded"},
+		{"plain body", http.StatusBadGateway, "bad gateway\n", "status 502: bad gateway"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newFakeClaudeServer(t, func(w http.ResponseWriter, request claudeRequest) {
+				w.WriteHeader(test.status)
+				io.WriteString(w, test.body)
+			})
+			_, err := client.createMessage(context.Background(), claudeRequest{Model: "claude-test"})
+			if err == nil || err.Error() != test.want {
+				t.Errorf("error = %v, want %s", err, test.want)
+			}
+		})
+	}
+}
+
+func TestStreamRecv(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		types   []string
+		wantErr string
+	}{
+		{
+			name: "events until message_stop",
+			body: "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+				": a comment\n\n" +
+				"event: ping\r\ndata: {\"type\":\"ping\"}\r\n\r\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n" +
+				"event: ping\ndata: {\"type\":\"ping\"}\n\n",
+			types: []string{"message_start", "ping"},
+		},
+		{
+			name:  "data split over several lines",
+			body:  "data: {\"type\":\ndata: \"content_block_delta\",\ndata: \"index\": 2}\n\ndata: {\"type\":\"message_stop\"}\n\n",
+			types: []string{"content_block_delta"},
+		},
+		{
+			name:  "last event without a blank line",
+			body:  "data: {\"type\":\"ping\"}\n\ndata: {\"type\":\"message_stop\"}",
+			types: []string{"ping"},
+		},
+		{
+			name:    "connection dropped before message_stop",
+			body:    "event: ping\ndata: {\"type\":\"ping\"}\n\nevent: content_block_delta\ndata: {\"type\":\"content_block_delta\"}\n\n",
+			types:   []string{"ping", "content_block_delta"},
+			wantErr: "the stream ended before the message was complete",
+		},
+		{
+			name:    "error event",
+			body:    "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+			wantErr: "overloaded_error: Overloaded",
+		},
+		{
+			name:    "invalid payload",
+			body:    "data: {not json\n\n",
+			wantErr: "failed to decode stream event",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := io.NopCloser(strings.NewReader(test.body))
+			stream := &claudeStream{body: body, reader: bufio.NewReader(body)}
+
+			var types []string
+			var err error
+			for {
+				var event claudeStreamEvent
+				event, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				types = append(types, event.Type)
+			}
+
+			if test.wantErr == "" && err != io.EOF {
+				t.Errorf("error = %v, want io.EOF", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("error = %v, want %s", err, test.wantErr)
+			}
+			if strings.Join(types, ",") != strings.Join(test.types, ",") {
+				t.Errorf("events = %v, want %v", types, test.types)
+			}
+		})
+	}
+}
+
+func TestProcessStreamAssemblesToolInput(t *testing.T) {
+	client := newFakeClaudeServer(t, func(w http.ResponseWriter, request claudeRequest) {
+		if !request.Stream {
+			t.Error("createMessageStream sent a request without stream")
+		}
+		writeEvents(w,
+			"message_start", `{"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}`,
+			"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"look."}}`,
+			"content_block_stop", `{"type":"content_block_stop","index":0}`,
+			"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\": \"ma"}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"in.go\"}"}}`,
+			"content_block_stop", `{"type":"content_block_stop","index":1}`,
+			"content_block_start", `{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"pwd","input":{}}}`,
+			"content_block_stop", `{"type":"content_block_stop","index":2}`,
+			"message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":40}}`,
+			"message_stop", `{"type":"message_stop"}`,
+		)
+	})
+
+	stream, err := client.createMessageStream(context.Background(), claudeRequest{Model: "claude-test"})
+	if err != nil {
+		t.Fatalf("createMessageStream: %v", err)
+	}
+	defer stream.Close()
+
+	var streamed strings.Builder
+	response, err := (&ClaudeAgent{}).processStream(stream, func(text string) { streamed.WriteString(text) })
+	if err != nil {
+		t.Fatalf("processStream: %v", err)
+	}
+
+	if streamed.String() != "Let me look." || response.Message.Content != "Let me look." {
+		t.Errorf("text = %q, streamed %q", response.Message.Content, streamed.String())
+	}
+	if response.Usage.InputTokens != 25 || response.Usage.OutputTokens != 40 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+	want := []ToolCall{
+		{ID: "toolu_1", Name: "read_file", Arguments: `{"file_path": "main.go"}`},
+		{ID: "toolu_2", Name: "pwd", Arguments: "{}"},
+	}
+	if fmt.Sprint(response.Message.ToolCalls) != fmt.Sprint(want) {
+		t.Errorf("tool calls = %+v, want %+v", response.Message.ToolCalls, want)
+	}
+}
+
+func TestProcessStreamKeepsTextOnError(t *testing.T) {
+	client := newFakeClaudeServer(t, func(w http.ResponseWriter, request claudeRequest) {
+		writeEvents(w,
+			"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"partial"}}`,
+			"error", `{"type":"error","error":{"type":"api_error","message":"Internal server error"}}`,
+		)
+	})
+
+	stream, err := client.createMessageStream(context.Background(), claudeRequest{Model: "claude-test"})
+	if err != nil {
+		t.Fatalf("createMessageStream: %v", err)
+	}
+	defer stream.Close()
+
+	response, err := (&ClaudeAgent{}).processStream(stream, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "api_error: Internal server error") {
+		t.Errorf("error = %v", err)
+	}
+	if response.Message.Content != "partial" {
+		t.Errorf("partial text = %q", response.Message.Content)
+	}
+}
+
+func TestProcessStreamFailsWithoutMessageStop(t *testing.T) {
+	client := newFakeClaudeServer(t, func(w http.ResponseWriter, request claudeRequest) {
+		writeEvents(w,
+			"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"The answer is"}}`,
+		)
+	})
+
+	stream, err := client.createMessageStream(context.Background(), claudeRequest{Model: "claude-test"})
+	if err != nil {
+		t.Fatalf("createMessageStream: %v", err)
+	}
+	defer stream.Close()
+
+	response, err := (&ClaudeAgent{}).processStream(stream, func(string) {})
+	if err == nil || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("a cut off stream was taken for a complete answer: %v", err)
+	}
+	if response.Message.Content != "The answer is" {
+		t.Errorf("partial text = %q", response.Message.Content)
+	}
+}
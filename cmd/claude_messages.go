@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// toClaudeTools converts tool definitions to Claude tools
+func toClaudeTools(tools []ToolDefinition) []claudeTool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	claudeTools := make([]claudeTool, 0, len(tools))
+	for _, tool := range tools {
+		claudeTools = append(claudeTools, claudeTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+
+	return claudeTools
+}
+
+// toClaudeMessages converts the conversation to Claude messages.
+// Tool results are sent as tool_result blocks of a user message and
+// consecutive messages of the same role are merged, as the API expects.
+func toClaudeMessages(conversation *Conversation) []claudeMessage {
+	var messages []claudeMessage
+
+	for _, message := range conversation.Messages {
+		var role string
+		var content []claudeContentBlock
+
+		switch message.Role {
+		case RoleUser:
+			role = "user"
+			content = append(content, claudeContentBlock{Type: "text", Text: message.Content})
+
+		case RoleAssistant:
+			role = "assistant"
+			if message.Content != "" {
+				content = append(content, claudeContentBlock{Type: "text", Text: message.Content})
+			}
+			for _, toolCall := range message.ToolCalls {
+				input := toolCall.Arguments
+				if !json.Valid([]byte(input)) {
+					input = "{}"
+				}
+				content = append(content, claudeContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Name,
+					Input: json.RawMessage(input),
+				})
+			}
+			// The API rejects empty assistant messages
+			if len(content) == 0 {
+				content = append(content, claudeContentBlock{Type: "text", Text: "(no response)"})
+			}
+
+		case RoleTool:
+			role = "user"
+			for _, result := range message.ToolResults {
+				content = append(content, claudeContentBlock{
+					Type:      "tool_result",
+					ToolUseID: result.CallID,
+					Content:   result.Content,
+					IsError:   result.IsError,
+				})
+			}
+		}
+
+		if len(content) == 0 {
+			continue
+		}
+
+		if last := len(messages) - 1; last >= 0 && messages[last].Role == role {
+			messages[last].Content = append(messages[last].Content, content...)
+			continue
+		}
+
+		messages = append(messages, claudeMessage{Role: role, Content: content})
+	}
+
+	return messages
+}
+
+// fromClaudeContent converts the content blocks of a Claude response to a message
+func fromClaudeContent(content []claudeContentBlock) Message {
+	var text strings.Builder
+	var toolCalls []ToolCall
+
+	for _, block := range content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			input := string(block.Input)
+			if input == "" {
+				input = "{}"
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: input,
+			})
+		}
+	}
+
+	return Message{
+		Role:      RoleAssistant,
+		Content:   text.String(),
+		ToolCalls: toolCalls,
+	}
+}
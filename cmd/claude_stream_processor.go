@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// processStream processes the Claude event stream and returns the assistant message
+func (a *ClaudeAgent) processStream(stream *claudeStream, onText func(string)) (ChatResponse, error) {
+	// Variables to collect the response
+	blocks := make(map[int]*claudeContentBlock)
+	toolInputs := make(map[int]string)
+	order := []int{}
+	var usage Usage
+	var text strings.Builder
+	var stopReason string
+
+	// Stream the response
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Keep the text received so far, e.g. when the user pressed Ctrl+C
+			partial := ChatResponse{Message: Message{Role: RoleAssistant, Content: text.String()}, Usage: usage}
+			return partial, fmt.Errorf("stream error: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			// Input tokens are reported when the message starts
+			if event.Message != nil {
+				usage.InputTokens = event.Message.Usage.InputTokens
+				usage.OutputTokens = event.Message.Usage.OutputTokens
+			}
+
+		case "message_delta":
+			// The final output token count and the stop reason come with the last delta
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+
+		case "content_block_start":
+			if event.ContentBlock == nil {
+				continue
+			}
+			block := *event.ContentBlock
+			block.Input = nil
+			blocks[event.Index] = &block
+			order = append(order, event.Index)
+
+		case "content_block_delta":
+			block, ok := blocks[event.Index]
+			if !ok {
+				continue
+			}
+
+			switch event.Delta.Type {
+			case "text_delta":
+				block.Text += event.Delta.Text
+				text.WriteString(event.Delta.Text)
+				onText(event.Delta.Text)
+			case "input_json_delta":
+				toolInputs[event.Index] += event.Delta.PartialJSON
+			}
+		}
+	}
+
+	if stopReason == "max_tokens" {
+		fmt.Printf("\n\033[33mWarning: the answer was cut off at the limit of %d output tokens, raise it with config set anthropic maxtokens\033[0m\n", a.maxTokens)
+	}
+
+	// Assemble the content blocks in the order they were started
+	content := make([]claudeContentBlock, 0, len(order))
+	for _, index := range order {
+		block := blocks[index]
+		if block.Type == "tool_use" {
+			input := toolInputs[index]
+			if input == "" {
+				input = "{}"
+			}
+			block.Input = json.RawMessage(input)
+		}
+		content = append(content, *block)
+	}
+
+	return ChatResponse{Message: fromClaudeContent(content), Usage: usage}, nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"aurora-agent/config"
+)
+
+// commandApproval is the result of running a command through the approval flow
+type commandApproval struct {
+	// Command is the command to run, it may have been edited by the user
+	Command string
+	// Approved is false when the policy or the user refused the command
+	Approved bool
+	// Reason explains the decision, it is sent to the model when the command is refused
+	Reason string
+	// ByUser is set when the user made the decision, not the policy
+	ByUser bool
+}
+
+// approveCommand applies the approval policy to a command the AI wants to run,
+// asking the user when the policy requires it
+func approveCommand(command string) commandApproval {
+	decision := evaluateCommandPolicy(command)
+
+	switch decision.Action {
+	case config.PolicyActionAllow:
+		return commandApproval{Command: command, Approved: true, Reason: decision.Reason}
+	case config.PolicyActionDeny:
+		fmt.Printf("\n\033[31mBlocked command: %s\033[0m\n", command)
+		fmt.Printf("\033[31mReason: denied by %s\033[0m\n", decision.Reason)
+		return commandApproval{Command: command, Reason: "denied by " + decision.Reason}
+	}
+
+	return askCommandApproval(command, decision)
+}
+
+// askCommandApproval asks the user to approve, reject, edit or always allow a command
+func askCommandApproval(command string, decision PolicyDecision) commandApproval {
+	fmt.Printf("\n\033[33mAurora wants to run:\033[0m \033[1m%s\033[0m\n", command)
+	fmt.Printf("Risk: %s \033[90m(%s)\033[0m\n", riskColor(decision.Risk), decision.Reason)
+	printRiskReasons(decision.RiskReasons)
+
+	switch askApproval("\033[33mAllow? [y]es / [n]o / [e]dit / [a]lways: \033[0m", "ynea") {
+	case "y":
+		return commandApproval{Command: command, Approved: true, Reason: "approved by the user", ByUser: true}
+
+	case "a":
+		sessionApprovals[strings.TrimSpace(command)] = true
+		return commandApproval{Command: command, Approved: true, Reason: "approved by the user for this session", ByUser: true}
+
+	case "e":
+		edited, err := askUser("\033[33mEdit command: \033[0m", command)
+		if err != nil || edited == "" {
+			return commandApproval{Command: command, Reason: "rejected by the user", ByUser: true}
+		}
+
+		// Deny rules still apply to the edited command
+		if decision := evaluateCommandPolicy(edited); decision.Action == config.PolicyActionDeny {
+			fmt.Printf("\033[31mBlocked command: %s\033[0m\n", edited)
+			return commandApproval{Command: edited, Reason: "denied by " + decision.Reason}
+		}
+		return commandApproval{Command: edited, Approved: true, Reason: "edited and approved by the user", ByUser: true}
+
+	default:
+		return commandApproval{Command: command, Reason: "rejected by the user", ByUser: true}
+	}
+}
+
+// approvalWords are the whole words accepted for the letters of approval prompts
+var approvalWords = map[string]string{"yes": "y", "no": "n", "edit": "e", "always": "a"}
+
+// askApproval asks an approval question until the answer is one of the letters
+// in choices, or the word it stands for, and returns the letter. An empty answer
+// is n. Ctrl+C at the prompt stops the whole turn, not just this call, and is n too.
+func askApproval(prompt string, choices string) string {
+	for {
+		answer, err := askUser(prompt, "")
+		if err != nil {
+			if AgentMgr != nil {
+				AgentMgr.CancelTurn()
+			}
+			return "n"
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if letter, ok := approvalWords[answer]; ok {
+			answer = letter
+		}
+		switch {
+		case answer == "":
+			return "n"
+		case len(answer) == 1 && strings.Contains(choices, answer):
+			return answer
+		}
+
+		letters := strings.Split(choices, "")
+		if len(letters) > 1 {
+			fmt.Printf("Please answer %s or %s.\n", strings.Join(letters[:len(letters)-1], ", "), letters[len(letters)-1])
+		} else {
+			fmt.Printf("Please answer %s.\n", choices)
+		}
+	}
+}
+
+// printRiskReasons prints why a command got its risk level
+func printRiskReasons(reasons []string) {
+	for _, reason := range reasons {
+		fmt.Printf("\033[90m  - %s\033[0m\n", reason)
+	}
+}
+
+// approveFileChange applies the approval policy to a file the AI wants to write.
+// The diff is always shown, the user is asked when the policy requires it.
+func approveFileChange(path string, diff string) commandApproval {
+	decision := evaluateFileChangePolicy(path)
+
+	fmt.Print("\n" + colorDiff(diff))
+	if decision.Action == config.PolicyActionAllow {
+		return commandApproval{Command: path, Approved: true, Reason: decision.Reason}
+	}
+
+	fmt.Printf("\033[33mAurora wants to change:\033[0m \033[1m%s\033[0m\n", path)
+	fmt.Printf("Risk: %s \033[90m(%s)\033[0m\n", riskColor(decision.Risk), decision.Reason)
+	printRiskReasons(decision.RiskReasons)
+
+	switch askApproval("\033[33mApply? [y]es / [n]o / [a]lways for this file: \033[0m", "yna") {
+	case "y":
+		return commandApproval{Command: path, Approved: true, Reason: "approved by the user", ByUser: true}
+	case "a":
+		sessionFileApprovals[path] = true
+		return commandApproval{Command: path, Approved: true, Reason: "approved by the user for this session", ByUser: true}
+	default:
+		return commandApproval{Command: path, Reason: "rejected by the user", ByUser: true}
+	}
+}
+
+// approveElevation shows a command the AI wants to run as root and asks the user
+// to confirm it, and for the sudo password when there is none. Unlike other
+// commands it is never approved by the policy or for the rest of the session,
+// only deny rules apply without asking.
+func approveElevation(command string) commandApproval {
+	// Deny rules written for sudo commands apply too
+	decision := evaluateCommandPolicy("sudo " + command)
+	if decision.Action == config.PolicyActionDeny {
+		fmt.Printf("\n\033[31mBlocked command: sudo %s\033[0m\n", command)
+		fmt.Printf("\033[31mReason: denied by %s\033[0m\n", decision.Reason)
+		return commandApproval{Command: command, Reason: "denied by " + decision.Reason}
+	}
+
+	fmt.Printf("\n\033[31mAurora wants to run as root:\033[0m \033[1m%s\033[0m\n", command)
+	fmt.Printf("Risk: %s \033[90m(%s)\033[0m\n", riskColor(decision.Risk), decision.Reason)
+	printRiskReasons(decision.RiskReasons)
+	if !SudoEnabled() {
+		fmt.Println("\033[90mSudo mode is off, the sudo password will be asked for\033[0m")
+	}
+
+	if askApproval("\033[31mRun as root? [y]es / [n]o: \033[0m", "yn") != "y" {
+		return commandApproval{Command: command, Reason: "rejected by the user", ByUser: true}
+	}
+	if !SudoEnabled() && !promptSudoPassword() {
+		return commandApproval{Command: command, Reason: "the sudo password was not given", ByUser: true}
+	}
+	return commandApproval{Command: command, Approved: true, Reason: "approved by the user", ByUser: true}
+}
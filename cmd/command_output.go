@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"aurora-agent/config"
+	"fmt"
+	"unicode/utf8"
+)
+
+// headTailBuffer keeps the first head and the last tail bytes written to it,
+// so commands with huge output don't fill the memory. A zero limit keeps everything.
+type headTailBuffer struct {
+	limit config.OutputLimit
+	head  []byte
+	// tail is a ring buffer, start is the position of its oldest byte
+	tail  []byte
+	start int
+	total int64
+}
+
+// newHeadTailBuffer creates a buffer for the given limit
+func newHeadTailBuffer(limit config.OutputLimit) *headTailBuffer {
+	return &headTailBuffer{limit: limit}
+}
+
+// Write implements io.Writer, it never fails
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.total += int64(n)
+
+	unlimited := b.limit.Head <= 0 && b.limit.Tail <= 0
+	if unlimited || len(b.head) < b.limit.Head {
+		take := len(p)
+		if !unlimited && take > b.limit.Head-len(b.head) {
+			take = b.limit.Head - len(b.head)
+		}
+		b.head = append(b.head, p[:take]...)
+		p = p[take:]
+	}
+	if len(p) == 0 || b.limit.Tail <= 0 {
+		return n, nil
+	}
+
+	// Only the last tail bytes of p can end up in the tail
+	if len(p) >= b.limit.Tail {
+		b.tail = append(b.tail[:0], p[len(p)-b.limit.Tail:]...)
+		b.start = 0
+		return n, nil
+	}
+	for _, c := range p {
+		if len(b.tail) < b.limit.Tail {
+			b.tail = append(b.tail, c)
+			continue
+		}
+		b.tail[b.start] = c
+		b.start = (b.start + 1) % len(b.tail)
+	}
+	return n, nil
+}
+
+// Truncated reports whether bytes were dropped
+func (b *headTailBuffer) Truncated() bool {
+	return b.total > int64(len(b.head)+len(b.tail))
+}
+
+// String returns the kept output with a note in place of the dropped middle
+func (b *headTailBuffer) String() string {
+	tail := append(append([]byte{}, b.tail[b.start:]...), b.tail[:b.start]...)
+	if !b.Truncated() {
+		return string(b.head) + string(tail)
+	}
+	return joinTruncated(string(b.head), string(tail), b.total)
+}
+
+// joinTruncated puts a note about the bytes dropped from an output of total bytes
+// between head and tail, runes split at the cut are dropped too
+func joinTruncated(head string, tail string, total int64) string {
+	for len(head) > 0 {
+		if r, size := utf8.DecodeLastRuneInString(head); r != utf8.RuneError || size != 1 {
+			break
+		}
+		head = head[:len(head)-1]
+	}
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	dropped := total - int64(len(head)+len(tail))
+	return fmt.Sprintf("%s\n[... %d bytes truncated ...]\n%s", head, dropped, tail)
+}
+
+// truncateHeadTail cuts the middle of text to the limit and reports whether it did
+func truncateHeadTail(text string, limit config.OutputLimit) (string, bool) {
+	if limit.Head <= 0 && limit.Tail <= 0 || len(text) <= limit.Head+limit.Tail {
+		return text, false
+	}
+	head := text[:max(limit.Head, 0)]
+	tail := text[len(text)-max(limit.Tail, 0):]
+	return joinTruncated(head, tail, int64(len(text))), true
+}
+
+// toolOutputLimit returns the output limit of a tool, zero when it is not capped
+func toolOutputLimit(functionName string) config.OutputLimit {
+	return config.CurrentConfig.Tools.OutputLimits[functionName]
+}
+
+// capToolOutput cuts the output of a tool to its configured limit
+func capToolOutput(result FunctionCallResult) FunctionCallResult {
+	var truncated bool
+	result.Output, truncated = truncateHeadTail(result.Output, toolOutputLimit(result.Name))
+	result.OutputTruncated = result.OutputTruncated || truncated
+	return result
+}
+
+// formatOutputLimit describes a limit for people
+func formatOutputLimit(limit config.OutputLimit) string {
+	if limit.Head <= 0 && limit.Tail <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("first %d and last %d bytes", max(limit.Head, 0), max(limit.Tail, 0))
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// commandSegment is a single simple command of a command line
+type commandSegment struct {
+	Text string
+	// Argv holds the words of the command with quotes and redirections removed
+	Argv []string
+	// Redirects is set when the output of the command is redirected to a file
+	Redirects bool
+	// OutputFiles lists the files the output of the command is redirected to
+	OutputFiles []string
+	// PipedFrom lists the names of the commands whose output is piped into this one
+	PipedFrom []string
+}
+
+// parseCommandSegments parses a command line with a bash parser and returns every
+// simple command in it, including the ones inside pipelines, lists, subshells,
+// command substitutions and control structures.
+// Leading variable assignments (FOO=bar cmd) are not part of Argv.
+func parseCommandSegments(command string) ([]commandSegment, error) {
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []commandSegment
+	pipeInputs := make(map[*syntax.Stmt][]string)
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.BinaryCmd:
+			if node.Op == syntax.Pipe || node.Op == syntax.PipeAll {
+				pipeInputs[node.Y] = append(commandNames(node.X), pipeInputs[node.Y]...)
+			}
+
+		case *syntax.Stmt:
+			segment := commandSegment{
+				Text:      nodeText(node),
+				PipedFrom: pipeInputs[node],
+			}
+
+			switch cmd := node.Cmd.(type) {
+			case *syntax.CallExpr:
+				for _, word := range cmd.Args {
+					segment.Argv = append(segment.Argv, wordText(word))
+				}
+			case *syntax.DeclClause:
+				segment.Argv = append(segment.Argv, cmd.Variant.Value)
+				for _, assign := range cmd.Args {
+					segment.Argv = append(segment.Argv, nodeText(assign))
+				}
+			case *syntax.BinaryCmd:
+				// The first command of a nested pipeline reads the outer pipe
+				if inputs := pipeInputs[node]; len(inputs) > 0 {
+					pipeInputs[cmd.X] = append(pipeInputs[cmd.X], inputs...)
+				}
+			}
+
+			for _, redirect := range node.Redirs {
+				if target, ok := outputRedirectTarget(redirect); ok {
+					segment.Redirects = true
+					segment.OutputFiles = append(segment.OutputFiles, target)
+				}
+			}
+
+			if len(segment.Argv) > 0 || segment.Redirects {
+				segments = append(segments, segment)
+			}
+		}
+		return true
+	})
+
+	return segments, nil
+}
+
+// outputRedirectTarget returns the file a redirection writes to.
+// Duplicated file descriptors (>&2) and /dev/null don't count as writes.
+func outputRedirectTarget(redirect *syntax.Redirect) (string, bool) {
+	switch redirect.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrAll, syntax.AppAll, syntax.ClbOut, syntax.RdrInOut:
+	default:
+		return "", false
+	}
+	if redirect.Word == nil {
+		return "", false
+	}
+
+	target := wordText(redirect.Word)
+	switch target {
+	case "", "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return "", false
+	}
+	return target, true
+}
+
+// commandNames returns the names of all simple commands inside a node
+func commandNames(node syntax.Node) []string {
+	var names []string
+	syntax.Walk(node, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+			names = append(names, wordText(call.Args[0]))
+		}
+		return true
+	})
+	return names
+}
+
+// wordText returns a word with its quotes removed.
+// Expansions such as $HOME or $(cmd) are kept as written.
+func wordText(word *syntax.Word) string {
+	var text strings.Builder
+	writeWordParts(&text, word.Parts, false)
+	return text.String()
+}
+
+// writeWordParts writes the unquoted text of word parts
+func writeWordParts(text *strings.Builder, parts []syntax.WordPart, quoted bool) {
+	for _, part := range parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			text.WriteString(unescapeLiteral(part.Value, quoted))
+		case *syntax.SglQuoted:
+			text.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			writeWordParts(text, part.Parts, true)
+		default:
+			text.WriteString(nodeText(part))
+		}
+	}
+}
+
+// unescapeLiteral removes the backslashes bash would remove from a literal
+func unescapeLiteral(value string, quoted bool) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var text strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			// Inside double quotes only a few characters can be escaped
+			if !quoted || strings.IndexByte("$`\"\\\n", value[i+1]) >= 0 {
+				i++
+			}
+		}
+		text.WriteByte(value[i])
+	}
+	return text.String()
+}
+
+// nodeText prints a syntax node back as shell source
+func nodeText(node syntax.Node) string {
+	var buf bytes.Buffer
+	if err := syntax.NewPrinter().Print(&buf, node); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
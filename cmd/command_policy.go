@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"aurora-agent/config"
+)
+
+// PolicyDecision is the outcome of checking a command against the approval policy
+type PolicyDecision struct {
+	// Action is one of config.PolicyActionAllow, PolicyActionDeny or PolicyActionAsk
+	Action string
+	// Reason explains which rule or mode produced the decision
+	Reason string
+	// Risk is the risk level of the command and RiskReasons explains it
+	Risk        string
+	RiskReasons []string
+}
+
+// sessionApprovals holds commands the user answered "always" for in this session
+var sessionApprovals = map[string]bool{}
+
+// evaluateCommandPolicy decides whether a command the AI wants to run is allowed,
+// must be confirmed by the user or is refused.
+//
+// Deny rules always win. Commands approved with "always" come next, then ask
+// and allow rules, and finally the policy mode decides based on the risk level
+// of the command.
+func evaluateCommandPolicy(command string) PolicyDecision {
+	policy := config.CurrentConfig.Policy
+	command = strings.TrimSpace(command)
+	segments, parseErr := parseCommandSegments(command)
+	risk := classifySegments(segments, parseErr)
+
+	decide := func(action string, reason string) PolicyDecision {
+		return PolicyDecision{Action: action, Reason: reason, Risk: risk.Level, RiskReasons: risk.Reasons}
+	}
+
+	for _, rule := range policy.Rules {
+		if rule.Action == config.PolicyActionDeny && ruleMatchesAny(rule, command, segments) {
+			return decide(config.PolicyActionDeny, ruleDescription(rule))
+		}
+	}
+
+	if sessionApprovals[command] {
+		return decide(config.PolicyActionAllow, "approved earlier in this session")
+	}
+
+	for _, rule := range policy.Rules {
+		if rule.Action == config.PolicyActionAsk && ruleMatchesAny(rule, command, segments) {
+			return decide(config.PolicyActionAsk, ruleDescription(rule))
+		}
+	}
+
+	if allowedByRules(policy.Rules, segments) {
+		return decide(config.PolicyActionAllow, "matches an allow rule")
+	}
+
+	return decide(decideByRisk(policy.Mode, risk.Level, "command"))
+}
+
+// decideByRisk applies the policy mode to the risk level of a command or file change
+func decideByRisk(mode string, level string, subject string) (action string, reason string) {
+	// The lowest risk level the mode asks about
+	var askFrom string
+	switch mode {
+	case config.PolicyModeAuto:
+		askFrom = config.RiskDestructive
+	case config.PolicyModeAlwaysAsk:
+		return config.PolicyActionAsk, "policy mode is always-ask"
+	default:
+		askFrom = config.RiskWritesFiles
+	}
+
+	if riskAtLeast(level, askFrom) {
+		return config.PolicyActionAsk, fmt.Sprintf("%s %s in %s mode", level, subject, mode)
+	}
+	return config.PolicyActionAllow, fmt.Sprintf("%s %s in %s mode", level, subject, mode)
+}
+
+// sessionFileApprovals holds files the user answered "always" for in this session
+var sessionFileApprovals = map[string]bool{}
+
+// evaluateFileChangePolicy decides whether the AI may write a file without asking.
+// Writing a file has the writes-files risk, or privileged inside system directories.
+func evaluateFileChangePolicy(path string) PolicyDecision {
+	policy := config.CurrentConfig.Policy
+
+	risk := commandRisk{Level: config.RiskWritesFiles}
+	if isSystemPath(path) {
+		risk.raise(config.RiskPrivileged, "writes to the system path "+path)
+	}
+
+	if sessionFileApprovals[path] {
+		return PolicyDecision{Action: config.PolicyActionAllow, Reason: "approved earlier in this session", Risk: risk.Level, RiskReasons: risk.Reasons}
+	}
+
+	action, reason := decideByRisk(policy.Mode, risk.Level, "file change")
+	return PolicyDecision{Action: action, Reason: reason, Risk: risk.Level, RiskReasons: risk.Reasons}
+}
+
+// ruleMatchesAny reports whether a deny or ask rule matches the command or any part of it
+func ruleMatchesAny(rule config.PolicyRule, command string, segments []commandSegment) bool {
+	// A regex can describe a whole pipeline, e.g. curl ... | sh
+	if rule.Regex != "" && matchRuleRegex(rule.Regex, command) {
+		return true
+	}
+
+	for _, segment := range segments {
+		if ruleMatchesSegment(rule, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedByRules reports whether every part of the command is matched by an allow rule.
+// Requiring all parts keeps "git status; rm -rf x" from passing an allow rule for git status.
+func allowedByRules(rules []config.PolicyRule, segments []commandSegment) bool {
+	if len(segments) == 0 {
+		return false
+	}
+
+	for _, segment := range segments {
+		allowed := false
+		for _, rule := range rules {
+			if rule.Action == config.PolicyActionAllow && ruleMatchesSegment(rule, segment) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleMatchesSegment reports whether a rule matches a single simple command
+func ruleMatchesSegment(rule config.PolicyRule, segment commandSegment) bool {
+	if rule.Prefix != "" && matchesWordPrefix(segment.Text, rule.Prefix) {
+		return true
+	}
+	if rule.Regex != "" && matchRuleRegex(rule.Regex, segment.Text) {
+		return true
+	}
+	if len(rule.Argv) > 0 && matchArgvPattern(rule.Argv, segment.Argv) {
+		return true
+	}
+	return false
+}
+
+// matchesWordPrefix reports whether text starts with prefix on a word boundary
+func matchesWordPrefix(text string, prefix string) bool {
+	if !strings.HasPrefix(text, prefix) {
+		return false
+	}
+	rest := text[len(prefix):]
+	return rest == "" || strings.HasSuffix(prefix, " ") || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// regexCache avoids recompiling rule regexes for every command
+var regexCache = map[string]*regexp.Regexp{}
+
+// matchRuleRegex matches text against a rule regex, invalid regexes never match
+func matchRuleRegex(pattern string, text string) bool {
+	re, ok := regexCache[pattern]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			fmt.Printf("\033[31mWarning: invalid policy regex %q: %v\033[0m\n", pattern, err)
+		}
+		regexCache[pattern] = re
+	}
+	return re != nil && re.MatchString(text)
+}
+
+// matchArgvPattern matches parsed arguments against a pattern where "*" matches
+// any single argument and a trailing "**" matches any remaining arguments
+func matchArgvPattern(pattern []string, argv []string) bool {
+	for i, expected := range pattern {
+		if expected == "**" {
+			return true
+		}
+		if i >= len(argv) {
+			return false
+		}
+		if expected != "*" && expected != argv[i] {
+			return false
+		}
+	}
+	return len(argv) == len(pattern)
+}
+
+// ruleDescription describes a rule for the user and the model
+func ruleDescription(rule config.PolicyRule) string {
+	var matcher string
+	switch {
+	case rule.Prefix != "":
+		matcher = fmt.Sprintf("prefix %q", rule.Prefix)
+	case rule.Regex != "":
+		matcher = fmt.Sprintf("regex %q", rule.Regex)
+	default:
+		matcher = fmt.Sprintf("argv %q", strings.Join(rule.Argv, " "))
+	}
+
+	if rule.Reason != "" {
+		return fmt.Sprintf("%s rule (%s): %s", rule.Action, matcher, rule.Reason)
+	}
+	return fmt.Sprintf("%s rule (%s)", rule.Action, matcher)
+}
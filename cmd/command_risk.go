@@ -0,0 +1,708 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"aurora-agent/config"
+)
+
+// commandRisk is the risk level of a command line and the reasons it got that level
+type commandRisk struct {
+	Level   string
+	Reasons []string
+	// Elevators are the commands found that run others with elevated
+	// privileges (sudo, doas, pkexec, su), however deeply they are nested
+	Elevators []string
+}
+
+// riskRanks orders the risk levels from least to most dangerous
+var riskRanks = map[string]int{
+	config.RiskReadOnly:    0,
+	config.RiskWritesFiles: 1,
+	config.RiskDestructive: 2,
+	config.RiskPrivileged:  3,
+}
+
+// maxRiskDepth limits how deep nested scripts (bash -c, eval, sudo ...) are classified
+const maxRiskDepth = 4
+
+// riskAtLeast reports whether level is as dangerous as minimum or more
+func riskAtLeast(level string, minimum string) bool {
+	return riskRanks[level] >= riskRanks[minimum]
+}
+
+// raise raises the risk to level and records why.
+// Reasons are only kept for levels above read-only.
+func (r *commandRisk) raise(level string, reason string) {
+	if riskRanks[level] > riskRanks[r.Level] {
+		r.Level = level
+	}
+	if reason == "" || level == config.RiskReadOnly {
+		return
+	}
+	for _, existing := range r.Reasons {
+		if existing == reason {
+			return
+		}
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// elevate records a command that runs others with elevated privileges
+func (r *commandRisk) elevate(name string) {
+	for _, existing := range r.Elevators {
+		if existing == name {
+			return
+		}
+	}
+	r.Elevators = append(r.Elevators, name)
+}
+
+// classifyCommand parses a command line and returns its risk level
+func classifyCommand(command string) commandRisk {
+	segments, err := parseCommandSegments(command)
+	return classifySegments(segments, err)
+}
+
+// classifySegments returns the risk level of parsed commands.
+// A command that can't be parsed is treated as destructive so it is never run unseen.
+func classifySegments(segments []commandSegment, parseErr error) commandRisk {
+	risk := commandRisk{Level: config.RiskReadOnly}
+	if parseErr != nil {
+		risk.raise(config.RiskDestructive, fmt.Sprintf("the command could not be parsed: %v", parseErr))
+		return risk
+	}
+
+	risks := commandRiskTable()
+	for _, segment := range segments {
+		classifySegment(&risk, segment, risks, 0)
+	}
+	return risk
+}
+
+// classifyScript classifies a script passed to another command, e.g. bash -c '...'
+func classifyScript(risk *commandRisk, script string, risks map[string]string, depth int) {
+	if depth >= maxRiskDepth {
+		risk.raise(config.RiskDestructive, "commands are nested too deeply to be checked")
+		return
+	}
+
+	segments, err := parseCommandSegments(script)
+	if err != nil {
+		risk.raise(config.RiskDestructive, fmt.Sprintf("the script %q could not be parsed: %v", script, err))
+		return
+	}
+	for _, segment := range segments {
+		classifySegment(risk, segment, risks, depth+1)
+	}
+}
+
+// classifySegment raises the risk for one simple command and its redirections
+func classifySegment(risk *commandRisk, segment commandSegment, risks map[string]string, depth int) {
+	for _, target := range segment.OutputFiles {
+		raiseWriteTarget(risk, "writes output to", target)
+	}
+
+	if len(segment.Argv) > 0 {
+		classifyArgv(risk, segment.Argv, segment.PipedFrom, risks, depth)
+	}
+}
+
+// raiseWriteTarget raises the risk for a file a command writes to, files in
+// system paths make it privileged. what describes the write, like "writes output to".
+func raiseWriteTarget(risk *commandRisk, what string, target string) {
+	switch target {
+	case "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return
+	}
+	if isSystemPath(target) {
+		risk.raise(config.RiskPrivileged, fmt.Sprintf("%s the system path %s", what, target))
+	} else {
+		risk.raise(config.RiskWritesFiles, fmt.Sprintf("%s %s", what, target))
+	}
+}
+
+// classifyArgv raises the risk for a command and its arguments
+func classifyArgv(risk *commandRisk, argv []string, pipedFrom []string, risks map[string]string, depth int) {
+	if depth >= maxRiskDepth {
+		risk.raise(config.RiskDestructive, "commands are nested too deeply to be checked")
+		return
+	}
+
+	name := path.Base(argv[0])
+	args := argv[1:]
+
+	if strings.ContainsAny(argv[0], "$`") {
+		risk.raise(config.RiskWritesFiles, fmt.Sprintf("the command name %s is only known when it runs", argv[0]))
+		return
+	}
+
+	// Commands that run another command
+	switch name {
+	case "sudo", "doas", "pkexec":
+		risk.raise(config.RiskPrivileged, fmt.Sprintf("%s runs the command with elevated privileges", name))
+		risk.elevate(name)
+		if inner := skipOptions(args, "ugCDhprtU", 0); len(inner) > 0 {
+			classifyArgv(risk, inner, pipedFrom, risks, depth+1)
+		}
+		return
+
+	case "su":
+		risk.raise(config.RiskPrivileged, "su runs commands as another user")
+		risk.elevate(name)
+		for i, arg := range args {
+			if (arg == "-c" || arg == "--command") && i+1 < len(args) {
+				classifyScript(risk, args[i+1], risks, depth)
+			}
+		}
+		return
+
+	case "env", "nice", "nohup", "time", "stdbuf", "ionice", "timeout", "xargs", "watch", "exec", "builtin", "command":
+		inner := wrappedCommand(name, args)
+		if len(inner) == 0 {
+			if name == "xargs" {
+				// xargs runs echo when no command is given
+				return
+			}
+			classifyKnownCommand(risk, argv, risks)
+			return
+		}
+		classifyArgv(risk, inner, pipedFrom, risks, depth+1)
+		return
+
+	case "strace", "ltrace":
+		// Tracers run a command and write the trace to the file of -o
+		options, _ := parseOptions(args, wrapperOptions[name].withValue, "--output")
+		for _, option := range options {
+			if option.Name == "-o" || option.Name == "--output" {
+				raiseWriteTarget(risk, name+" -o writes its trace to", option.Value)
+			}
+		}
+		if inner := wrappedCommand(name, args); len(inner) > 0 {
+			classifyArgv(risk, inner, pipedFrom, risks, depth+1)
+		} else {
+			classifyKnownCommand(risk, argv, risks)
+		}
+		return
+
+	case "bash", "sh", "zsh", "dash", "ksh", "fish":
+		classifyShell(risk, name, args, pipedFrom, risks, depth)
+		return
+
+	case "eval":
+		classifyScript(risk, strings.Join(args, " "), risks, depth)
+		return
+	}
+
+	level := classifyKnownCommand(risk, argv, risks)
+
+	// Arguments that change what a command does
+	switch name {
+	case "rm":
+		if hasShortOption(args, 'r', "--recursive") || hasShortOption(args, 'R', "--recursive") {
+			if hasShortOption(args, 'f', "--force") {
+				risk.raise(config.RiskDestructive, "rm -rf removes directories recursively without asking")
+			} else {
+				risk.raise(config.RiskDestructive, "rm -r removes directories recursively")
+			}
+		}
+
+	case "find":
+		for i, arg := range args {
+			switch arg {
+			case "-delete":
+				risk.raise(config.RiskDestructive, "find -delete removes the files it finds")
+			case "-fprint", "-fprint0", "-fprintf", "-fls":
+				risk.raise(config.RiskWritesFiles, fmt.Sprintf("find %s writes to a file", arg))
+			case "-exec", "-execdir", "-ok", "-okdir":
+				inner := args[i+1:]
+				for j, word := range inner {
+					if word == ";" || word == "+" {
+						inner = inner[:j]
+						break
+					}
+				}
+				if len(inner) > 0 {
+					classifyArgv(risk, inner, nil, risks, depth+1)
+				}
+			}
+		}
+
+	case "sed":
+		if hasShortOption(args, 'i', "--in-place") {
+			level = config.RiskWritesFiles
+			risk.raise(level, "sed -i edits files in place")
+		}
+
+	case "curl":
+		options, _ := parseOptions(args, curlValueOptions, curlLongValueOptions...)
+		for _, option := range options {
+			switch option.Name {
+			case "-o", "--output":
+				raiseWriteTarget(risk, "curl saves the download to", option.Value)
+			case "-D", "--dump-header":
+				raiseWriteTarget(risk, "curl saves the headers to", option.Value)
+			case "-c", "--cookie-jar":
+				raiseWriteTarget(risk, "curl saves cookies to", option.Value)
+			case "-O", "--remote-name", "--remote-name-all", "-J", "--remote-header-name":
+				risk.raise(config.RiskWritesFiles, "curl saves the download to a file")
+			case "-d", "-F", "-T", "--data", "--data-raw", "--data-binary", "--data-urlencode", "--data-ascii",
+				"--form", "--form-string", "--json", "--upload-file":
+				risk.raise(config.RiskWritesFiles, "curl sends data to a server")
+			}
+		}
+
+	case "sort":
+		options, _ := parseOptions(args, "kotST", "--output", "--key", "--field-separator", "--temporary-directory", "--buffer-size")
+		for _, option := range options {
+			if option.Name == "-o" || option.Name == "--output" {
+				raiseWriteTarget(risk, "sort -o writes to", option.Value)
+			}
+		}
+
+	case "date":
+		options, _ := parseOptions(args, "dfrs", "--date", "--file", "--reference", "--set")
+		for _, option := range options {
+			if option.Name == "-s" || option.Name == "--set" {
+				risk.raise(config.RiskPrivileged, "date -s sets the system clock")
+			}
+		}
+
+	case "awk", "gawk", "mawk", "nawk":
+		classifyAwk(risk, name, args)
+
+	case "git":
+		classifyGit(risk, args)
+	}
+
+	// Writing commands become privileged when they touch system paths
+	if riskAtLeast(level, config.RiskWritesFiles) {
+		for _, target := range writeTargets(name, args) {
+			if isSystemPath(target) {
+				risk.raise(config.RiskPrivileged, fmt.Sprintf("%s modifies the system path %s", name, target))
+			}
+		}
+	}
+}
+
+// awkSideEffects matches awk programs that run commands or write files:
+// system(), print > file, print | "command", "command" | getline and |& coprocesses
+var awkSideEffects = regexp.MustCompile(`\bsystem\s*\(|\bprintf?\b[^;}\n]*[>|]|\|\s*getline|\|&`)
+
+// classifyAwk raises the risk of awk programs that run commands or write files
+func classifyAwk(risk *commandRisk, name string, args []string) {
+	options, operands := parseOptions(args, "fvFiEl", "--file", "--assign", "--field-separator", "--include", "--exec", "--load")
+	for _, option := range options {
+		switch option.Name {
+		case "-f", "--file", "-E", "--exec", "-i", "--include":
+			risk.raise(config.RiskWritesFiles, fmt.Sprintf("%s runs the program file %s", name, option.Value))
+			return
+		}
+	}
+	if len(operands) > 0 && awkSideEffects.MatchString(operands[0]) {
+		risk.raise(config.RiskWritesFiles, fmt.Sprintf("the %s program runs commands or writes files", name))
+	}
+}
+
+// gitRemoteChanges are the git remote subcommands that change the remotes
+var gitRemoteChanges = map[string]bool{
+	"add": true, "remove": true, "rm": true, "rename": true, "set-url": true, "set-head": true,
+	"set-branches": true, "prune": true, "update": true,
+}
+
+// gitBranchListing are the git branch options that list branches, names
+// after them are patterns rather than new branches
+var gitBranchListing = map[string]bool{
+	"-l": true, "--list": true, "-a": true, "--all": true, "-r": true, "--remotes": true,
+	"--contains": true, "--no-contains": true, "--merged": true, "--no-merged": true,
+	"--points-at": true, "--show-current": true,
+}
+
+// classifyGit raises the risk of git subcommands the risk table lists as
+// read-only when their arguments change the repository or write files
+func classifyGit(risk *commandRisk, args []string) {
+	rest := skipOptions(args, "Cc", 0)
+	if len(rest) == 0 {
+		return
+	}
+	subcommand := rest[0]
+	options, operands := parseOptions(rest[1:], "u", "--output", "--set-upstream-to",
+		"--contains", "--no-contains", "--points-at", "--format", "--sort")
+
+	for _, option := range options {
+		if option.Name == "--output" {
+			raiseWriteTarget(risk, fmt.Sprintf("git %s --output writes to", subcommand), option.Value)
+		}
+	}
+
+	switch subcommand {
+	case "branch":
+		listing := false
+		for _, option := range options {
+			switch option.Name {
+			case "-D":
+				risk.raise(config.RiskDestructive, "git branch -D deletes a branch even when it is not merged")
+			case "-d", "--delete":
+				if hasShortOption(rest[1:], 'f', "--force") {
+					risk.raise(config.RiskDestructive, "git branch -d --force deletes a branch even when it is not merged")
+				} else {
+					risk.raise(config.RiskWritesFiles, "git branch -d deletes a branch")
+				}
+			case "-m", "-M", "--move", "-c", "-C", "--copy":
+				risk.raise(config.RiskWritesFiles, fmt.Sprintf("git branch %s renames or copies a branch", option.Name))
+			case "-u", "--set-upstream-to", "--unset-upstream", "--edit-description", "-t", "--track", "-f", "--force":
+				risk.raise(config.RiskWritesFiles, fmt.Sprintf("git branch %s changes a branch", option.Name))
+			}
+			if gitBranchListing[option.Name] {
+				listing = true
+			}
+		}
+		if !listing && len(operands) > 0 {
+			risk.raise(config.RiskWritesFiles, fmt.Sprintf("git branch %s creates a branch", operands[0]))
+		}
+
+	case "remote":
+		if len(operands) > 0 && gitRemoteChanges[operands[0]] {
+			risk.raise(config.RiskWritesFiles, fmt.Sprintf("git remote %s changes the remotes", operands[0]))
+		}
+	}
+}
+
+// commandOption is an option given to a command, with its value if it takes one
+type commandOption struct {
+	Name  string
+	Value string
+}
+
+// parseOptions splits the arguments of a command into options and operands,
+// options end at --. Short options may be combined (-sSL); the ones in
+// shortValues take the rest of the argument or the next one as their value
+// (-ofile, -sSo file). Long options take a value after = or, when listed in
+// longValues, from the next argument.
+func parseOptions(args []string, shortValues string, longValues ...string) ([]commandOption, []string) {
+	var options []commandOption
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return options, append(operands, args[i+1:]...)
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			if !hasValue && slices.Contains(longValues, name) && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			options = append(options, commandOption{Name: name, Value: value})
+
+		case len(arg) > 1 && arg[0] == '-':
+			for j := 1; j < len(arg); j++ {
+				name := "-" + arg[j:j+1]
+				if !strings.ContainsRune(shortValues, rune(arg[j])) {
+					options = append(options, commandOption{Name: name})
+					continue
+				}
+				value := arg[j+1:]
+				if value == "" && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				options = append(options, commandOption{Name: name, Value: value})
+				break
+			}
+
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return options, operands
+}
+
+// writeTargets returns the arguments a writing command may modify.
+// Copying commands only write to their last argument.
+func writeTargets(name string, args []string) []string {
+	var targets []string
+	for _, arg := range args {
+		switch {
+		case name == "dd":
+			if strings.HasPrefix(arg, "of=") {
+				targets = append(targets, strings.TrimPrefix(arg, "of="))
+			}
+		case !strings.HasPrefix(arg, "-"):
+			targets = append(targets, arg)
+		}
+	}
+
+	switch name {
+	case "cp", "mv", "ln", "install", "rsync", "scp":
+		if len(targets) > 1 {
+			return targets[len(targets)-1:]
+		}
+	}
+	return targets
+}
+
+// classifyKnownCommand raises the risk to the level of the command in the risk table
+// and returns that level. Unknown commands are assumed to write files.
+func classifyKnownCommand(risk *commandRisk, argv []string, risks map[string]string) string {
+	key, level, found := lookupCommandRisk(argv, risks)
+	if !found {
+		// mkfs.ext4 and fsck.vfat are variants of mkfs and fsck
+		if family, _, ok := strings.Cut(path.Base(argv[0]), "."); ok && family != "" {
+			key, level, found = lookupCommandRisk(append([]string{family}, argv[1:]...), risks)
+		}
+	}
+	if !found {
+		risk.raise(config.RiskWritesFiles, fmt.Sprintf("%s is not a known command", path.Base(argv[0])))
+		return config.RiskWritesFiles
+	}
+
+	risk.raise(level, fmt.Sprintf("%s is classified as %s", key, level))
+	return level
+}
+
+// classifyShell classifies a shell started with a script, a script file or standard input
+func classifyShell(risk *commandRisk, name string, args []string, pipedFrom []string, risks map[string]string, depth int) {
+	for i, arg := range args {
+		if arg == "-c" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.HasSuffix(arg, "c")) {
+			if i+1 < len(args) {
+				classifyScript(risk, args[i+1], risks, depth)
+				return
+			}
+		}
+		if !strings.HasPrefix(arg, "-") {
+			risk.raise(config.RiskWritesFiles, fmt.Sprintf("%s runs the script %s", name, arg))
+			return
+		}
+	}
+
+	// The shell reads its commands from standard input
+	for _, source := range pipedFrom {
+		switch path.Base(source) {
+		case "curl", "wget", "fetch", "nc":
+			risk.raise(config.RiskDestructive, fmt.Sprintf("runs a script downloaded by %s without reviewing it", path.Base(source)))
+			return
+		}
+	}
+	risk.raise(config.RiskWritesFiles, fmt.Sprintf("%s runs commands from its standard input", name))
+}
+
+// wrapperOptions lists, for commands that run another command, the short options
+// that take a value and how many positional arguments come before the command
+var wrapperOptions = map[string]struct {
+	withValue   string
+	positionals int
+}{
+	"env":     {withValue: "uCS"},
+	"nice":    {withValue: "n"},
+	"nohup":   {},
+	"time":    {withValue: "fo"},
+	"stdbuf":  {withValue: "ioe"},
+	"ionice":  {withValue: "cnp"},
+	"timeout": {withValue: "sk", positionals: 1},
+	"xargs":   {withValue: "IdnPLsaEe"},
+	"watch":   {withValue: "nd"},
+	"exec":    {withValue: "a"},
+	"builtin": {},
+	"command": {},
+	"strace":  {withValue: "abeEIoOpPsSuUX"},
+	"ltrace":  {withValue: "aAeFnopsuwxDl"},
+}
+
+// curlValueOptions are the short options of curl that take a value
+const curlValueOptions = "AbcCdDeEFHKmoPQrtTuUwxXyYz"
+
+// curlLongValueOptions are the long options of curl whose value may be the next argument
+var curlLongValueOptions = []string{
+	"--output", "--dump-header", "--cookie-jar", "--data", "--data-raw", "--data-binary", "--data-urlencode",
+	"--data-ascii", "--form", "--form-string", "--json", "--upload-file", "--header", "--user", "--request",
+	"--user-agent", "--cookie", "--referer", "--proxy", "--url", "--config", "--max-time", "--write-out",
+}
+
+// wrappedCommand returns the command run by a wrapper such as env or timeout
+func wrappedCommand(name string, args []string) []string {
+	options := wrapperOptions[name]
+	inner := skipOptions(args, options.withValue, options.positionals)
+
+	// env also takes variable assignments before the command
+	if name == "env" {
+		for len(inner) > 0 && strings.Contains(inner[0], "=") {
+			inner = inner[1:]
+		}
+	}
+	// command -v and -V only look the command up
+	if name == "command" && len(args) > 0 && (args[0] == "-v" || args[0] == "-V") {
+		return nil
+	}
+	return inner
+}
+
+// skipOptions skips leading options and returns the remaining arguments.
+// withValue lists the short options that take a value, it is the next argument
+// when the option ends the argument.
+func skipOptions(args []string, withValue string, positionals int) []string {
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		// -n 10 and -fo file take the next argument, -n10 and --name=value don't
+		if !strings.HasPrefix(arg, "--") {
+			for j := 1; j < len(arg); j++ {
+				if strings.ContainsRune(withValue, rune(arg[j])) {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+		}
+		i++
+	}
+
+	if i+positionals > len(args) {
+		return nil
+	}
+	return args[i+positionals:]
+}
+
+// hasShortOption reports whether a short option is set, alone or combined (-rf),
+// or its long form is given
+func hasShortOption(args []string, option byte, long string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if long != "" && (arg == long || strings.HasPrefix(arg, long+"=")) {
+			return true
+		}
+		if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], option) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupCommandRisk finds the most specific entry of the risk table for a command.
+// Entries that lower the risk of a command ("git status") must match the leading
+// arguments exactly, entries that raise it ("git push --force") may have their
+// options anywhere in the arguments.
+func lookupCommandRisk(argv []string, risks map[string]string) (string, string, bool) {
+	name := path.Base(argv[0])
+	base, baseFound := risks[name]
+
+	bestKey, bestLevel := name, base
+	bestWords := 0
+	if baseFound {
+		bestWords = 1
+	}
+
+	for key, level := range risks {
+		words := strings.Fields(key)
+		if len(words) < 2 || words[0] != name || len(words) < bestWords {
+			continue
+		}
+		if len(words) == bestWords && (riskRanks[level] < riskRanks[bestLevel] ||
+			riskRanks[level] == riskRanks[bestLevel] && key > bestKey) {
+			continue
+		}
+
+		loose := baseFound && riskRanks[level] > riskRanks[base]
+		if matchRiskKey(words[1:], argv[1:], loose) {
+			bestKey, bestLevel, bestWords = key, level, len(words)
+		}
+	}
+
+	return bestKey, bestLevel, bestWords > 0
+}
+
+// matchRiskKey matches the words of a risk table key after the command name
+func matchRiskKey(words []string, args []string, loose bool) bool {
+	if !loose {
+		return len(args) >= len(words) && strings.Join(args[:len(words)], " ") == strings.Join(words, " ")
+	}
+
+	// Subcommands must come first, options may appear anywhere after them
+	i := 0
+	for _, word := range words {
+		if strings.HasPrefix(word, "-") {
+			found := false
+			for _, arg := range args[i:] {
+				if arg == word {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+
+		for i < len(args) && strings.HasPrefix(args[i], "-") {
+			i++
+		}
+		if i >= len(args) || args[i] != word {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// commandRiskTable returns the built-in risk levels merged with the configured ones.
+// Configured read-only commands and command risks override the built-in levels.
+func commandRiskTable() map[string]string {
+	policy := config.CurrentConfig.Policy
+	risks := make(map[string]string, len(config.DefaultCommandRisks)+len(policy.CommandRisks))
+	for command, level := range config.DefaultCommandRisks {
+		risks[command] = level
+	}
+	for _, command := range policy.ReadOnlyCommands {
+		risks[strings.Join(strings.Fields(command), " ")] = config.RiskReadOnly
+	}
+	for command, level := range policy.CommandRisks {
+		if _, ok := riskRanks[level]; !ok {
+			fmt.Printf("\033[31mWarning: unknown risk level %q for %q in policy.command_risks\033[0m\n", level, command)
+			continue
+		}
+		risks[strings.Join(strings.Fields(command), " ")] = level
+	}
+	return risks
+}
+
+// isSystemPath reports whether an absolute path is inside a system directory
+func isSystemPath(target string) bool {
+	if !strings.HasPrefix(target, "/") {
+		return false
+	}
+	target = path.Clean(target)
+	for _, prefix := range config.SystemPathPrefixes {
+		if target == prefix || strings.HasPrefix(target, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// riskColor returns the risk level colored for display
+func riskColor(level string) string {
+	switch level {
+	case config.RiskReadOnly:
+		return "\033[32m" + level + "\033[0m"
+	case config.RiskWritesFiles:
+		return "\033[33m" + level + "\033[0m"
+	default:
+		return "\033[31m" + level + "\033[0m"
+	}
+}
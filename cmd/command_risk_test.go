@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"testing"
+
+	"aurora-agent/config"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	useTestHome(t)
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"git status", config.RiskReadOnly},
+		{"git log --oneline -n 5", config.RiskReadOnly},
+		{"git diff HEAD~1", config.RiskReadOnly},
+		{"git branch", config.RiskReadOnly},
+		{"git branch -a --contains abc123", config.RiskReadOnly},
+		{"git branch --list 'feature/*'", config.RiskReadOnly},
+		{"git remote -v", config.RiskReadOnly},
+		{"git remote show origin", config.RiskReadOnly},
+		{"git branch -D main", config.RiskDestructive},
+		{"git branch -d --force topic", config.RiskDestructive},
+		{"git branch -d topic", config.RiskWritesFiles},
+		{"git branch -m old new", config.RiskWritesFiles},
+		{"git branch -M main", config.RiskWritesFiles},
+		{"git branch topic", config.RiskWritesFiles},
+		{"git branch -u origin/main", config.RiskWritesFiles},
+		{"git remote remove origin", config.RiskWritesFiles},
+		{"git remote add upstream https://example.com/repo.git", config.RiskWritesFiles},
+		{"git remote rename origin old", config.RiskWritesFiles},
+		{"git remote set-url origin https://example.com/repo.git", config.RiskWritesFiles},
+		{"git log --output=x", config.RiskWritesFiles},
+		{"git diff --output=/tmp/x", config.RiskWritesFiles},
+		{"git diff --output /etc/motd", config.RiskPrivileged},
+		{"strace ls", config.RiskReadOnly},
+		{"strace -p 1234", config.RiskReadOnly},
+		{"strace -o /dev/null rm -rf foo", config.RiskDestructive},
+		{"strace -fo trace.txt ls", config.RiskWritesFiles},
+		{"ltrace -o out.txt cat file", config.RiskWritesFiles},
+		{"ltrace sudo ls", config.RiskPrivileged},
+		{"awk '{print $1}' file", config.RiskReadOnly},
+		{"awk -F'|' '$2 > 10 {print $1}' file", config.RiskReadOnly},
+		{`awk '{system("rm -rf /tmp/x")}'`, config.RiskWritesFiles},
+		{`awk '{print $1 > "out.txt"}' file`, config.RiskWritesFiles},
+		{`awk '{print | "sh"}' file`, config.RiskWritesFiles},
+		{`awk 'BEGIN {"date" | getline d; print d}'`, config.RiskWritesFiles},
+		{"awk -f script.awk file", config.RiskWritesFiles},
+		{"curl https://example.com", config.RiskReadOnly},
+		{"curl -sSL https://example.com", config.RiskReadOnly},
+		{"curl -H 'Accept: text/html' https://example.com", config.RiskReadOnly},
+		{"curl -sSo page.html https://example.com", config.RiskWritesFiles},
+		{"curl --output=page.html https://example.com", config.RiskWritesFiles},
+		{"curl -opage.html https://example.com", config.RiskWritesFiles},
+		{"curl -sSLO https://example.com/file.tar.gz", config.RiskWritesFiles},
+		{"curl -o /usr/local/bin/tool https://example.com/tool", config.RiskPrivileged},
+		{"curl -d name=value https://example.com", config.RiskWritesFiles},
+		{"sort file", config.RiskReadOnly},
+		{"sort -o sorted.txt file", config.RiskWritesFiles},
+		{"sort -uo sorted.txt file", config.RiskWritesFiles},
+		{"sort --output=sorted.txt file", config.RiskWritesFiles},
+		{"date +%s", config.RiskReadOnly},
+		{"date -d yesterday", config.RiskReadOnly},
+		{"date -s '2026-01-01 00:00'", config.RiskPrivileged},
+		{"date --set=12:00", config.RiskPrivileged},
+		{"nice -n10 ls", config.RiskReadOnly},
+		{"timeout -s KILL 5 rm -rf build", config.RiskDestructive},
+		{"ls > /dev/null", config.RiskReadOnly},
+	}
+	for _, test := range tests {
+		if got := classifyCommand(test.command); got.Level != test.want {
+			t.Errorf("classifyCommand(%q) = %s %v, want %s", test.command, got.Level, got.Reasons, test.want)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	options, operands := parseOptions([]string{"-sSo", "out", "-Hx: y", "--data=a", "--json", "{}", "url", "--", "-v"},
+		curlValueOptions, curlLongValueOptions...)
+	want := []commandOption{{"-s", ""}, {"-S", ""}, {"-o", "out"}, {"-H", "x: y"}, {"--data", "a"}, {"--json", "{}"}}
+	if len(options) != len(want) {
+		t.Fatalf("options = %v, want %v", options, want)
+	}
+	for i := range want {
+		if options[i] != want[i] {
+			t.Errorf("option %d = %v, want %v", i, options[i], want[i])
+		}
+	}
+	if len(operands) != 2 || operands[0] != "url" || operands[1] != "-v" {
+		t.Errorf("operands = %v", operands)
+	}
+}
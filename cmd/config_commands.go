@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"aurora-agent/config"
@@ -60,6 +62,8 @@ func processConfigCommand(input string) bool {
 				fmt.Printf("\033[31mError: Failed to load configuration: %v\033[0m\n", err)
 			} else {
 				fmt.Println("\033[32mConfiguration loaded successfully\033[0m")
+				// Apply the new settings to the agents, the conversation is kept
+				AgentMgr.ReloadAgents()
 			}
 			return true
 
@@ -139,18 +143,97 @@ func setConfigValue(section, key, value string) {
 			config.CurrentConfig.OpenAI.APIKey = value
 			fmt.Println("\033[32mOpenAI.APIKey updated\033[0m")
 			// if api key is changed, reload agent
-			AgentMgr = NewAgentManager()
+			AgentMgr.ReloadAgents()
 		case "model":
-			config.CurrentConfig.OpenAI.Model = value
+			config.SetOpenAIModel(value)
 			fmt.Printf("\033[32mOpenAI.Model = %s\033[0m\n", value)
 			// Update the model in the active agent
 			if agent, ok := AgentMgr.activeAgent.(*OpenAIAgent); ok {
 				agent.SetModel(value)
 			}
+		case "baseurl":
+			config.CurrentConfig.OpenAI.BaseURL = value
+			fmt.Printf("\033[32mOpenAI.BaseURL = %s\033[0m\n", value)
+			// if base url is changed, reload agent
+			AgentMgr.ReloadAgents()
+		case "endpoint":
+			if err := config.SetOpenAIEndpoint(value); err != nil {
+				fmt.Printf("\033[31mError: %v\033[0m\n", err)
+				return
+			}
+			fmt.Printf("\033[32mOpenAI.Endpoint = %s\033[0m\n", value)
+			// if endpoint is changed, reload agent
+			AgentMgr.ReloadAgents()
+		case "streamusage":
+			switch strings.ToLower(value) {
+			case "true", "on", "yes":
+				config.SetOpenAIStreamUsage(true)
+			case "false", "off", "no":
+				config.SetOpenAIStreamUsage(false)
+			default:
+				fmt.Println("\033[31mError: StreamUsage must be true or false\033[0m")
+				return
+			}
+			fmt.Printf("\033[32mOpenAI.StreamUsage = %s\033[0m\n", strings.ToLower(value))
+			// the agent reads it when it is created
+			AgentMgr.ReloadAgents()
+		case "header":
+			// value is "<Header-Name> [value]", an empty value removes the header
+			parts := strings.SplitN(value, " ", 2)
+			if parts[0] == "" {
+				fmt.Println("\033[31mError: Wrong format. Use: config set openai header <name> [value]\033[0m")
+				return
+			}
+			if config.CurrentConfig.OpenAI.Headers == nil {
+				config.CurrentConfig.OpenAI.Headers = map[string]string{}
+			}
+			if len(parts) == 1 || parts[1] == "" {
+				delete(config.CurrentConfig.OpenAI.Headers, parts[0])
+				fmt.Printf("\033[32mOpenAI.Headers[%s] removed\033[0m\n", parts[0])
+			} else {
+				config.CurrentConfig.OpenAI.Headers[parts[0]] = parts[1]
+				fmt.Printf("\033[32mOpenAI.Headers[%s] updated\033[0m\n", parts[0])
+			}
+			// if headers are changed, reload agent
+			AgentMgr.ReloadAgents()
 		default:
 			fmt.Printf("\033[31mError: '%s' key not found in OpenAI section\033[0m\n", key)
 		}
 
+	case "anthropic":
+		switch strings.ToLower(key) {
+		case "apikey":
+			config.CurrentConfig.Anthropic.APIKey = value
+			fmt.Println("\033[32mAnthropic.APIKey updated\033[0m")
+			// if api key is changed, reload agent
+			AgentMgr.ReloadAgents()
+		case "model":
+			config.CurrentConfig.Anthropic.Model = value
+			fmt.Printf("\033[32mAnthropic.Model = %s\033[0m\n", value)
+			// Update the model in the Claude agent
+			if agent, ok := AgentMgr.agents[Claude].(*ClaudeAgent); ok {
+				agent.SetModel(value)
+			}
+		case "maxtokens":
+			var maxTokens int
+			if _, err := fmt.Sscanf(value, "%d", &maxTokens); err != nil || maxTokens <= 0 {
+				fmt.Println("\033[31mError: MaxTokens must be a positive integer\033[0m")
+				return
+			}
+			config.CurrentConfig.Anthropic.MaxTokens = maxTokens
+			fmt.Printf("\033[32mAnthropic.MaxTokens = %d\033[0m\n", maxTokens)
+			if agent, ok := AgentMgr.agents[Claude].(*ClaudeAgent); ok {
+				agent.maxTokens = maxTokens
+			}
+		case "baseurl":
+			config.CurrentConfig.Anthropic.BaseURL = value
+			fmt.Printf("\033[32mAnthropic.BaseURL = %s\033[0m\n", value)
+			// if base url is changed, reload agent
+			AgentMgr.ReloadAgents()
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Anthropic section\033[0m\n", key)
+		}
+
 	case "interface":
 		switch strings.ToLower(key) {
 		case "theme":
@@ -159,14 +242,242 @@ func setConfigValue(section, key, value string) {
 		case "systemprompt":
 			config.CurrentConfig.Interface.SystemPrompt = value
 			fmt.Printf("\033[32mInterface.SystemPrompt = %s\033[0m\n", value)
-			// if system prompt is changed, reload agent
-			AgentMgr = NewAgentManager()
+			// if system prompt is changed, reload agents, the conversation is kept
+			AgentMgr.ReloadAgents()
 		default:
 			fmt.Printf("\033[31mError: '%s' key not found in Interface section\033[0m\n", key)
 		}
 
+	case "policy":
+		switch strings.ToLower(key) {
+		case "mode":
+			switch value {
+			case config.PolicyModeAuto, config.PolicyModeAskOnWrite, config.PolicyModeAlwaysAsk:
+				config.CurrentConfig.Policy.Mode = value
+				fmt.Printf("\033[32mPolicy.Mode = %s\033[0m\n", value)
+			default:
+				fmt.Printf("\033[31mError: Mode must be one of %s, %s, %s\033[0m\n",
+					config.PolicyModeAuto, config.PolicyModeAskOnWrite, config.PolicyModeAlwaysAsk)
+				return
+			}
+		case "outside":
+			switch value {
+			case config.PolicyActionAsk, config.PolicyActionDeny, config.PolicyActionAllow:
+				config.CurrentConfig.Policy.Paths.Outside = value
+				fmt.Printf("\033[32mPolicy.Paths.Outside = %s\033[0m\n", value)
+			default:
+				fmt.Printf("\033[31mError: Outside must be one of %s, %s, %s\033[0m\n",
+					config.PolicyActionAsk, config.PolicyActionDeny, config.PolicyActionAllow)
+				return
+			}
+		case "root":
+			if value == "" {
+				fmt.Println("\033[31mError: Root must be a directory\033[0m")
+				return
+			}
+			config.CurrentConfig.Policy.Paths.Roots = append(config.CurrentConfig.Policy.Paths.Roots, value)
+			fmt.Printf("\033[32mAdded workspace root: %s\033[0m\n", value)
+		case "denypath":
+			if value == "" {
+				fmt.Println("\033[31mError: DenyPath must be a glob\033[0m")
+				return
+			}
+			config.CurrentConfig.Policy.Paths.Deny = append(config.CurrentConfig.Policy.Paths.Deny, value)
+			fmt.Printf("\033[32mAdded denied path: %s\033[0m\n", value)
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Policy section\033[0m\n", key)
+		}
+
+	case "context":
+		switch strings.ToLower(key) {
+		case "summarize":
+			switch strings.ToLower(value) {
+			case "true", "on", "yes":
+				config.CurrentConfig.Context.Summarize = true
+			case "false", "off", "no":
+				config.CurrentConfig.Context.Summarize = false
+			default:
+				fmt.Println("\033[31mError: Summarize must be true or false\033[0m")
+				return
+			}
+			fmt.Printf("\033[32mContext.Summarize = %t\033[0m\n", config.CurrentConfig.Context.Summarize)
+		case "budget", "compactat", "keepturns", "tooloutputlimit":
+			var number int
+			if _, err := fmt.Sscanf(value, "%d", &number); err != nil || number < 0 {
+				fmt.Printf("\033[31mError: %s must be a non-negative integer\033[0m\n", key)
+				return
+			}
+			switch strings.ToLower(key) {
+			case "budget":
+				config.CurrentConfig.Context.Budget = number
+			case "compactat":
+				if number < 1 || number > 100 {
+					fmt.Println("\033[31mError: CompactAt must be a percentage between 1 and 100\033[0m")
+					return
+				}
+				config.CurrentConfig.Context.CompactAt = number
+			case "keepturns":
+				config.CurrentConfig.Context.KeepTurns = number
+			case "tooloutputlimit":
+				config.CurrentConfig.Context.ToolOutputLimit = number
+			}
+			fmt.Printf("\033[32mContext.%s = %d\033[0m\n", key, number)
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Context section\033[0m\n", key)
+		}
+
+	case "usage":
+		switch strings.ToLower(key) {
+		case "sessionbudget", "dailybudget":
+			budget, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil || budget < 0 {
+				fmt.Printf("\033[31mError: %s must be an amount in USD, 0 disables it\033[0m\n", key)
+				return
+			}
+			if strings.ToLower(key) == "sessionbudget" {
+				config.CurrentConfig.Usage.SessionBudget = budget
+				fmt.Printf("\033[32mUsage.SessionBudget = $%.2f\033[0m\n", budget)
+			} else {
+				config.CurrentConfig.Usage.DailyBudget = budget
+				fmt.Printf("\033[32mUsage.DailyBudget = $%.2f\033[0m\n", budget)
+			}
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Usage section\033[0m\n", key)
+		}
+
+	case "checkpoints":
+		switch strings.ToLower(key) {
+		case "enabled", "scancommands":
+			var enabled bool
+			switch strings.ToLower(value) {
+			case "true", "on", "yes":
+				enabled = true
+			case "false", "off", "no":
+				enabled = false
+			default:
+				fmt.Printf("\033[31mError: %s must be true or false\033[0m\n", key)
+				return
+			}
+			if strings.ToLower(key) == "enabled" {
+				config.CurrentConfig.Checkpoints.Enabled = enabled
+				fmt.Printf("\033[32mCheckpoints.Enabled = %t\033[0m\n", enabled)
+			} else {
+				config.CurrentConfig.Checkpoints.ScanCommands = enabled
+				fmt.Printf("\033[32mCheckpoints.ScanCommands = %t\033[0m\n", enabled)
+			}
+		case "maxcheckpoints", "maxfiles", "maxfilesize":
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || number < 0 {
+				fmt.Printf("\033[31mError: %s must be a non-negative integer\033[0m\n", key)
+				return
+			}
+			switch strings.ToLower(key) {
+			case "maxcheckpoints":
+				config.CurrentConfig.Checkpoints.MaxCheckpoints = int(number)
+			case "maxfiles":
+				config.CurrentConfig.Checkpoints.MaxFiles = int(number)
+			case "maxfilesize":
+				config.CurrentConfig.Checkpoints.MaxFileSize = number
+			}
+			fmt.Printf("\033[32mCheckpoints.%s = %d\033[0m\n", key, number)
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Checkpoints section\033[0m\n", key)
+		}
+
+	case "tools":
+		switch strings.ToLower(key) {
+		case "outputlimit":
+			// value is "<tool> <head> [tail]", 0 for both removes the limit
+			parts := strings.Fields(value)
+			if len(parts) < 2 || len(parts) > 3 {
+				fmt.Println("\033[31mError: Wrong format. Use: config set tools outputlimit <tool> <head bytes> [tail bytes]\033[0m")
+				return
+			}
+			var limit config.OutputLimit
+			var err error
+			limit.Head, err = strconv.Atoi(parts[1])
+			if err == nil && len(parts) == 3 {
+				limit.Tail, err = strconv.Atoi(parts[2])
+			}
+			if err != nil || limit.Head < 0 || limit.Tail < 0 {
+				fmt.Println("\033[31mError: head and tail must be non-negative integers\033[0m")
+				return
+			}
+			if config.CurrentConfig.Tools.OutputLimits == nil {
+				config.CurrentConfig.Tools.OutputLimits = map[string]config.OutputLimit{}
+			}
+			if limit.Head == 0 && limit.Tail == 0 {
+				delete(config.CurrentConfig.Tools.OutputLimits, parts[0])
+				fmt.Printf("\033[32mTools.OutputLimits[%s] removed\033[0m\n", parts[0])
+				return
+			}
+			config.CurrentConfig.Tools.OutputLimits[parts[0]] = limit
+			fmt.Printf("\033[32mTools.OutputLimits[%s] = %s\033[0m\n", parts[0], formatOutputLimit(limit))
+		case "commandtimeout", "maxcommandtimeout":
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				fmt.Printf("\033[31mError: %s must be a number of seconds, 0 disables it\033[0m\n", key)
+				return
+			}
+			if strings.ToLower(key) == "commandtimeout" {
+				config.CurrentConfig.Tools.CommandTimeout = seconds
+				fmt.Printf("\033[32mTools.CommandTimeout = %d\033[0m\n", seconds)
+			} else {
+				config.CurrentConfig.Tools.MaxCommandTimeout = seconds
+				fmt.Printf("\033[32mTools.MaxCommandTimeout = %d\033[0m\n", seconds)
+			}
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Tools section\033[0m\n", key)
+		}
+
+	case "sudo":
+		switch strings.ToLower(key) {
+		case "idletimeout":
+			minutes, err := strconv.Atoi(value)
+			if err != nil || minutes < 0 {
+				fmt.Printf("\033[31mError: %s must be a number of minutes, 0 disables it\033[0m\n", key)
+				return
+			}
+			config.CurrentConfig.Sudo.IdleTimeout = minutes
+			fmt.Printf("\033[32mSudo.IdleTimeout = %d\033[0m\n", minutes)
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Sudo section\033[0m\n", key)
+		}
+
+	case "redaction":
+		switch strings.ToLower(key) {
+		case "enabled", "highentropy":
+			var enabled bool
+			switch strings.ToLower(value) {
+			case "true", "on", "yes":
+				enabled = true
+			case "false", "off", "no":
+				enabled = false
+			default:
+				fmt.Printf("\033[31mError: %s must be true or false\033[0m\n", key)
+				return
+			}
+			if strings.ToLower(key) == "enabled" {
+				config.CurrentConfig.Redaction.Enabled = enabled
+				fmt.Printf("\033[32mRedaction.Enabled = %t\033[0m\n", enabled)
+			} else {
+				config.CurrentConfig.Redaction.HighEntropy = enabled
+				fmt.Printf("\033[32mRedaction.HighEntropy = %t\033[0m\n", enabled)
+			}
+		case "pattern":
+			// value is a regular expression added to the patterns
+			if _, err := regexp.Compile(value); err != nil || value == "" {
+				fmt.Println("\033[31mError: Wrong format. Use: config set redaction pattern <regular expression>\033[0m")
+				return
+			}
+			config.CurrentConfig.Redaction.Patterns = append(config.CurrentConfig.Redaction.Patterns, value)
+			fmt.Printf("\033[32mRedaction.Patterns += %s\033[0m\n", value)
+		default:
+			fmt.Printf("\033[31mError: '%s' key not found in Redaction section\033[0m\n", key)
+		}
+
 	default:
-		fmt.Printf("\033[31mError: '%s' section not found. Available sections: General, OpenAI, Interface\033[0m\n", section)
+		fmt.Printf("\033[31mError: '%s' section not found. Available sections: General, OpenAI, Anthropic, Interface, Policy, Context, Usage, Checkpoints, Tools, Sudo, Redaction\033[0m\n", section)
 	}
 
 	fmt.Println("\033[33mNote: Remember to save changes using 'config save'\033[0m")
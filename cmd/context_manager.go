@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"aurora-agent/config"
+)
+
+// ContextUsage describes how much of the context budget the conversation uses
+type ContextUsage struct {
+	Model       string
+	Window      int
+	Budget      int
+	Used        int
+	Messages    int
+	Compactions int
+}
+
+const (
+	// messageOverheadTokens approximates the role and formatting tokens of a message
+	messageOverheadTokens = 4
+	// defaultResponseReserve is kept free for the response when the provider doesn't limit it
+	defaultResponseReserve = 4096
+)
+
+// summaryPrompt is the system prompt used to summarize earlier turns
+const summaryPrompt = `You summarize the earlier part of a conversation between a user and Aurora, a terminal assistant that runs shell commands and reads files.
+Write a concise summary that lets Aurora continue the conversation without the original messages. Keep:
+- what the user asked for and any preferences or decisions they stated
+- commands that were run and the important facts learned from their output (paths, versions, errors, configuration values)
+- files that were read or changed
+- work that is still open
+Leave out greetings and output that no longer matters. Answer with the summary only.`
+
+// charsPerToken returns the average number of characters per token of a model family.
+// Tokenizers differ per provider, the estimate errs on the side of more tokens.
+func charsPerToken(model string) float64 {
+	if strings.HasPrefix(strings.ToLower(model), "claude") {
+		return 3.5
+	}
+	return 4
+}
+
+// estimateTokens estimates the number of tokens of text for a model
+func estimateTokens(model string, text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(float64(len(text))/charsPerToken(model)) + 1
+}
+
+// estimateMessageTokens estimates the tokens a message uses in a request
+func estimateMessageTokens(model string, message Message) int {
+	tokens := messageOverheadTokens + estimateTokens(model, message.Content)
+	for _, call := range message.ToolCalls {
+		tokens += messageOverheadTokens + estimateTokens(model, call.Name) + estimateTokens(model, call.Arguments)
+	}
+	for _, result := range message.ToolResults {
+		tokens += messageOverheadTokens + estimateTokens(model, result.Content)
+	}
+	return tokens
+}
+
+// estimateConversationTokens estimates the tokens of a request with the conversation and tools
+func estimateConversationTokens(model string, conversation *Conversation, tools []ToolDefinition) int {
+	tokens := estimateTokens(model, conversation.SystemPrompt)
+	for _, message := range conversation.Messages {
+		tokens += estimateMessageTokens(model, message)
+	}
+	if len(tools) > 0 {
+		definitions, _ := json.Marshal(tools)
+		tokens += estimateTokens(model, string(definitions))
+	}
+	return tokens
+}
+
+// contextBudget returns the number of tokens the request may use, leaving room for the response
+func contextBudget(provider ChatProvider) (window int, budget int) {
+	window = config.GetContextWindow(provider.Model())
+
+	reserve := provider.MaxOutputTokens()
+	if reserve <= 0 {
+		reserve = defaultResponseReserve
+	}
+	if reserve > window/2 {
+		reserve = window / 2
+	}
+	budget = window - reserve
+
+	if configured := config.CurrentConfig.Context.Budget; configured > 0 && configured < budget {
+		budget = configured
+	}
+	return window, budget
+}
+
+// contextUsage reports the context usage of the conversation with the agent's model
+func (a *agentCore) contextUsage() ContextUsage {
+	window, budget := contextBudget(a.provider)
+	return ContextUsage{
+		Model:       a.provider.Model(),
+		Window:      window,
+		Budget:      budget,
+		Used:        estimateConversationTokens(a.provider.Model(), a.conversation, getToolDefinitions()),
+		Messages:    len(a.conversation.Messages),
+		Compactions: a.conversation.Compactions,
+	}
+}
+
+// manageContext compacts the conversation when it gets close to the context budget
+func (a *agentCore) manageContext(ctx context.Context, tools []ToolDefinition) {
+	settings := config.CurrentConfig.Context
+	_, budget := contextBudget(a.provider)
+	used := estimateConversationTokens(a.provider.Model(), a.conversation, tools)
+
+	compactAt := settings.CompactAt
+	if compactAt <= 0 || compactAt > 100 {
+		compactAt = 100
+	}
+	if used <= budget*compactAt/100 {
+		return
+	}
+
+	a.compactContext(ctx, tools, false)
+}
+
+// compactContext shrinks the conversation. The system prompt and the most recent
+// turns are pinned, older tool outputs are truncated first, then older turns are
+// summarized and, if the conversation still doesn't fit, dropped.
+// force summarizes even when truncating was enough.
+func (a *agentCore) compactContext(ctx context.Context, tools []ToolDefinition, force bool) (before int, after int) {
+	settings := config.CurrentConfig.Context
+	model := a.provider.Model()
+	_, budget := contextBudget(a.provider)
+	target := budget * 60 / 100
+
+	before = estimateConversationTokens(model, a.conversation, tools)
+	used := func() int {
+		return estimateConversationTokens(model, a.conversation, tools)
+	}
+
+	keepFrom := pinnedTurnsStart(a.conversation.Messages, settings.KeepTurns)
+
+	// 1. Truncate the output of older tool calls
+	truncateToolOutputs(a.conversation.Messages[:keepFrom], settings.ToolOutputLimit)
+
+	// 2. Summarize older turns with the model
+	if keepFrom > 0 && settings.Summarize && (force || used() > target) {
+		if err := a.summarizeTurns(ctx, keepFrom, budget); err != nil {
+			fmt.Printf("\033[31mWarning: could not summarize the conversation: %v\033[0m\n", err)
+		}
+	}
+
+	// 3. Truncate the tool outputs of all but the current turn
+	if used() > budget {
+		if starts := turnStarts(a.conversation.Messages); len(starts) > 0 {
+			truncateToolOutputs(a.conversation.Messages[:starts[len(starts)-1]], settings.ToolOutputLimit)
+		}
+	}
+
+	// 4. Drop the oldest turns, keeping the summary and the current turn
+	for used() > budget {
+		starts := turnStarts(a.conversation.Messages)
+		if len(starts) < 2 {
+			break
+		}
+		var kept []Message
+		if a.conversation.Messages[0].Summary {
+			kept = append(kept, a.conversation.Messages[0])
+		}
+		a.conversation.Messages = append(kept, a.conversation.Messages[starts[1]:]...)
+	}
+
+	// 5. As a last resort truncate the tool outputs of the current turn too
+	if used() > budget {
+		truncateToolOutputs(a.conversation.Messages, settings.ToolOutputLimit)
+	}
+
+	after = used()
+	if after < before {
+		a.conversation.Compactions++
+		fmt.Printf("\033[90m(Context compacted: %d -> %d tokens)\033[0m\n", before, after)
+	}
+	return before, after
+}
+
+// summarizeTurns replaces the messages before end with a summary written by the model
+func (a *agentCore) summarizeTurns(ctx context.Context, end int, budget int) error {
+	// Keep the transcript well within the budget of the summary request
+	transcript := renderTranscript(a.conversation.Messages[:end], config.CurrentConfig.Context.ToolOutputLimit)
+	maxChars := int(float64(budget/2) * charsPerToken(a.provider.Model()))
+	transcript = truncateMiddle(transcript, maxChars)
+
+	request := NewConversation(summaryPrompt)
+	request.AddUserMessage(transcript)
+
+	response, err := a.provider.Complete(ctx, request, nil)
+	if err != nil {
+		return err
+	}
+	// Summaries are paid for like any other request, the budget is checked by the tool loop
+	_ = a.recordUsage(request, nil, response)
+	if strings.TrimSpace(response.Message.Content) == "" {
+		return fmt.Errorf("the model returned an empty summary")
+	}
+
+	summary := Message{
+		Role:    RoleUser,
+		Content: "Summary of the earlier conversation:\n" + strings.TrimSpace(response.Message.Content),
+		Summary: true,
+	}
+	a.conversation.Messages = append([]Message{summary}, a.conversation.Messages[end:]...)
+	return nil
+}
+
+// turnStarts returns the indexes of the user messages that start a turn.
+// A summary of earlier turns belongs to the turn after it.
+func turnStarts(messages []Message) []int {
+	var starts []int
+	for i, message := range messages {
+		if message.Role == RoleUser && !message.Summary {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// pinnedTurnsStart returns the index of the first message of the last keep turns
+func pinnedTurnsStart(messages []Message, keep int) int {
+	if keep < 1 {
+		keep = 1
+	}
+	starts := turnStarts(messages)
+	if len(starts) <= keep {
+		return 0
+	}
+	return starts[len(starts)-keep]
+}
+
+// truncateToolOutputs shortens tool outputs longer than limit characters
+func truncateToolOutputs(messages []Message, limit int) {
+	if limit <= 0 {
+		return
+	}
+	for i := range messages {
+		for j := range messages[i].ToolResults {
+			result := &messages[i].ToolResults[j]
+			result.Content = truncateMiddle(result.Content, limit)
+		}
+	}
+}
+
+// truncateMiddle keeps the start and the end of text when it is longer than limit
+func truncateMiddle(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+
+	head := limit * 2 / 3
+	tail := limit - head
+	return fmt.Sprintf("%s\n[... %d characters truncated to save context ...]\n%s",
+		strings.ToValidUTF8(text[:head], ""), len(text)-head-tail, strings.ToValidUTF8(text[len(text)-tail:], ""))
+}
+
+// renderTranscript writes messages as plain text for the summary request
+func renderTranscript(messages []Message, toolOutputLimit int) string {
+	var transcript strings.Builder
+	for _, message := range messages {
+		switch message.Role {
+		case RoleUser:
+			if message.Summary {
+				fmt.Fprintf(&transcript, "Earlier summary: %s\n\n", message.Content)
+			} else {
+				fmt.Fprintf(&transcript, "User: %s\n\n", message.Content)
+			}
+		case RoleAssistant:
+			if message.Content != "" {
+				fmt.Fprintf(&transcript, "Aurora: %s\n\n", message.Content)
+			}
+			for _, call := range message.ToolCalls {
+				fmt.Fprintf(&transcript, "Aurora called %s(%s)\n\n", call.Name, call.Arguments)
+			}
+		case RoleTool:
+			for _, result := range message.ToolResults {
+				fmt.Fprintf(&transcript, "Result of %s: %s\n\n", result.Name, truncateMiddle(result.Content, toolOutputLimit))
+			}
+		}
+	}
+	return transcript.String()
+}
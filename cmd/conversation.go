@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// MessageRole is the author of a conversation message
+type MessageRole string
+
+const (
+	// RoleUser is a message written by the user
+	RoleUser MessageRole = "user"
+	// RoleAssistant is a message produced by the model
+	RoleAssistant MessageRole = "assistant"
+	// RoleTool is a message carrying tool results back to the model
+	RoleTool MessageRole = "tool"
+)
+
+// ToolCall is a request from the model to run a tool
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolResult is the output of a tool call sent back to the model
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Message is a provider independent conversation message
+type Message struct {
+	Role        MessageRole  `json:"role"`
+	Content     string       `json:"content,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+	// Summary is set on the message that replaced earlier turns when the context was compacted
+	Summary bool `json:"summary,omitempty"`
+}
+
+// Conversation holds the system prompt and the message history shared by all providers
+type Conversation struct {
+	SystemPrompt string    `json:"system_prompt"`
+	Messages     []Message `json:"messages"`
+	// Compactions counts how often the history was compacted to fit the context budget
+	Compactions int `json:"compactions,omitempty"`
+}
+
+// NewConversation creates an empty conversation with the given system prompt
+func NewConversation(systemPrompt string) *Conversation {
+	return &Conversation{
+		SystemPrompt: systemPrompt,
+		Messages:     []Message{},
+	}
+}
+
+// AddUserMessage adds a user message to the history
+func (c *Conversation) AddUserMessage(content string) {
+	c.Messages = append(c.Messages, Message{
+		Role:    RoleUser,
+		Content: content,
+	})
+}
+
+// AddAssistantMessage adds a model response, including any tool calls, to the history
+func (c *Conversation) AddAssistantMessage(message Message) {
+	message.Role = RoleAssistant
+	c.Messages = append(c.Messages, message)
+}
+
+// AddToolResults adds the results of the tool calls of the previous assistant message
+func (c *Conversation) AddToolResults(results []ToolResult) {
+	c.Messages = append(c.Messages, Message{
+		Role:        RoleTool,
+		ToolResults: results,
+	})
+}
+
+// newToolCallID generates an ID for providers that don't assign one to tool calls
+func newToolCallID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "call_unknown"
+	}
+	return "call_" + hex.EncodeToString(buf)
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"aurora-agent/config"
 )
@@ -22,6 +24,43 @@ func showConfig() {
 	}
 	fmt.Printf("  APIKey: %s\n", apiKey)
 	fmt.Printf("  Model: %s\n", config.CurrentConfig.OpenAI.Model)
+	if config.CurrentConfig.OpenAI.BaseURL != "" {
+		fmt.Printf("  BaseURL: %s\n", config.CurrentConfig.OpenAI.BaseURL)
+	}
+	if len(config.CurrentConfig.OpenAI.Headers) > 0 {
+		fmt.Printf("  Headers: %d custom headers\n", len(config.CurrentConfig.OpenAI.Headers))
+	}
+	if config.CurrentConfig.OpenAI.StreamUsage != nil {
+		fmt.Printf("  StreamUsage: %t\n", *config.CurrentConfig.OpenAI.StreamUsage)
+	}
+	if len(config.CurrentConfig.OpenAI.Endpoints) > 0 {
+		endpoint := config.CurrentConfig.OpenAI.Endpoint
+		if endpoint == "" {
+			endpoint = "default"
+		}
+		fmt.Printf("  Endpoint: %s (%d configured)\n", endpoint, len(config.CurrentConfig.OpenAI.Endpoints))
+		names := make([]string, 0, len(config.CurrentConfig.OpenAI.Endpoints))
+		for name := range config.CurrentConfig.OpenAI.Endpoints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			e := config.CurrentConfig.OpenAI.Endpoints[name]
+			fmt.Printf("    %s: %s (model: %s)\n", name, e.BaseURL, e.Model)
+		}
+	}
+
+	fmt.Println("\033[1m[Anthropic]\033[0m")
+	anthropicKey := config.CurrentConfig.Anthropic.APIKey
+	if anthropicKey != "" {
+		anthropicKey = "********" // Hide API key
+	}
+	fmt.Printf("  APIKey: %s\n", anthropicKey)
+	fmt.Printf("  Model: %s\n", config.CurrentConfig.Anthropic.Model)
+	fmt.Printf("  MaxTokens: %d\n", config.CurrentConfig.Anthropic.MaxTokens)
+	if config.CurrentConfig.Anthropic.BaseURL != "" {
+		fmt.Printf("  BaseURL: %s\n", config.CurrentConfig.Anthropic.BaseURL)
+	}
 
 	fmt.Println("\033[1m[Interface]\033[0m")
 	fmt.Printf("  Theme: %s\n", config.CurrentConfig.Interface.Theme)
@@ -39,6 +78,78 @@ func showConfig() {
 		}
 	}
 
+	fmt.Println("\033[1m[Policy]\033[0m")
+	fmt.Printf("  Mode: %s\n", config.CurrentConfig.Policy.Mode)
+	fmt.Printf("  Rules: %d rules\n", len(config.CurrentConfig.Policy.Rules))
+	fmt.Printf("  ReadOnlyCommands: %d commands\n", len(config.CurrentConfig.Policy.ReadOnlyCommands))
+	fmt.Printf("  CommandRisks: %d overrides\n", len(config.CurrentConfig.Policy.CommandRisks))
+	fmt.Printf("  Paths.Roots: %s\n", strings.Join(config.CurrentConfig.Policy.Paths.Roots, ", "))
+	fmt.Printf("  Paths.Deny: %d globs\n", len(config.CurrentConfig.Policy.Paths.Deny))
+	fmt.Printf("  Paths.Outside: %s\n", config.CurrentConfig.Policy.Paths.Outside)
+
+	fmt.Println("\033[1m[Context]\033[0m")
+	if config.CurrentConfig.Context.Budget > 0 {
+		fmt.Printf("  Budget: %d tokens\n", config.CurrentConfig.Context.Budget)
+	} else {
+		fmt.Printf("  Budget: model context window\n")
+	}
+	fmt.Printf("  CompactAt: %d%%\n", config.CurrentConfig.Context.CompactAt)
+	fmt.Printf("  KeepTurns: %d\n", config.CurrentConfig.Context.KeepTurns)
+	fmt.Printf("  ToolOutputLimit: %d characters\n", config.CurrentConfig.Context.ToolOutputLimit)
+	fmt.Printf("  Summarize: %t\n", config.CurrentConfig.Context.Summarize)
+
+	fmt.Println("\033[1m[Usage]\033[0m")
+	fmt.Printf("  SessionBudget: $%.2f\n", config.CurrentConfig.Usage.SessionBudget)
+	fmt.Printf("  DailyBudget: $%.2f\n", config.CurrentConfig.Usage.DailyBudget)
+	fmt.Printf("  Prices: %d overrides\n", len(config.CurrentConfig.Usage.Prices))
+
+	fmt.Println("\033[1m[Checkpoints]\033[0m")
+	fmt.Printf("  Enabled: %t\n", config.CurrentConfig.Checkpoints.Enabled)
+	fmt.Printf("  MaxCheckpoints: %d per session\n", config.CurrentConfig.Checkpoints.MaxCheckpoints)
+	fmt.Printf("  ScanCommands: %t\n", config.CurrentConfig.Checkpoints.ScanCommands)
+	fmt.Printf("  MaxFiles: %d\n", config.CurrentConfig.Checkpoints.MaxFiles)
+	fmt.Printf("  MaxFileSize: %d bytes\n", config.CurrentConfig.Checkpoints.MaxFileSize)
+	fmt.Printf("  IgnoreDirs: %s\n", strings.Join(config.CurrentConfig.Checkpoints.IgnoreDirs, ", "))
+
+	fmt.Println("\033[1m[Tools]\033[0m")
+	if config.CurrentConfig.Tools.CommandTimeout > 0 {
+		fmt.Printf("  CommandTimeout: %d seconds\n", config.CurrentConfig.Tools.CommandTimeout)
+	} else {
+		fmt.Println("  CommandTimeout: none")
+	}
+	if config.CurrentConfig.Tools.MaxCommandTimeout > 0 {
+		fmt.Printf("  MaxCommandTimeout: %d seconds\n", config.CurrentConfig.Tools.MaxCommandTimeout)
+	} else {
+		fmt.Println("  MaxCommandTimeout: none")
+	}
+	tools := make([]string, 0, len(config.CurrentConfig.Tools.OutputLimits))
+	for tool := range config.CurrentConfig.Tools.OutputLimits {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	if len(tools) == 0 {
+		fmt.Println("  OutputLimits: none")
+	}
+	for _, tool := range tools {
+		fmt.Printf("  OutputLimit %s: %s\n", tool, formatOutputLimit(config.CurrentConfig.Tools.OutputLimits[tool]))
+	}
+
+	fmt.Println("\033[1m[Sudo]\033[0m")
+	if config.CurrentConfig.Sudo.IdleTimeout > 0 {
+		fmt.Printf("  IdleTimeout: %d minutes\n", config.CurrentConfig.Sudo.IdleTimeout)
+	} else {
+		fmt.Println("  IdleTimeout: none")
+	}
+
+	fmt.Println("\033[1m[Redaction]\033[0m")
+	fmt.Printf("  Enabled: %t\n", config.CurrentConfig.Redaction.Enabled)
+	fmt.Printf("  HighEntropy: %t\n", config.CurrentConfig.Redaction.HighEntropy)
+	if len(config.CurrentConfig.Redaction.Patterns) > 0 {
+		fmt.Printf("  Patterns: %s\n", strings.Join(config.CurrentConfig.Redaction.Patterns, ", "))
+	} else {
+		fmt.Println("  Patterns: none")
+	}
+
 	fmt.Printf("\nConfiguration file: \033[32m%s\033[0m\n", config.GetConfigPath())
 	fmt.Println("\nTo see the commands list, use `\033[32mconfig commands list\033[0m`")
 	fmt.Println()
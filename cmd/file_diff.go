@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around a change
+	diffContext = 3
+	// maxDiffEdits limits the work of the diff, larger changes are shown as a full replacement
+	maxDiffEdits = 2000
+)
+
+// diffOp is a line of a diff: ' ' unchanged, '-' removed or '+' added.
+// Text includes the line ending, the last line of a file may have none.
+type diffOp struct {
+	Kind byte
+	Text string
+}
+
+// splitLines splits text into lines that keep their line ending
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the operations that turn the lines a into the lines b
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myersDiff finds the shortest edit script between a and b with Myers' algorithm
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// v[max+k] is the furthest x reached on diagonal k, trace keeps v before every step
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[max-d:max+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+	return replaceLines(a, b)
+}
+
+// backtrackDiff walks the trace of myersDiff back from the end to build the edit script
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	var reversed []diffOp
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d]
+		get := func(k int) int { return previous[k+d] }
+
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := get(previousK)
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			reversed = append(reversed, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == previousX {
+			reversed = append(reversed, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, diffOp{' ', a[x-1]})
+		x--
+		y--
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// replaceLines returns an edit script that removes all of a and adds all of b
+func replaceLines(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// unifiedDiff returns the unified diff between two versions of a file, empty when they are equal
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	// Line numbers before every operation
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if op.Kind != '+' {
+			oldLines[i+1]++
+		}
+		if op.Kind != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	hunkEnd := 0
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := i - diffContext
+		if start < hunkEnd {
+			start = hunkEnd
+		}
+
+		// Extend the hunk over changes separated by only a few unchanged lines
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end += diffContext
+				if end > run {
+					end = run
+				}
+				break
+			}
+			end = run
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.Kind)
+			out.WriteString(op.Text)
+			if !strings.HasSuffix(op.Text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		hunkEnd = end
+		i = end
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk, before is the number of lines before it
+func hunkRange(before int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// diffStats counts the added and removed lines of a unified diff
+func diffStats(diff string) (added int, removed int) {
+	lines := strings.Split(diff, "\n")
+	if len(lines) < 2 {
+		return 0, 0
+	}
+	// The first two lines are the file names
+	for _, line := range lines[2:] {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// colorDiff colors a unified diff for the terminal
+func colorDiff(diff string) string {
+	var out strings.Builder
+	for i, line := range strings.SplitAfter(diff, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		newline := line[len(text):]
+		// Carriage returns of CRLF files would move the cursor
+		text = strings.TrimSuffix(text, "\r")
+		switch {
+		case i < 2:
+			out.WriteString("\033[1m" + text + "\033[0m" + newline)
+		case strings.HasPrefix(text, "@@"):
+			out.WriteString("\033[36m" + text + "\033[0m" + newline)
+		case strings.HasPrefix(text, "+"):
+			out.WriteString("\033[32m" + text + "\033[0m" + newline)
+		case strings.HasPrefix(text, "-"):
+			out.WriteString("\033[31m" + text + "\033[0m" + newline)
+		case strings.HasPrefix(text, "\\"):
+			out.WriteString("\033[90m" + text + "\033[0m" + newline)
+		default:
+			out.WriteString(text + newline)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,441 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Limits of a single read_file call
+const (
+	// readPageLines and readPageBytes limit one page of output, the rest is available with a continuation token
+	readPageLines = 500
+	readPageBytes = 64 * 1024
+	// readEntireBytes limits the output when the whole file is requested
+	readEntireBytes = 1024 * 1024
+	// readMaxLineLength is the number of characters shown of a very long line
+	readMaxLineLength = 2000
+	// readLineKeepBytes is how much of a line is kept, enough for readMaxLineLength characters
+	readLineKeepBytes = readMaxLineLength*utf8.UTFMax + utf8.UTFMax
+	// readCountLinesLimit is the largest file whose lines are counted to show the total
+	readCountLinesLimit = 50 * 1024 * 1024
+	// readDecodeLimit is the largest UTF-16 file that is decoded, these are decoded in memory
+	readDecodeLimit = 16 * 1024 * 1024
+	// readSniffBytes is the amount of data used to detect the encoding
+	readSniffBytes = 8192
+)
+
+// fileReadRequest describes which part of a file to read
+type fileReadRequest struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Entire    bool
+	Token     string
+}
+
+// fileReadResult is a page of a file with line numbers
+type fileReadResult struct {
+	Path       string
+	Encoding   string
+	CRLF       bool
+	FirstLine  int
+	LastLine   int
+	TotalLines int // 0 when the file is too large to count
+	Size       int64
+	// NoFinalNewline is set when the page ends with the last line and it has no newline
+	NoFinalNewline bool
+	// NextToken continues reading after LastLine, empty at the end of the requested range
+	NextToken string
+	Content   string
+}
+
+// readContinuation is the state stored in a continuation token
+type readContinuation struct {
+	Path    string `json:"p"`
+	Offset  int64  `json:"o"`
+	Line    int    `json:"l"`
+	EndLine int    `json:"e,omitempty"`
+	Size    int64  `json:"s"`
+	ModTime int64  `json:"m"`
+}
+
+// readFilePage reads a range of lines of a text file
+func readFilePage(request fileReadRequest) (fileReadResult, error) {
+	var continuation *readContinuation
+	if request.Token != "" {
+		decoded, err := decodeReadToken(request.Token)
+		if err != nil {
+			return fileReadResult{}, err
+		}
+		if request.Path != "" && request.Path != decoded.Path {
+			return fileReadResult{}, fmt.Errorf("continuation token belongs to %s, not %s", decoded.Path, request.Path)
+		}
+		continuation = &decoded
+		request.Path = decoded.Path
+		request.EndLine = decoded.EndLine
+	}
+
+	info, err := os.Stat(request.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fileReadResult{}, fmt.Errorf("file not found: %s", request.Path)
+		}
+		return fileReadResult{}, err
+	}
+	if info.IsDir() {
+		return fileReadResult{}, fmt.Errorf("%s is a directory", request.Path)
+	}
+	if continuation != nil && (continuation.Size != info.Size() || continuation.ModTime != info.ModTime().UnixNano()) {
+		return fileReadResult{}, fmt.Errorf("%s changed since the continuation token was issued, read it again by line range", request.Path)
+	}
+
+	file, err := os.Open(request.Path)
+	if err != nil {
+		return fileReadResult{}, err
+	}
+	defer file.Close()
+
+	sample := make([]byte, readSniffBytes)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fileReadResult{}, err
+	}
+	sample = sample[:n]
+
+	encoding, bomLength := detectEncoding(sample)
+	if encoding == "binary" {
+		return fileReadResult{}, fmt.Errorf("%s is a binary file (%s, %d bytes)",
+			request.Path, http.DetectContentType(sample), info.Size())
+	}
+
+	// The text is read from a UTF-8 or Latin-1 stream, UTF-16 files are converted first
+	var text io.ReadSeeker = file
+	if strings.HasPrefix(encoding, "utf-16") {
+		if info.Size() > readDecodeLimit {
+			return fileReadResult{}, fmt.Errorf("%s is a UTF-16 file larger than %d bytes", request.Path, readDecodeLimit)
+		}
+		decoded, err := decodeUTF16File(file, encoding, bomLength)
+		if err != nil {
+			return fileReadResult{}, err
+		}
+		text = bytes.NewReader(decoded)
+		bomLength = 0
+	}
+
+	result := fileReadResult{
+		Path:     request.Path,
+		Encoding: encoding,
+		Size:     info.Size(),
+	}
+
+	// Start at the continuation point, or at the beginning of the text
+	offset := int64(bomLength)
+	line := 1
+	if continuation != nil {
+		offset = continuation.Offset
+		line = continuation.Line
+	}
+	if _, err := text.Seek(offset, io.SeekStart); err != nil {
+		return fileReadResult{}, err
+	}
+
+	startLine := request.StartLine
+	if startLine < 1 || continuation != nil {
+		startLine = line
+	}
+	endLine := request.EndLine
+	if endLine > 0 && endLine < startLine {
+		return fileReadResult{}, fmt.Errorf("end_line %d is before start_line %d", endLine, startLine)
+	}
+
+	maxLines, maxBytes := readPageLines, readPageBytes
+	if request.Entire {
+		maxLines, maxBytes = 0, readEntireBytes
+	}
+
+	reader := bufio.NewReader(text)
+	var content strings.Builder
+	for {
+		lineText, length, omitted, hadNewline, err := readTextLine(reader)
+		if length == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return fileReadResult{}, err
+		}
+
+		if strings.HasSuffix(lineText, "\r") {
+			lineText = strings.TrimSuffix(lineText, "\r")
+			result.CRLF = true
+		}
+
+		if line >= startLine {
+			// Stop at the end of the page, the next page starts with this line
+			pageFull := (maxLines > 0 && result.LastLine-result.FirstLine+1 >= maxLines) ||
+				(content.Len() > 0 && content.Len()+len(lineText) > maxBytes)
+			if result.FirstLine > 0 && pageFull {
+				result.NextToken = encodeReadToken(readContinuation{
+					Path:    request.Path,
+					Offset:  offset,
+					Line:    line,
+					EndLine: endLine,
+					Size:    info.Size(),
+					ModTime: info.ModTime().UnixNano(),
+				})
+				break
+			}
+
+			if result.FirstLine == 0 {
+				result.FirstLine = line
+			}
+			result.LastLine = line
+			if encoding == "latin-1" {
+				lineText = latin1ToUTF8(lineText)
+			} else {
+				// Invalid sequences after the sniffed part of the file
+				lineText = strings.ToValidUTF8(lineText, "\uFFFD")
+			}
+			fmt.Fprintf(&content, "%6d\t%s\n", line, truncateLine(lineText, omitted))
+			if !hadNewline {
+				result.NoFinalNewline = true
+			}
+		}
+
+		offset += int64(length)
+		if endLine > 0 && line >= endLine {
+			break
+		}
+		line++
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if result.FirstLine == 0 {
+		if startLine > 1 {
+			return fileReadResult{}, fmt.Errorf("start_line %d is past the end of %s, which has %d lines", startLine, request.Path, line-1)
+		}
+		result.Content = ""
+		return result, nil
+	}
+	result.Content = content.String()
+
+	if info.Size() <= readCountLinesLimit {
+		if total, err := countLines(text); err == nil {
+			result.TotalLines = total
+		}
+	}
+
+	return result, nil
+}
+
+// readTextLine reads one line without its newline. Only the start of a very long
+// line is kept, it is cut at readMaxLineLength characters by truncateLine anyway.
+// length counts every byte so offsets stay correct, omitted counts the characters
+// that were not kept.
+func readTextLine(reader *bufio.Reader) (text string, length int, omitted int, hadNewline bool, err error) {
+	var line []byte
+	// crOmitted is set when the last byte that was only counted is a carriage return
+	crOmitted := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		length += len(chunk)
+		hadNewline = err == nil
+		if hadNewline {
+			chunk = chunk[:len(chunk)-1]
+		}
+
+		// Keep a bit more than the display limit, the rest is only counted
+		keep := min(max(readLineKeepBytes-len(line), 0), len(chunk))
+		line = append(line, chunk[:keep]...)
+		if len(chunk) > 0 {
+			crOmitted = keep < len(chunk) && chunk[len(chunk)-1] == '\r'
+			omitted += utf8.RuneCount(chunk[keep:])
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		// The carriage return of a CRLF line ending is needed to detect it
+		if crOmitted {
+			line = append(line, '\r')
+			omitted--
+		}
+		if err != nil {
+			return string(line), length, omitted, false, err
+		}
+		return string(line), length, omitted, true, nil
+	}
+}
+
+// truncateLine shortens a line longer than readMaxLineLength characters, omitted
+// counts the characters of the line that readTextLine didn't keep
+func truncateLine(line string, omitted int) string {
+	if omitted == 0 && utf8.RuneCountInString(line) <= readMaxLineLength {
+		return line
+	}
+	runes := []rune(line)
+	shown := min(len(runes), readMaxLineLength)
+	return fmt.Sprintf("%s... [line truncated, %d more characters]", string(runes[:shown]), len(runes)-shown+omitted)
+}
+
+// detectEncoding guesses the encoding of a file from its first bytes.
+// It returns "utf-8", "utf-8-bom", "utf-16le", "utf-16be", "latin-1" or "binary",
+// and the length of the byte order mark.
+func detectEncoding(sample []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8-bom", 3
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return "utf-16le", 2
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return "utf-16be", 2
+	}
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return "binary", 0
+	}
+
+	// Control characters other than whitespace and escape sequences mean binary data
+	control := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != 0x1b {
+			control++
+		}
+	}
+	if len(sample) > 0 && control*10 > len(sample) {
+		return "binary", 0
+	}
+
+	// A full sample may end in the middle of a character
+	valid := sample
+	if len(sample) == readSniffBytes {
+		for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+			if utf8.RuneStart(sample[len(sample)-i]) {
+				if !utf8.FullRune(sample[len(sample)-i:]) {
+					valid = sample[:len(sample)-i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(valid) {
+		return "utf-8", 0
+	}
+	return "latin-1", 0
+}
+
+// decodeUTF16File converts a UTF-16 file to UTF-8
+func decodeUTF16File(file *os.File, encoding string, bomLength int) ([]byte, error) {
+	if _, err := file.Seek(int64(bomLength), io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if encoding == "utf-16le" {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		} else {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		}
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+// latin1ToUTF8 converts Latin-1 text to UTF-8
+func latin1ToUTF8(text string) string {
+	runes := make([]rune, len(text))
+	for i := 0; i < len(text); i++ {
+		runes[i] = rune(text[i])
+	}
+	return string(runes)
+}
+
+// countLines counts the lines of the text, a last line without newline counts too
+func countLines(text io.ReadSeeker) (int, error) {
+	if _, err := text.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	lines := 0
+	last := byte('\n')
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := text.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
+// encodeReadToken encodes continuation state as an opaque token
+func encodeReadToken(state readContinuation) string {
+	data, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeReadToken decodes a continuation token
+func decodeReadToken(token string) (readContinuation, error) {
+	var state readContinuation
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &state) != nil || state.Path == "" || state.Line < 1 {
+		return readContinuation{}, fmt.Errorf("invalid continuation token")
+	}
+	return state, nil
+}
+
+// formatFileRead renders a page of a file for the model
+func formatFileRead(result fileReadResult) string {
+	var out strings.Builder
+
+	total := "unknown"
+	if result.TotalLines > 0 {
+		total = fmt.Sprintf("%d", result.TotalLines)
+	}
+	if result.FirstLine == 0 {
+		fmt.Fprintf(&out, "File: %s (empty, %d bytes)\n", result.Path, result.Size)
+		return out.String()
+	}
+
+	fmt.Fprintf(&out, "File: %s (lines %d-%d of %s, %d bytes, %s", result.Path,
+		result.FirstLine, result.LastLine, total, result.Size, result.Encoding)
+	if result.CRLF {
+		out.WriteString(", CRLF line endings")
+	}
+	out.WriteString(")\n")
+
+	out.WriteString(result.Content)
+
+	if result.NoFinalNewline {
+		out.WriteString("\\ No newline at end of file\n")
+	}
+	if result.NextToken != "" {
+		fmt.Fprintf(&out, "[More lines follow. To continue, call read_file with continuation_token %q]\n", result.NextToken)
+	}
+	return out.String()
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes a file in a temporary directory and returns its path
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// numberedLines returns the lines first to last of a text, numbered like readFilePage does
+func numberedLines(lines []string, first int) string {
+	var out strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&out, "%6d\t%s\n", first+i, line)
+	}
+	return out.String()
+}
+
+func TestReadFilePage(t *testing.T) {
+	longLine := strings.Repeat("x", readMaxLineLength+500)
+	tests := []struct {
+		name    string
+		data    []byte
+		request fileReadRequest
+		want    fileReadResult
+		wantErr string
+	}{
+		{
+			name: "lf",
+			data: []byte("one\ntwo\nthree\n"),
+			want: fileReadResult{Encoding: "utf-8", FirstLine: 1, LastLine: 3, TotalLines: 3,
+				Content: numberedLines([]string{"one", "two", "three"}, 1)},
+		},
+		{
+			name: "crlf without final newline",
+			data: []byte("one\r\ntwo\r\nthree"),
+			want: fileReadResult{Encoding: "utf-8", CRLF: true, NoFinalNewline: true, FirstLine: 1, LastLine: 3, TotalLines: 3,
+				Content: numberedLines([]string{"one", "two", "three"}, 1)},
+		},
+		{
+			name: "single line without newline",
+			data: []byte("only"),
+			want: fileReadResult{Encoding: "utf-8", NoFinalNewline: true, FirstLine: 1, LastLine: 1, TotalLines: 1,
+				Content: numberedLines([]string{"only"}, 1)},
+		},
+		{
+			name: "empty",
+			data: []byte{},
+			want: fileReadResult{Encoding: "utf-8"},
+		},
+		{
+			name:    "line range",
+			data:    []byte("a\nb\nc\nd\ne\n"),
+			request: fileReadRequest{StartLine: 2, EndLine: 4},
+			want: fileReadResult{Encoding: "utf-8", FirstLine: 2, LastLine: 4, TotalLines: 5,
+				Content: numberedLines([]string{"b", "c", "d"}, 2)},
+		},
+		{
+			name:    "range ending at a last line without newline",
+			data:    []byte("a\nb\nc"),
+			request: fileReadRequest{StartLine: 3, EndLine: 9},
+			want: fileReadResult{Encoding: "utf-8", NoFinalNewline: true, FirstLine: 3, LastLine: 3, TotalLines: 3,
+				Content: numberedLines([]string{"c"}, 3)},
+		},
+		{
+			name:    "start past the end",
+			data:    []byte("a\nb\n"),
+			request: fileReadRequest{StartLine: 5},
+			wantErr: "start_line 5 is past the end",
+		},
+		{
+			name:    "end before start",
+			data:    []byte("a\nb\n"),
+			request: fileReadRequest{StartLine: 2, EndLine: 1},
+			wantErr: "end_line 1 is before start_line 2",
+		},
+		{
+			name: "utf-8 with bom",
+			data: []byte("\xEF\xBB\xBFcafé\n"),
+			want: fileReadResult{Encoding: "utf-8-bom", FirstLine: 1, LastLine: 1, TotalLines: 1,
+				Content: numberedLines([]string{"café"}, 1)},
+		},
+		{
+			name: "utf-16le",
+			data: []byte("\xFF\xFEh\x00\xe9\x00\r\x00\n\x00!\x00\n\x00"),
+			want: fileReadResult{Encoding: "utf-16le", CRLF: true, FirstLine: 1, LastLine: 2, TotalLines: 2,
+				Content: numberedLines([]string{"hé", "!"}, 1)},
+		},
+		{
+			name: "utf-16be",
+			data: []byte("\xFE\xFF\x00h\x00\xe9\x00\n\x00!"),
+			want: fileReadResult{Encoding: "utf-16be", NoFinalNewline: true, FirstLine: 1, LastLine: 2, TotalLines: 2,
+				Content: numberedLines([]string{"hé", "!"}, 1)},
+		},
+		{
+			name: "latin-1",
+			data: []byte("caf\xe9\nna\xefve\n"),
+			want: fileReadResult{Encoding: "latin-1", FirstLine: 1, LastLine: 2, TotalLines: 2,
+				Content: numberedLines([]string{"café", "naïve"}, 1)},
+		},
+		{
+			name:    "binary",
+			data:    []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+			wantErr: "is a binary file (image/png",
+		},
+		{
+			name:    "control characters",
+			data:    []byte(strings.Repeat("\x01\x02\x03a", 20)),
+			wantErr: "is a binary file",
+		},
+		{
+			name: "over-long line",
+			data: []byte(longLine + "\nnext\n"),
+			want: fileReadResult{Encoding: "utf-8", FirstLine: 1, LastLine: 2, TotalLines: 2,
+				Content: numberedLines([]string{strings.Repeat("x", readMaxLineLength) + "... [line truncated, 500 more characters]", "next"}, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeTestFile(t, "file.txt", test.data)
+			test.request.Path = path
+			result, err := readFilePage(test.request)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readFilePage: %v", err)
+			}
+
+			test.want.Path = path
+			test.want.Size = int64(len(test.data))
+			if result != test.want {
+				t.Errorf("result = %+v\nwant     %+v", result, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	line := strings.Repeat("é", readMaxLineLength+10)
+	got := truncateLine(line, 0)
+	want := strings.Repeat("é", readMaxLineLength) + "... [line truncated, 10 more characters]"
+	if got != want {
+		t.Errorf("truncateLine cut %d characters, want %d", len([]rune(got)), len([]rune(want)))
+	}
+	if short := strings.Repeat("é", readMaxLineLength); truncateLine(short, 0) != short {
+		t.Error("truncateLine changed a line of the maximum length")
+	}
+}
+
+func TestReadFileContinuation(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 1200; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	path := writeTestFile(t, "long.txt", []byte(strings.Join(lines, "\r\n")))
+
+	// Read the file page by page until there is no token left
+	var pages [][2]int
+	var content strings.Builder
+	request := fileReadRequest{Path: path}
+	for {
+		result, err := readFilePage(request)
+		if err != nil {
+			t.Fatalf("page %d: %v", len(pages)+1, err)
+		}
+		pages = append(pages, [2]int{result.FirstLine, result.LastLine})
+		content.WriteString(result.Content)
+		if !result.CRLF || result.TotalLines != 1200 {
+			t.Errorf("page %d: CRLF = %t, total = %d", len(pages), result.CRLF, result.TotalLines)
+		}
+		if result.NextToken == "" {
+			if !result.NoFinalNewline {
+				t.Error("the last page doesn't report the missing final newline")
+			}
+			break
+		}
+		// The path may be left out when a token is given
+		request = fileReadRequest{Token: result.NextToken}
+	}
+
+	if fmt.Sprint(pages) != "[[1 500] [501 1000] [1001 1200]]" {
+		t.Errorf("pages = %v", pages)
+	}
+	if content.String() != numberedLines(lines, 1) {
+		t.Error("the pages don't add up to the file")
+	}
+
+	// A token keeps the end of the requested range
+	result, err := readFilePage(fileReadRequest{Path: path, StartLine: 400, EndLine: 1100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = readFilePage(fileReadRequest{Path: path, Token: result.NextToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FirstLine != 900 || result.LastLine != 1100 || result.NextToken != "" {
+		t.Errorf("second page of the range = %d-%d, token %q", result.FirstLine, result.LastLine, result.NextToken)
+	}
+
+	// A token belongs to one file
+	first, err := readFilePage(fileReadRequest{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := writeTestFile(t, "other.txt", []byte("x\n"))
+	if _, err := readFilePage(fileReadRequest{Path: other, Token: first.NextToken}); err == nil || !strings.Contains(err.Error(), "belongs to") {
+		t.Errorf("token of another file: error = %v", err)
+	}
+	if _, err := readFilePage(fileReadRequest{Token: "not a token"}); err == nil || !strings.Contains(err.Error(), "invalid continuation token") {
+		t.Errorf("invalid token: error = %v", err)
+	}
+
+	// The token is rejected once the file changed
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFilePage(fileReadRequest{Token: first.NextToken}); err == nil || !strings.Contains(err.Error(), "changed since the continuation token was issued") {
+		t.Errorf("token of a changed file: error = %v", err)
+	}
+}
+
+func TestReadFilePageLimits(t *testing.T) {
+	// Lines of 1000 bytes fill a page by size before the line limit
+	line := strings.Repeat("y", 999)
+	var data strings.Builder
+	for i := 0; i < 200; i++ {
+		data.WriteString(line + "\n")
+	}
+	path := writeTestFile(t, "wide.txt", []byte(data.String()))
+
+	result, err := readFilePage(fileReadRequest{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) > readPageBytes || result.NextToken == "" || result.LastLine >= 200 {
+		t.Errorf("page of %d bytes, lines %d-%d, token %q", len(result.Content), result.FirstLine, result.LastLine, result.NextToken)
+	}
+
+	// The whole file fits in one read
+	result, err = readFilePage(fileReadRequest{Path: path, Entire: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FirstLine != 1 || result.LastLine != 200 || result.NextToken != "" {
+		t.Errorf("entire read: lines %d-%d, token %q", result.FirstLine, result.LastLine, result.NextToken)
+	}
+
+	// A line larger than the read buffer is cut for display but counted whole,
+	// so the next line and the offsets after it stay right
+	huge := strings.Repeat("z", 5*1024*1024)
+	path = writeTestFile(t, "huge.txt", []byte("first\n"+huge+"\nlast"))
+	result, err = readFilePage(fileReadRequest{Path: path, StartLine: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := numberedLines([]string{strings.Repeat("z", readMaxLineLength) + fmt.Sprintf("... [line truncated, %d more characters]", len(huge)-readMaxLineLength), "last"}, 2)
+	if result.Content != want || result.TotalLines != 3 || !result.NoFinalNewline {
+		t.Errorf("huge line: lines %d-%d of %d, %d bytes of content", result.FirstLine, result.LastLine, result.TotalLines, len(result.Content))
+	}
+
+	// The carriage return at the end of a huge line still marks CRLF line endings
+	path = writeTestFile(t, "huge-crlf.txt", []byte("first\r\n"+huge+"\r\nlast\r\n"))
+	result, err = readFilePage(fileReadRequest{Path: path, StartLine: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != want || !result.CRLF || result.NoFinalNewline {
+		t.Errorf("huge CRLF line: CRLF = %t, %d bytes of content", result.CRLF, len(result.Content))
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	fullSample := []byte(strings.Repeat("a", readSniffBytes-1) + "\xc3")
+	tests := []struct {
+		name      string
+		sample    []byte
+		encoding  string
+		bomLength int
+	}{
+		{"empty", []byte{}, "utf-8", 0},
+		{"ascii", []byte("hello\tworld\r\n"), "utf-8", 0},
+		{"utf-8", []byte("grüße, 世界\n"), "utf-8", 0},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFtext"), "utf-8-bom", 3},
+		{"utf-16le bom", []byte("\xFF\xFEa\x00"), "utf-16le", 2},
+		{"utf-16be bom", []byte("\xFE\xFF\x00a"), "utf-16be", 2},
+		{"utf-16 without bom", []byte("a\x00b\x00"), "binary", 0},
+		{"latin-1", []byte("Se\xf1or\n"), "latin-1", 0},
+		{"escape sequences", []byte("\x1b[31mred\x1b[0m\n"), "utf-8", 0},
+		{"nul byte", []byte("text\x00more"), "binary", 0},
+		{"control characters", []byte("\x01\x02\x03\x04abc"), "binary", 0},
+		{"sample cut inside a character", fullSample, "utf-8", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoding, bomLength := detectEncoding(test.sample)
+			if encoding != test.encoding || bomLength != test.bomLength {
+				t.Errorf("detectEncoding = %s, %d, want %s, %d", encoding, bomLength, test.encoding, test.bomLength)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Limits of the file discovery tools, the rest of a result is available with offset
+const (
+	// listPageEntries and globPageResults are the entries returned per call
+	listPageEntries = 200
+	globPageResults = 200
+	// searchPageMatches is the number of matches, with their context, returned per call
+	searchPageMatches = 100
+	// discoveryPageBytes limits the output of a single call
+	discoveryPageBytes = 32 * 1024
+	// walkEntryLimit stops walks of huge trees
+	walkEntryLimit = 200000
+	// searchFileLimit is the largest file that is searched
+	searchFileLimit = 5 * 1024 * 1024
+	// searchLineLength is the number of characters shown of a matching line
+	searchLineLength = 300
+	// maxListDepth limits the depth of list_dir
+	maxListDepth = 10
+)
+
+// errWalkLimit stops a walk that reached walkEntryLimit
+var errWalkLimit = errors.New("walk limit reached")
+
+// errWalkDone stops a walk that found enough
+var errWalkDone = errors.New("walk done")
+
+// workspaceEntry is a file or directory found by walkWorkspace
+type workspaceEntry struct {
+	// Path is the path to show, the requested directory joined with Relative
+	Path     string
+	Relative string
+	Entry    fs.DirEntry
+}
+
+// walkWorkspace visits the files and directories below dir in lexical order,
+// down to maxDepth levels (0 for no limit). The .git directory and the paths the
+// path policy denies are skipped and, unless all is set, so is everything
+// .gitignore ignores.
+// Returning filepath.SkipDir from visit doesn't descend into a directory.
+func walkWorkspace(ctx context.Context, dir string, maxDepth int, all bool, visit func(entry workspaceEntry) error) error {
+	prefix, rules := repositoryIgnoreRules(dir)
+	// The deny globs are matched against the real paths of the entries
+	_, root, err := resolvePolicyPath(dir)
+	if err != nil {
+		return err
+	}
+
+	visited := 0
+	var walk func(current string, relative string, depth int, rules []ignoreRule) error
+	walk = func(current string, relative string, depth int, rules []ignoreRule) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			// Unreadable directories are skipped
+			return nil
+		}
+
+		repositoryPath := path.Join(prefix, relative)
+		if !all {
+			// Copy so sibling directories don't share the rules of this one
+			rules = append(rules[:len(rules):len(rules)], parseIgnoreFile(filepath.Join(current, ".gitignore"), repositoryPath)...)
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if name == ".git" {
+				continue
+			}
+			childRelative := path.Join(relative, name)
+			if !all && isIgnored(rules, path.Join(prefix, childRelative), entry.IsDir()) {
+				continue
+			}
+			// Symbolic links are not followed by the walk, but where they lead counts
+			realPath := filepath.Join(root, filepath.FromSlash(childRelative))
+			if _, denied := deniedPathGlob(realPath); denied || (entry.Type()&fs.ModeSymlink != 0 && isPathDenied(realPath)) {
+				continue
+			}
+
+			visited++
+			if visited > walkEntryLimit {
+				return errWalkLimit
+			}
+
+			err := visit(workspaceEntry{
+				Path:     filepath.Join(dir, filepath.FromSlash(childRelative)),
+				Relative: childRelative,
+				Entry:    entry,
+			})
+			if err == filepath.SkipDir {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && (maxDepth <= 0 || depth+1 < maxDepth) {
+				if err := walk(filepath.Join(current, name), childRelative, depth+1, rules); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory not found: %s", dir)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	err = walk(dir, "", 0, rules)
+	if err == errWalkDone {
+		return nil
+	}
+	return err
+}
+
+// discoveryPage collects the lines of a paginated result
+type discoveryPage struct {
+	offset int
+	limit  int
+	// seen counts every result, also the ones before offset
+	seen  int
+	lines []string
+	bytes int
+	// next is the offset of the next page, 0 when the result is complete
+	next int
+}
+
+// add adds a result and reports whether the page is full
+func (p *discoveryPage) add(text string) bool {
+	p.seen++
+	if p.seen <= p.offset {
+		return false
+	}
+	if len(p.lines) >= p.limit || (len(p.lines) > 0 && p.bytes+len(text) > discoveryPageBytes) {
+		p.next = p.seen - 1
+		return true
+	}
+	p.lines = append(p.lines, text)
+	p.bytes += len(text) + 1
+	return false
+}
+
+// footer describes where the page ends and how to get the next one
+func (p *discoveryPage) footer(noun string, walkErr error) string {
+	var notes []string
+	if p.next > 0 {
+		notes = append(notes, fmt.Sprintf("Showing %s %d-%d. More results follow, call again with offset %d to see them.",
+			noun, p.offset+1, p.offset+len(p.lines), p.next))
+	} else if p.offset > 0 {
+		notes = append(notes, fmt.Sprintf("Showing %s %d-%d, the end of the results.", noun, p.offset+1, p.offset+len(p.lines)))
+	}
+	if walkErr == errWalkLimit {
+		notes = append(notes, fmt.Sprintf("Stopped after %d entries, narrow down the path or pattern.", walkEntryLimit))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(notes, " ") + "]\n"
+}
+
+// listDirectory lists a directory as an indented tree down to depth levels
+func listDirectory(ctx context.Context, dir string, depth int, all bool, offset int) (string, error) {
+	if depth <= 0 {
+		depth = 1
+	}
+	if depth > maxListDepth {
+		depth = maxListDepth
+	}
+
+	page := &discoveryPage{offset: offset, limit: listPageEntries}
+	err := walkWorkspace(ctx, dir, depth, all, func(entry workspaceEntry) error {
+		indent := strings.Repeat("  ", strings.Count(entry.Relative, "/"))
+		line := indent + entry.Entry.Name()
+		switch {
+		case entry.Entry.IsDir():
+			line += "/"
+		case entry.Entry.Type()&fs.ModeSymlink != 0:
+			if target, err := os.Readlink(entry.Path); err == nil {
+				line += " -> " + target
+			}
+		default:
+			if info, err := entry.Entry.Info(); err == nil {
+				line += fmt.Sprintf(" (%s)", formatFileSize(info.Size()))
+			}
+		}
+		if page.add(line) {
+			return errWalkDone
+		}
+		return nil
+	})
+	if err != nil && err != errWalkLimit {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Directory: %s\n", dir)
+	if len(page.lines) == 0 {
+		out.WriteString("(no entries)\n")
+	}
+	for _, line := range page.lines {
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(page.footer("entries", err))
+	return out.String(), nil
+}
+
+// globFiles returns the files below dir whose path relative to dir matches the pattern
+func globFiles(ctx context.Context, dir string, pattern string, all bool, offset int) (string, error) {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if pattern == "" {
+		return "", fmt.Errorf("pattern is empty")
+	}
+	matcher, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	page := &discoveryPage{offset: offset, limit: globPageResults}
+	err = walkWorkspace(ctx, dir, 0, all, func(entry workspaceEntry) error {
+		if entry.Entry.IsDir() || !matcher.MatchString(entry.Relative) {
+			return nil
+		}
+		if page.add(entry.Path) {
+			return errWalkDone
+		}
+		return nil
+	})
+	if err != nil && err != errWalkLimit {
+		return "", err
+	}
+
+	var out strings.Builder
+	if len(page.lines) == 0 {
+		fmt.Fprintf(&out, "No files match %s in %s\n", pattern, dir)
+	}
+	for _, line := range page.lines {
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(page.footer("files", err))
+	return out.String(), nil
+}
+
+// searchRequest describes a regular expression search through files
+type searchRequest struct {
+	Pattern         string
+	Path            string
+	Include         string
+	ContextLines    int
+	CaseInsensitive bool
+	All             bool
+	Offset          int
+}
+
+// searchFiles searches the text files below a directory, or a single file, for a
+// regular expression and returns the matching lines grep-style: path:line:text for
+// matches, path-line-text for context lines and -- between groups
+func searchFiles(ctx context.Context, request searchRequest) (string, error) {
+	expression := request.Pattern
+	if request.CaseInsensitive {
+		expression = "(?i)" + expression
+	}
+	matcher, err := regexp.Compile(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	var include *regexp.Regexp
+	if request.Include != "" {
+		glob := strings.TrimPrefix(filepath.ToSlash(request.Include), "./")
+		if strings.Contains(glob, "/") {
+			include, err = regexp.Compile("^" + globToRegexp(glob) + "$")
+		} else {
+			// A pattern without a slash matches the file name in any directory
+			include, err = regexp.Compile("^(?:.*/)?" + globToRegexp(glob) + "$")
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid include pattern: %v", err)
+		}
+	}
+
+	contextLines := request.ContextLines
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if contextLines > 10 {
+		contextLines = 10
+	}
+
+	page := &discoveryPage{offset: request.Offset, limit: searchPageMatches}
+	var out strings.Builder
+	skipped := 0
+
+	searchOne := func(file string) error {
+		groups, err := searchFile(file, matcher, contextLines)
+		if err != nil {
+			skipped++
+			return nil
+		}
+		for _, group := range groups {
+			if page.add(group) {
+				return errWalkDone
+			}
+		}
+		return nil
+	}
+
+	info, err := os.Stat(request.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("path not found: %s", request.Path)
+		}
+		return "", err
+	}
+	if info.IsDir() {
+		err = walkWorkspace(ctx, request.Path, 0, request.All, func(entry workspaceEntry) error {
+			if !entry.Entry.Type().IsRegular() {
+				return nil
+			}
+			if include != nil && !include.MatchString(entry.Relative) {
+				return nil
+			}
+			return searchOne(entry.Path)
+		})
+	} else {
+		err = searchOne(request.Path)
+		if err == errWalkDone {
+			err = nil
+		}
+	}
+	if err != nil && err != errWalkLimit {
+		return "", err
+	}
+
+	if len(page.lines) == 0 {
+		fmt.Fprintf(&out, "No matches for %s in %s\n", request.Pattern, request.Path)
+	}
+	for i, group := range page.lines {
+		if i > 0 && contextLines > 0 {
+			out.WriteString("--\n")
+		}
+		out.WriteString(group + "\n")
+	}
+	out.WriteString(page.footer("matches", err))
+	if skipped > 0 {
+		fmt.Fprintf(&out, "[%d binary, unreadable or larger than %d MB files were not searched]\n", skipped, searchFileLimit/(1024*1024))
+	}
+	return out.String(), nil
+}
+
+// searchFile returns the matches of a single file, each with its context lines.
+// Matches whose context overlaps are merged into one group.
+func searchFile(file string, matcher *regexp.Regexp, contextLines int) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > searchFileLimit {
+		return nil, fmt.Errorf("file too large")
+	}
+
+	sample := make([]byte, readSniffBytes)
+	n, _ := io.ReadFull(f, sample)
+	switch encoding, _ := detectEncoding(sample[:n]); encoding {
+	case "utf-8", "utf-8-bom", "latin-1":
+	default:
+		return nil, fmt.Errorf("not a searchable text file")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), searchFileLimit+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	var group []string
+	lastShown := -1
+	for i, line := range lines {
+		if !matcher.MatchString(line) {
+			continue
+		}
+
+		start := i - contextLines
+		if start <= lastShown+1 && group != nil {
+			// Overlapping or adjacent context continues the group
+			start = lastShown + 1
+		} else if group != nil {
+			groups = append(groups, strings.Join(group, "\n"))
+			group = nil
+		}
+		if start < 0 {
+			start = 0
+		}
+		end := i + contextLines
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+
+		// Context lines may contain further matches, they are marked as such
+		for j := start; j <= end; j++ {
+			separator := "-"
+			if matcher.MatchString(lines[j]) {
+				separator = ":"
+			}
+			group = append(group, fmt.Sprintf("%s%s%d%s%s", file, separator, j+1, separator, truncateSearchLine(lines[j])))
+		}
+		lastShown = end
+	}
+	if group != nil {
+		groups = append(groups, strings.Join(group, "\n"))
+	}
+	return groups, nil
+}
+
+// truncateSearchLine shortens very long lines such as minified code
+func truncateSearchLine(line string) string {
+	if len(line) <= searchLineLength {
+		return line
+	}
+	return strings.ToValidUTF8(line[:searchLineLength], "") + "..."
+}
+
+// formatFileSize formats a file size for people
+func formatFileSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	case size < 1024*1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	default:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1024*1024*1024))
+	}
+}
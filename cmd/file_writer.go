@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// editFileLimit is the largest file the edit tools change, they keep the whole file in memory
+const editFileLimit = 10 * 1024 * 1024
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files
+const utf8BOM = "\xEF\xBB\xBF"
+
+// editableFile is the current state of a file the AI wants to change
+type editableFile struct {
+	// Path is the absolute path of the file, symlinks resolved
+	Path   string
+	Exists bool
+	Mode   os.FileMode
+	// Text is the content without byte order mark
+	Text string
+	BOM  bool
+	CRLF bool
+}
+
+// resolveWritePath returns the absolute path a write goes to.
+// A symlink is followed so the link itself is not replaced by a file.
+func resolveWritePath(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("file_path is empty")
+	}
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absolute); err == nil {
+		return resolved, nil
+	}
+	return absolute, nil
+}
+
+// loadEditableFile reads the file at path, a missing file is returned empty
+func loadEditableFile(path string) (editableFile, error) {
+	resolved, err := resolveWritePath(path)
+	if err != nil {
+		return editableFile{}, err
+	}
+	file := editableFile{Path: resolved, Mode: 0644}
+
+	info, err := os.Stat(resolved)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return editableFile{}, err
+	}
+	if info.IsDir() {
+		return editableFile{}, fmt.Errorf("%s is a directory", resolved)
+	}
+	if info.Size() > editFileLimit {
+		return editableFile{}, fmt.Errorf("%s is larger than %d bytes", resolved, editFileLimit)
+	}
+
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return editableFile{}, err
+	}
+	sample := data
+	if len(sample) > readSniffBytes {
+		sample = sample[:readSniffBytes]
+	}
+	switch encoding, _ := detectEncoding(sample); encoding {
+	case "utf-8", "utf-8-bom":
+	case "binary":
+		return editableFile{}, fmt.Errorf("%s is a binary file", resolved)
+	default:
+		return editableFile{}, fmt.Errorf("%s is a %s file, only UTF-8 files can be changed", resolved, encoding)
+	}
+
+	file.Exists = true
+	file.Mode = info.Mode().Perm()
+	file.BOM = bytes.HasPrefix(data, []byte(utf8BOM))
+	file.Text = strings.TrimPrefix(string(data), utf8BOM)
+	file.CRLF = strings.Contains(file.Text, "\r\n")
+	return file, nil
+}
+
+// matchLineEndings converts the line endings of text written by the AI to CRLF in CRLF files
+func (f editableFile) matchLineEndings(text string) string {
+	if !f.CRLF || strings.Contains(text, "\r\n") {
+		return text
+	}
+	return strings.ReplaceAll(text, "\n", "\r\n")
+}
+
+// replaceString replaces an exact string in the file. Unless all is set
+// the string must occur exactly once, so the edit can't hit the wrong place.
+func (f editableFile) replaceString(oldString string, newString string, all bool) (string, error) {
+	if oldString == "" {
+		return "", fmt.Errorf("old_string is empty, use start_line and end_line to insert lines")
+	}
+
+	count := strings.Count(f.Text, oldString)
+	if count == 0 && f.CRLF {
+		// The AI usually writes \n, the file uses \r\n
+		oldString, newString = f.matchLineEndings(oldString), f.matchLineEndings(newString)
+		count = strings.Count(f.Text, oldString)
+	}
+
+	switch {
+	case count == 0:
+		return "", fmt.Errorf("old_string was not found in %s, read the file again to get the exact text", f.Path)
+	case count > 1 && !all:
+		return "", fmt.Errorf("old_string occurs %d times in %s, include more surrounding lines to make it unique or set replace_all", count, f.Path)
+	case all:
+		return strings.ReplaceAll(f.Text, oldString, newString), nil
+	default:
+		return strings.Replace(f.Text, oldString, newString, 1), nil
+	}
+}
+
+// replaceLines replaces the lines start to end (1-based, inclusive) with text.
+// With end = start-1 the text is inserted before line start.
+func (f editableFile) replaceLines(start int, end int, text string) (string, error) {
+	lines := splitLines(f.Text)
+	if start < 1 || start > len(lines)+1 {
+		return "", fmt.Errorf("start_line %d is outside of %s, which has %d lines", start, f.Path, len(lines))
+	}
+	if end < start-1 || end > len(lines) {
+		return "", fmt.Errorf("end_line %d is invalid for start_line %d, %s has %d lines", end, start, f.Path, len(lines))
+	}
+
+	text = f.matchLineEndings(text)
+	newline := "\n"
+	if f.CRLF {
+		newline = "\r\n"
+	}
+	// The new lines end with a newline unless they replace the last line, which had none
+	lastWithoutNewline := end == len(lines) && end > 0 && !strings.HasSuffix(lines[end-1], "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") && !lastWithoutNewline {
+		text += newline
+	}
+	// Lines inserted after a last line without newline need one in between
+	if start == len(lines)+1 && start > 1 && !strings.HasSuffix(lines[start-2], "\n") {
+		text = newline + text
+	}
+
+	return strings.Join(lines[:start-1], "") + text + strings.Join(lines[end:], ""), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so the file is never left half written
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".aurora-*")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+	defer os.Remove(tempPath)
+
+	if _, err := io.Copy(temp, bytes.NewReader(data)); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return err
+	}
+
+	// Make the rename itself durable, not every platform can sync a directory
+	if dirFile, err := os.Open(dir); err == nil {
+		_ = dirFile.Sync()
+		dirFile.Close()
+	}
+	return nil
+}
+
+// applyFileChange shows the diff of a change, asks for approval and writes the file
+func applyFileChange(functionName string, file editableFile, newText string) FunctionCallResult {
+	oldName, newName := "a"+file.Path, "b"+file.Path
+	if !file.Exists {
+		oldName = "/dev/null"
+	}
+	diff := unifiedDiff(oldName, newName, file.Text, newText)
+	if diff == "" {
+		if file.Exists {
+			output := fmt.Sprintf("No changes: %s already has this content", file.Path)
+			fmt.Printf("%s\n\n", output)
+			return FunctionCallResult{
+				Name:    functionName,
+				Output:  output,
+				Success: true,
+			}
+		}
+		// A new empty file
+		diff = fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName)
+	}
+
+	approval := approveFileChange(file.Path, diff)
+	if !approval.Approved {
+		return FunctionCallResult{
+			Name:       functionName,
+			Success:    false,
+			Denied:     true,
+			DenyReason: approval.Reason,
+		}
+	}
+
+	// Keep the current content so the change can be undone
+	if err := checkpoints.snapshotFile(file.Path, functionName); err != nil {
+		fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+	}
+
+	data := newText
+	if file.BOM {
+		data = utf8BOM + data
+	}
+	if err := writeFileAtomic(file.Path, []byte(data), file.Mode); err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n\n", err)
+		return FunctionCallResult{
+			Name:    functionName,
+			Output:  fmt.Sprintf("Error: %v", err),
+			Success: false,
+		}
+	}
+
+	added, removed := diffStats(diff)
+	verb := "Updated"
+	if !file.Exists {
+		verb = "Created"
+	}
+	output := fmt.Sprintf("%s %s (%d lines added, %d lines removed)", verb, file.Path, added, removed)
+	fmt.Printf("\033[32m%s\033[0m\n\n", output)
+
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  output,
+		Success: true,
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single pattern of a .gitignore file
+type ignoreRule struct {
+	// base is the directory of the .gitignore file, relative to the repository root
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnoreFile reads the patterns of a .gitignore file, a missing file has none
+func parseIgnoreFile(file string, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnorePattern(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnorePattern converts a .gitignore line into a rule
+func parseIgnorePattern(line string, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the directory of the file
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expression := globToRegexp(line)
+	if anchored {
+		expression = "^" + expression + "$"
+	} else {
+		expression = "^(?:.*/)?" + expression + "$"
+	}
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// globToRegexp converts a glob with *, ?, [...] and ** into a regular expression.
+// * and ? don't match a slash, ** matches any number of directories.
+func globToRegexp(glob string) string {
+	var expression strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case c == '*':
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expression.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expression.String()
+}
+
+// isIgnored reports whether a path, relative to the repository root, is ignored.
+// The last matching rule wins, so later and deeper rules can re-include files.
+func isIgnored(rules []ignoreRule, relative string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		subject := relative
+		if rule.base != "" {
+			if !strings.HasPrefix(relative, rule.base+"/") {
+				continue
+			}
+			subject = relative[len(rule.base)+1:]
+		}
+		if rule.pattern.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// repositoryIgnoreRules finds the git repository dir belongs to and returns the
+// path of dir relative to the repository root and the rules that apply above dir:
+// .git/info/exclude and the .gitignore files of the parent directories.
+// Outside of a repository dir itself is the root.
+func repositoryIgnoreRules(dir string) (prefix string, rules []ignoreRule) {
+	absolute, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil
+	}
+
+	root := absolute
+	for {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(root) == root {
+			// Not in a repository
+			return "", nil
+		}
+		root = filepath.Dir(root)
+	}
+
+	rules = parseIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	relative, err := filepath.Rel(root, absolute)
+	if err != nil || relative == "." {
+		return "", rules
+	}
+	prefix = filepath.ToSlash(relative)
+
+	// The .gitignore files between the repository root and dir, dir itself is read by the walk
+	base := ""
+	for _, name := range strings.Split(prefix, "/") {
+		rules = append(rules, parseIgnoreFile(filepath.Join(root, filepath.FromSlash(base), ".gitignore"), base)...)
+		base = path.Join(base, name)
+	}
+	return prefix, rules
+}
@@ -9,7 +9,8 @@ func showHelp() {
 	fmt.Println("\n\033[1mAurora Agent help information\033[0m")
 	fmt.Println("\n\033[1mMain commands:\033[0m")
 	fmt.Println("  \033[32mhelp\033[0m                - Show help information")
-	fmt.Println("  \033[32mexit, quit\033[0m          - Exit the program")
+	fmt.Println("  \033[32mexit, quit [code]\033[0m   - Exit the program, with the exit code of the last command by default")
+	fmt.Println("  \033[32mcd, pushd, popd, dirs\033[0m - Change and show the working directory and the directory stack")
 	fmt.Println("  \033[32mclear\033[0m               - Clear the screen")
 
 	fmt.Println("\033[1mConfiguration commands:\033[0m")
@@ -25,11 +26,48 @@ func showHelp() {
 	fmt.Println("  \033[32mconfig commands remove <command>\033[0m - Remove or ignore command")
 	fmt.Println("  \033[32mconfig commands reset\033[0m   - Reset commands list to default")
 
+	fmt.Println("\033[1mCommand approval policy:\033[0m")
+	fmt.Println("  \033[32mpolicy\033[0m              - Show approval mode and rules")
+	fmt.Println("  \033[32mpolicy check <command>\033[0m - Show the risk level of a command and what the policy would do")
+	fmt.Println("  \033[32mpolicy path <path>\033[0m  - Show what the path policy would do with a path of a file tool")
+	fmt.Println("  \033[32mpolicy forget\033[0m       - Forget commands, files and paths approved with \"always\"")
+
+	fmt.Println("\033[1mConversation sessions:\033[0m")
+	fmt.Println("  \033[32msession\033[0m             - Show the current session")
+	fmt.Println("  \033[32msession list\033[0m        - List saved sessions")
+	fmt.Println("  \033[32msession new\033[0m         - Start a new conversation")
+	fmt.Println("  \033[32msession resume [id|name]\033[0m - Continue a saved session, the most recent one by default")
+	fmt.Println("  \033[32msession rename <name>\033[0m - Name the current session")
+	fmt.Println("  \033[32msession delete <id|name>\033[0m - Delete a saved session")
+	fmt.Println("  \033[32msession fork [name]\033[0m - Continue in a copy of the current session")
+
+	fmt.Println("\033[1mToken usage:\033[0m")
+	fmt.Println("  \033[32musage\033[0m               - Show tokens and cost of the last turn, the session and today")
+	fmt.Println("  \033[32musage days [number]\033[0m - Show usage per day and model")
+
+	fmt.Println("\033[1mUndoing file changes:\033[0m")
+	fmt.Println("  \033[32mundo\033[0m                - Restore the files changed by the AI in the last turn")
+	fmt.Println("  \033[32mcheckpoints\033[0m         - List the turns whose file changes can be undone")
+	fmt.Println("  \033[32mcheckpoints show <id>\033[0m - Show the files saved in a checkpoint")
+	fmt.Println("  \033[32mcheckpoints restore <id>\033[0m - Undo the file changes of a turn and every later turn")
+
+	fmt.Println("\033[1mSudo:\033[0m")
+	fmt.Println("  \033[32msudo on\033[0m             - Enter the sudo password, sudo commands use it without asking")
+	fmt.Println("  \033[32msudo forget\033[0m         - Forget the sudo password and sudo's cached credentials")
+
+	fmt.Println("\033[1mAudit log:\033[0m")
+	fmt.Println("  \033[32maudit\033[0m               - Show the last commands run by you and the AI")
+	fmt.Println("  \033[32maudit [since <time>] [origin <user|agent|policy>] [limit <number>] [pattern]\033[0m - Search the audit log")
+
 	fmt.Println("\033[1mExample:\033[0m")
 	fmt.Println("  \033[32mconfig set openai apikey sk-your-api-key\033[0m")
 	fmt.Println("  \033[32mconfig set general defaultshell /bin/zsh\033[0m")
 	fmt.Println("  \033[32mconfig commands add mycommand\033[0m")
 	fmt.Println("  \033[32mconfig commands remove ls\033[0m")
+	fmt.Println("  \033[32mconfig set policy mode always-ask\033[0m")
+	fmt.Println("  \033[32mconfig set context budget 60000\033[0m")
+	fmt.Println("  \033[32mconfig set usage sessionbudget 2.50\033[0m")
+	fmt.Println("  \033[32mconfig set tools outputlimit execute_command 32768 8192\033[0m")
 	fmt.Println("  \033[32mconfig save\033[0m")
 	fmt.Println()
 }
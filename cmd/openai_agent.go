@@ -2,55 +2,83 @@ package cmd
 
 import (
 	"aurora-agent/config"
-	"log"
-	"os"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// OpenAIAgent implements the AIAgent interface for OpenAI
+// OpenAIAgent implements the AIAgent interface for OpenAI and OpenAI-compatible servers
 type OpenAIAgent struct {
-	client   *openai.Client
-	model    string
-	messages []openai.ChatCompletionMessage
+	agentCore
+	client      *openai.Client
+	model       string
+	requiresKey bool
+	// streamUsage is cleared when the endpoint rejects the stream_options field
+	streamUsage bool
+}
+
+// headerDoer adds custom headers to every request sent to an endpoint
+type headerDoer struct {
+	client  *http.Client
+	headers map[string]string
+}
+
+// Do sends the request with the configured headers
+func (d *headerDoer) Do(req *http.Request) (*http.Response, error) {
+	for name, value := range d.headers {
+		req.Header.Set(name, value)
+	}
+	return d.client.Do(req)
 }
 
 // NewOpenAIAgent creates a new OpenAI agent
 func NewOpenAIAgent(apiKey string) *OpenAIAgent {
-	// If apiKey is empty, try to get it from config first, then from environment variable
+	endpoint := config.GetOpenAIEndpoint()
+
+	// If apiKey is empty, use the key of the configured endpoint.
+	// Self-hosted endpoints (Ollama, vLLM, llama.cpp) usually don't need a key,
+	// so a missing key is only reported when the official API is used.
 	if apiKey == "" {
-		// Try to get API key from config
-		apiKey = config.CurrentConfig.OpenAI.APIKey
-
-		// If still empty, try to get from environment variable
-		if apiKey == "" {
-			apiKey = os.Getenv("OPENAI_API_KEY")
-			if apiKey == "" {
-				log.Fatal("Error: OpenAI API key not found in config or environment variable (OPENAI_API_KEY).")
-				os.Exit(1)
-			}
-		}
+		apiKey = endpoint.APIKey
 	}
 
-	client := openai.NewClient(apiKey)
+	clientConfig := openai.DefaultConfig(apiKey)
+	if endpoint.BaseURL != "" {
+		clientConfig.BaseURL = strings.TrimRight(endpoint.BaseURL, "/")
+	}
+	if len(endpoint.Headers) > 0 {
+		clientConfig.HTTPClient = &headerDoer{
+			client:  &http.Client{},
+			headers: endpoint.Headers,
+		}
+	}
 
 	// Get model from config, use default if empty or invalid
-	model := config.CurrentConfig.OpenAI.Model
+	model := endpoint.Model
 	if model == "" {
 		// Use default model if not specified
 		model = openai.GPT4o
 	}
 
-	return &OpenAIAgent{
-		client: client,
-		model:  model,
-		messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: config.GetSystemPrompt(),
-			},
-		},
+	agent := &OpenAIAgent{
+		client:      openai.NewClientWithConfig(clientConfig),
+		model:       model,
+		requiresKey: apiKey == "" && endpoint.BaseURL == "",
+		streamUsage: endpoint.WantsStreamUsage(),
+	}
+	agent.agentCore = newAgentCore(agent)
+
+	return agent
+}
+
+// checkAPIKey returns an error if the official API is used without a key
+func (a *OpenAIAgent) checkAPIKey() error {
+	if a.requiresKey {
+		return fmt.Errorf("OpenAI API key not found in config or environment variable (OPENAI_API_KEY); set openai.base_url to use a local endpoint without a key")
 	}
+	return nil
 }
 
 // Name returns the name of the agent
@@ -63,10 +91,12 @@ func (a *OpenAIAgent) SetModel(model string) {
 	a.model = model
 }
 
-// StreamQueryWithFunctionCallsV2 is the new refactored version of StreamQueryWithFunctionCalls
-// It will replace StreamQueryWithFunctionCalls once the refactoring is complete
-func (a *OpenAIAgent) StreamQueryWithFunctionCallsV2(prompt string) error {
-	// For now, just call the original function
-	// This will be updated to use the new refactored functions
-	return a.StreamQueryWithFunctionCalls(prompt)
+// Model returns the model the agent uses
+func (a *OpenAIAgent) Model() string {
+	return a.model
+}
+
+// MaxOutputTokens returns 0, requests don't limit the response length
+func (a *OpenAIAgent) MaxOutputTokens() int {
+	return 0
 }
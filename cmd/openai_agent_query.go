@@ -3,36 +3,49 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// Query sends a prompt to OpenAI and returns the response
-func (a *OpenAIAgent) Query(prompt string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	a.messages = append(a.messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: prompt,
-	})
-
-	resp, err := a.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:    a.model,
-			Messages: a.messages,
-		},
-	)
+// Complete sends the conversation to OpenAI and returns the response
+func (a *OpenAIAgent) Complete(ctx context.Context, conversation *Conversation, tools []ToolDefinition) (ChatResponse, error) {
+	if err := a.checkAPIKey(); err != nil {
+		return ChatResponse{}, err
+	}
 
+	resp, err := a.client.CreateChatCompletion(ctx, a.newRequest(conversation, tools))
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API error: %v", err)
+		return ChatResponse{}, fmt.Errorf("OpenAI API error: %v", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+		return ChatResponse{}, fmt.Errorf("no response from OpenAI")
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	return ChatResponse{
+		Message: Message{
+			Role:      RoleAssistant,
+			Content:   resp.Choices[0].Message.Content,
+			ToolCalls: fromOpenAIToolCalls(resp.Choices[0].Message.ToolCalls),
+		},
+		Usage: Usage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// newRequest builds a chat completion request from the conversation
+func (a *OpenAIAgent) newRequest(conversation *Conversation, tools []ToolDefinition) openai.ChatCompletionRequest {
+	// Validate model before making the API call
+	if a.model == "" {
+		// If model is empty, use a default model
+		a.model = openai.GPT4o
+	}
+
+	return openai.ChatCompletionRequest{
+		Model:    a.model,
+		Messages: toOpenAIMessages(conversation),
+		Tools:    toOpenAITools(tools),
+	}
 }
@@ -2,28 +2,52 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// streamResponseWithFunctions creates a completion stream and processes the response
-func (a *OpenAIAgent) streamResponseWithFunctions(ctx context.Context) (string, bool, string, string, error) {
-	functions := a.getAvailableFunctions()
+// Stream creates a completion stream and processes the response
+func (a *OpenAIAgent) Stream(ctx context.Context, conversation *Conversation, tools []ToolDefinition, onText func(string)) (ChatResponse, error) {
+	if err := a.checkAPIKey(); err != nil {
+		return ChatResponse{}, err
+	}
+
+	request := a.newRequest(conversation, tools)
+	request.Stream = true
+	if a.streamUsage {
+		// Ask for token usage in the last chunk of the stream
+		request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
 
-	stream, err := a.client.CreateChatCompletionStream(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:     a.model,
-			Messages:  a.messages,
-			Stream:    true,
-			Functions: functions,
-		},
-	)
+	stream, err := a.client.CreateChatCompletionStream(ctx, request)
+	if err != nil && request.StreamOptions != nil && isRejectedRequest(err) {
+		// Some OpenAI-compatible servers don't know stream_options, ask again
+		// without it and estimate the usage from now on
+		fmt.Println("\033[90mThe endpoint rejected stream_options, token usage will be estimated\033[0m")
+		a.streamUsage = false
+		request.StreamOptions = nil
+		stream, err = a.client.CreateChatCompletionStream(ctx, request)
+	}
 	if err != nil {
-		return "", false, "", "", fmt.Errorf("OpenAI API stream error: %v", err)
+		return ChatResponse{}, fmt.Errorf("OpenAI API stream error: %v", err)
 	}
 	defer stream.Close()
 
-	return a.processStream(stream)
+	return a.processStream(stream, onText)
+}
+
+// isRejectedRequest reports whether the server refused a request as invalid
+func isRejectedRequest(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusBadRequest || apiErr.HTTPStatusCode == http.StatusUnprocessableEntity
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode == http.StatusBadRequest || requestErr.HTTPStatusCode == http.StatusUnprocessableEntity
+	}
+	return false
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newFakeOpenAIAgent starts a server that streams a short answer, with usage when
+// the request asks for it. With strict set it rejects requests with stream_options
+// like some OpenAI-compatible servers do.
+func newFakeOpenAIAgent(t *testing.T, strict bool, streamUsage bool) (*OpenAIAgent, *[]bool) {
+	t.Helper()
+	var asked []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&request)
+		_, withOptions := request["stream_options"]
+		asked = append(asked, withOptions)
+		if strict && withOptions {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"Unrecognized request argument supplied: stream_options","type":"invalid_request_error"}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, `data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"hello"}}]}`+"\n\n")
+		if withOptions {
+			io.WriteString(w, `data: {"id":"1","choices":[],"usage":{"prompt_tokens":11,"completion_tokens":3,"total_tokens":14}}`+"\n\n")
+		}
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = server.URL + "/v1"
+	agent := &OpenAIAgent{client: openai.NewClientWithConfig(clientConfig), model: "test-model", streamUsage: streamUsage}
+	agent.agentCore = newAgentCore(agent)
+	return agent, &asked
+}
+
+func TestStreamUsage(t *testing.T) {
+	tests := []struct {
+		name        string
+		strict      bool
+		streamUsage bool
+		wantAsked   string
+		wantUsage   Usage
+	}{
+		{"usage reported", false, true, "[true]", Usage{InputTokens: 11, OutputTokens: 3}},
+		{"stream_options rejected", true, true, "[true false]", Usage{}},
+		{"usage turned off", true, false, "[false]", Usage{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent, asked := newFakeOpenAIAgent(t, test.strict, test.streamUsage)
+			response, err := agent.Stream(context.Background(), NewConversation("system"), nil, func(string) {})
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			if response.Message.Content != "hello" {
+				t.Errorf("content = %q", response.Message.Content)
+			}
+			if response.Usage != test.wantUsage {
+				t.Errorf("usage = %+v, want %+v", response.Usage, test.wantUsage)
+			}
+			if fmt.Sprint(*asked) != test.wantAsked {
+				t.Errorf("stream_options sent = %v, want %s", *asked, test.wantAsked)
+			}
+
+			// A rejected stream_options is not sent again
+			if test.strict {
+				*asked = nil
+				if _, err := agent.Stream(context.Background(), NewConversation("system"), nil, func(string) {}); err != nil {
+					t.Fatalf("second Stream: %v", err)
+				}
+				if fmt.Sprint(*asked) != "[false]" {
+					t.Errorf("second request sent stream_options = %v", *asked)
+				}
+			}
+		})
+	}
+}
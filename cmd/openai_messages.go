@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"github.com/sashabaranov/go-openai"
+)
+
+// toOpenAITools converts tool definitions to OpenAI tools
+func toOpenAITools(tools []ToolDefinition) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	openAITools := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		openAITools = append(openAITools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	return openAITools
+}
+
+// toOpenAIMessages converts the conversation to OpenAI chat messages
+func toOpenAIMessages(conversation *Conversation) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: conversation.SystemPrompt,
+		},
+	}
+
+	for _, message := range conversation.Messages {
+		switch message.Role {
+		case RoleUser:
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: message.Content,
+			})
+
+		case RoleAssistant:
+			assistantMessage := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: message.Content,
+			}
+			for _, toolCall := range message.ToolCalls {
+				assistantMessage.ToolCalls = append(assistantMessage.ToolCalls, openai.ToolCall{
+					ID:   toolCall.ID,
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      toolCall.Name,
+						Arguments: toolCall.Arguments,
+					},
+				})
+			}
+			messages = append(messages, assistantMessage)
+
+		case RoleTool:
+			// Every result is a separate message linked to its call by tool_call_id
+			for _, result := range message.ToolResults {
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Name:       result.Name,
+					ToolCallID: result.CallID,
+					Content:    result.Content,
+				})
+			}
+		}
+	}
+
+	return messages
+}
+
+// fromOpenAIToolCalls converts OpenAI tool calls to conversation tool calls
+func fromOpenAIToolCalls(toolCalls []openai.ToolCall) []ToolCall {
+	var calls []ToolCall
+	for _, toolCall := range toolCalls {
+		id := toolCall.ID
+		if id == "" {
+			// Some compatible servers omit IDs, generate one so results can be matched
+			id = newToolCallID()
+		}
+		calls = append(calls, ToolCall{
+			ID:        id,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+	return calls
+}
@@ -1,23 +1,22 @@
 package cmd
 
 import (
-	"aurora-agent/utils"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// processStream processes the completion stream and returns the extracted data
-func (a *OpenAIAgent) processStream(stream *openai.ChatCompletionStream) (string, bool, string, string, error) {
+// processStream processes the completion stream and returns the assistant message
+func (a *OpenAIAgent) processStream(stream *openai.ChatCompletionStream, onText func(string)) (ChatResponse, error) {
 	// Variables to collect the response
 	fullResponse := ""
-	functionCall := ""
-	functionName := ""
-	isFunctionCall := false
 
-	// Ansi buffer
-	ansiBuffer := ""
+	// Tool calls arrive in fragments, accumulate them by their index
+	toolCalls := make(map[int]*openai.ToolCall)
+	lastIndex := 0
+	var usage Usage
 
 	// Stream the response
 	for {
@@ -26,38 +25,73 @@ func (a *OpenAIAgent) processStream(stream *openai.ChatCompletionStream) (string
 			break
 		}
 		if err != nil {
-			return "", false, "", "", fmt.Errorf("stream error: %v", err)
+			// Keep the text received so far, e.g. when the user pressed Ctrl+C
+			partial := ChatResponse{Message: Message{Role: RoleAssistant, Content: fullResponse}, Usage: usage}
+			return partial, fmt.Errorf("stream error: %v", err)
 		}
 
-		// Check for function call
-		if response.Choices[0].Delta.FunctionCall != nil {
-			isFunctionCall = true
-			if response.Choices[0].Delta.FunctionCall.Name != "" {
-				functionName = response.Choices[0].Delta.FunctionCall.Name
+		// The usage chunk has no choices
+		if response.Usage != nil {
+			usage.InputTokens = response.Usage.PromptTokens
+			usage.OutputTokens = response.Usage.CompletionTokens
+		}
+
+		if len(response.Choices) == 0 {
+			continue
+		}
+		delta := response.Choices[0].Delta
+
+		// Check for tool calls
+		for _, fragment := range delta.ToolCalls {
+			index := lastIndex
+			if fragment.Index != nil {
+				index = *fragment.Index
+			} else if existing, ok := toolCalls[index]; ok && fragment.ID != "" && existing.ID != fragment.ID {
+				// Servers that omit the index start a new call with a new ID
+				index++
+			}
+			lastIndex = index
+
+			toolCall, ok := toolCalls[index]
+			if !ok {
+				toolCall = &openai.ToolCall{Type: openai.ToolTypeFunction}
+				toolCalls[index] = toolCall
 			}
-			if response.Choices[0].Delta.FunctionCall.Arguments != "" {
-				functionCall += response.Choices[0].Delta.FunctionCall.Arguments
+			if fragment.ID != "" {
+				toolCall.ID = fragment.ID
 			}
-			continue
+			if fragment.Function.Name != "" {
+				toolCall.Function.Name = fragment.Function.Name
+			}
+			toolCall.Function.Arguments += fragment.Function.Arguments
 		}
 
 		// Get the content delta
-		content := response.Choices[0].Delta.Content
-		if content != "" {
+		if delta.Content != "" {
 			// Collect the full response
-			fullResponse += content
-
-			// add to ansi buffer
-			ansiBuffer += content
-			ansiBuffer = utils.ProcessAnsiBuffer(ansiBuffer)
+			fullResponse += delta.Content
+			onText(delta.Content)
 		}
 	}
 
-	// Process remaining buffer
-	if ansiBuffer != "" {
-		processedBuffer := utils.ProcessANSICodes(ansiBuffer)
-		fmt.Print(processedBuffer)
+	// Order tool calls by their index
+	indexes := make([]int, 0, len(toolCalls))
+	for index := range toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	ordered := make([]openai.ToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		ordered = append(ordered, *toolCalls[index])
 	}
 
-	return fullResponse, isFunctionCall, functionName, functionCall, nil
+	return ChatResponse{
+		Message: Message{
+			Role:      RoleAssistant,
+			Content:   fullResponse,
+			ToolCalls: fromOpenAIToolCalls(ordered),
+		},
+		Usage: usage,
+	}, nil
 }
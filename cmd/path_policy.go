@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"aurora-agent/config"
+)
+
+// sessionPathApprovals holds paths outside the workspace the user answered
+// "always" for in this session, everything below them is approved too
+var sessionPathApprovals = map[string]bool{}
+
+// pathGlobs caches the compiled deny globs, walks match every entry against them
+var (
+	pathGlobs   = map[string]*regexp.Regexp{}
+	pathGlobsMu sync.Mutex
+)
+
+// workspaceBase is the directory Aurora started in. Relative roots are resolved
+// against it, not against the working directory, which the AI's commands change.
+var workspaceBase, _ = os.Getwd()
+
+// resolvedRoots caches the resolved directories of the workspace roots, a root
+// is resolved once and stays where it led when it was first used
+var (
+	resolvedRoots   = map[string]string{}
+	resolvedRootsMu sync.Mutex
+)
+
+// pathApprovalMu keeps read-only tools that run in parallel from asking at the
+// same time and guards sessionPathApprovals
+var pathApprovalMu sync.Mutex
+
+// evaluatePathPolicy decides whether a file tool may use a path. Symbolic links
+// are resolved first, so a link can't lead into a denied path or out of the
+// workspace. Deny globs win, then paths inside a root are allowed, then paths
+// approved with "always", and the rest get the action configured for paths
+// outside the roots. resolved is the path the target leads to.
+func evaluatePathPolicy(target string) (decision PolicyDecision, resolved string) {
+	settings := config.CurrentConfig.Policy.Paths
+	lexical, resolved, err := resolvePolicyPath(target)
+	if err != nil {
+		return PolicyDecision{Action: config.PolicyActionDeny, Reason: err.Error()}, target
+	}
+	decide := func(action string, reason string) (PolicyDecision, string) {
+		return PolicyDecision{Action: action, Reason: reason}, resolved
+	}
+
+	// Both the path as given and where it leads must be allowed
+	if glob, denied := deniedPathGlob(lexical, resolved); denied {
+		return decide(config.PolicyActionDeny, "the deny glob "+glob)
+	}
+
+	for _, dir := range workspaceRoots() {
+		if isWithin(resolved, dir) {
+			return decide(config.PolicyActionAllow, "inside the workspace root "+dir)
+		}
+	}
+
+	for approved := range sessionPathApprovals {
+		if isWithin(resolved, approved) {
+			return decide(config.PolicyActionAllow, "approved earlier in this session")
+		}
+	}
+
+	switch settings.Outside {
+	case config.PolicyActionAllow:
+		return decide(config.PolicyActionAllow, "outside the workspace, allowed by the path policy")
+	case config.PolicyActionDeny:
+		return decide(config.PolicyActionDeny, "the path policy for paths outside the workspace")
+	default:
+		return decide(config.PolicyActionAsk, "outside the workspace")
+	}
+}
+
+// approvePathAccess applies the path policy to a path a file tool wants to use,
+// asking the user when the path is outside the workspace. access is what the
+// tool does, like read or write. Command is the resolved path.
+func approvePathAccess(target string, access string) commandApproval {
+	pathApprovalMu.Lock()
+	defer pathApprovalMu.Unlock()
+
+	decision, resolved := evaluatePathPolicy(target)
+
+	switch decision.Action {
+	case config.PolicyActionAllow:
+		return commandApproval{Command: resolved, Approved: true, Reason: decision.Reason}
+	case config.PolicyActionDeny:
+		fmt.Printf("\033[31mBlocked path: %s\033[0m\n", resolved)
+		fmt.Printf("\033[31mReason: denied by %s\033[0m\n", decision.Reason)
+		return commandApproval{Command: resolved, Reason: "denied by " + decision.Reason}
+	}
+
+	if resolved != target {
+		fmt.Printf("\033[33mAurora wants to %s outside the workspace:\033[0m \033[1m%s\033[0m \033[90m(%s)\033[0m\n", access, resolved, target)
+	} else {
+		fmt.Printf("\033[33mAurora wants to %s outside the workspace:\033[0m \033[1m%s\033[0m\n", access, resolved)
+	}
+	switch askApproval("\033[33mAllow? [y]es / [n]o / [a]lways for this path: \033[0m", "yna") {
+	case "y":
+		return commandApproval{Command: resolved, Approved: true, Reason: "approved by the user", ByUser: true}
+	case "a":
+		sessionPathApprovals[resolved] = true
+		return commandApproval{Command: resolved, Approved: true, Reason: "approved by the user for this session", ByUser: true}
+	default:
+		return commandApproval{Command: resolved, Reason: "rejected by the user", ByUser: true}
+	}
+}
+
+// approvedPath applies the path policy to a path of a file tool call and returns
+// the resolved path the tool must use, so a link changed after the approval
+// can't lead it elsewhere. When the path may not be used, ok is false and denied
+// is the result to send instead.
+func approvedPath(functionName string, target string, access string) (resolved string, denied FunctionCallResult, ok bool) {
+	approval := approvePathAccess(target, access)
+	if approval.Approved {
+		return approval.Command, FunctionCallResult{}, true
+	}
+	return "", FunctionCallResult{
+		Name:       functionName,
+		Success:    false,
+		Denied:     true,
+		DenyReason: approval.Reason,
+	}, false
+}
+
+// isPathDenied reports whether a deny glob matches a path or where it leads
+func isPathDenied(target string) bool {
+	lexical, resolved, err := resolvePolicyPath(target)
+	if err != nil {
+		return true
+	}
+	_, denied := deniedPathGlob(lexical, resolved)
+	return denied
+}
+
+// deniedPathGlob returns the first deny glob that matches one of the absolute paths
+func deniedPathGlob(paths ...string) (string, bool) {
+	for _, glob := range config.CurrentConfig.Policy.Paths.Deny {
+		for _, target := range paths {
+			if matchPathGlob(glob, target) {
+				return glob, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolvePolicyPath returns the absolute path of a target and the path it leads
+// to with every symbolic link resolved. Parts that don't exist yet, like a file
+// about to be written, are kept as they are.
+func resolvePolicyPath(target string) (lexical string, resolved string, err error) {
+	if strings.TrimSpace(target) == "" {
+		return "", "", fmt.Errorf("the path is empty")
+	}
+	lexical, err = filepath.Abs(target)
+	if err != nil {
+		return "", "", err
+	}
+	return lexical, resolveExistingPath(lexical), nil
+}
+
+// resolveExistingPath resolves the symbolic links of the longest part of a path
+// that exists and appends the rest
+func resolveExistingPath(absolute string) string {
+	current := absolute
+	var rest []string
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return absolute
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
+// workspaceRoots returns the resolved directories of the configured workspace roots
+func workspaceRoots() []string {
+	resolvedRootsMu.Lock()
+	defer resolvedRootsMu.Unlock()
+
+	var dirs []string
+	for _, root := range config.CurrentConfig.Policy.Paths.Roots {
+		dir, ok := resolvedRoots[root]
+		if !ok {
+			dir, ok = resolvePolicyRoot(root)
+			if !ok {
+				continue
+			}
+			resolvedRoots[root] = dir
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// resolvePolicyRoot returns the resolved directory of a workspace root, ~ is
+// the home directory and relative roots start at the directory Aurora started in
+func resolvePolicyRoot(root string) (string, bool) {
+	root = expandHome(root)
+	if strings.TrimSpace(root) == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(root) {
+		if workspaceBase == "" {
+			return "", false
+		}
+		root = filepath.Join(workspaceBase, root)
+	}
+	return resolveExistingPath(filepath.Clean(root)), true
+}
+
+// isWithin reports whether a path is a directory or inside it
+func isWithin(target string, dir string) bool {
+	relative, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return relative == "." || (relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)))
+}
+
+// matchPathGlob reports whether a deny glob matches an absolute path. A glob
+// without a slash matches a file or directory name anywhere, others match the
+// whole path, ~ is the home directory and a glob that doesn't start at the root
+// can start in any directory. A glob that matches a directory also matches
+// everything in it.
+func matchPathGlob(glob string, target string) bool {
+	pathGlobsMu.Lock()
+	matcher, ok := pathGlobs[glob]
+	if !ok {
+		// dir/** also matches dir itself
+		pattern := strings.TrimSuffix(filepath.ToSlash(expandHome(glob)), "/**")
+		var expression string
+		switch {
+		case !strings.Contains(pattern, "/"):
+			expression = "^(?:.*/)?" + globToRegexp(pattern) + "(?:/.*)?$"
+		case strings.HasPrefix(pattern, "/"):
+			expression = "^" + globToRegexp(pattern) + "(?:/.*)?$"
+		default:
+			expression = "^(?:.*/)?" + globToRegexp(strings.TrimPrefix(pattern, "**/")) + "(?:/.*)?$"
+		}
+		// Invalid globs are kept as nil and match nothing
+		matcher, _ = regexp.Compile(expression)
+		pathGlobs[glob] = matcher
+	}
+	pathGlobsMu.Unlock()
+	return matcher != nil && matcher.MatchString(filepath.ToSlash(target))
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
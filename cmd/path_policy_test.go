@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aurora-agent/config"
+)
+
+// useTestWorkspace makes a new directory the one Aurora started in, with the
+// default path policy
+func useTestWorkspace(t *testing.T) string {
+	t.Helper()
+	useTestHome(t)
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	savedBase := workspaceBase
+	workspaceBase = dir
+	resolvedRoots = map[string]string{}
+	t.Cleanup(func() {
+		workspaceBase = savedBase
+		resolvedRoots = map[string]string{}
+	})
+	return dir
+}
+
+func TestWorkspaceRootsStayAfterCd(t *testing.T) {
+	workspace := useTestWorkspace(t)
+	config.CurrentConfig.Policy.Paths.Outside = config.PolicyActionAsk
+	if err := os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The AI's cd / must not make the whole file system the workspace
+	for _, dir := range []string{workspace, "/"} {
+		t.Chdir(dir)
+		if decision, _ := evaluatePathPolicy("/etc/hostname"); decision.Action != config.PolicyActionAsk {
+			t.Errorf("in %s: /etc/hostname is %s (%s), want ask", dir, decision.Action, decision.Reason)
+		}
+		if decision, _ := evaluatePathPolicy(filepath.Join(workspace, "main.go")); decision.Action != config.PolicyActionAllow {
+			t.Errorf("in %s: main.go is %s (%s), want allow", dir, decision.Action, decision.Reason)
+		}
+	}
+
+	if roots := workspaceRoots(); len(roots) != 1 || roots[0] != workspace {
+		t.Errorf("roots = %v, want %s", roots, workspace)
+	}
+}
+
+func TestWorkspaceRootsAreResolvedOnce(t *testing.T) {
+	workspace := useTestWorkspace(t)
+	config.CurrentConfig.Policy.Paths.Outside = config.PolicyActionDeny
+	project := filepath.Join(workspace, "project")
+	other := filepath.Join(workspace, "other")
+	for _, dir := range []string{project, other} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(workspace, "current")
+	if err := os.Symlink(project, link); err != nil {
+		t.Fatal(err)
+	}
+	config.CurrentConfig.Policy.Paths.Roots = []string{"current"}
+	if roots := workspaceRoots(); len(roots) != 1 || roots[0] != project {
+		t.Fatalf("roots = %v, want %s", roots, project)
+	}
+
+	// Pointing the link elsewhere doesn't move the root
+	os.Remove(link)
+	if err := os.Symlink(other, link); err != nil {
+		t.Fatal(err)
+	}
+	if decision, _ := evaluatePathPolicy(filepath.Join(other, "file")); decision.Action != config.PolicyActionDeny {
+		t.Errorf("a path in the new link target is %s (%s), want deny", decision.Action, decision.Reason)
+	}
+}
+
+func TestApprovedPathIsResolved(t *testing.T) {
+	workspace := useTestWorkspace(t)
+	config.CurrentConfig.Policy.Paths.Outside = config.PolicyActionDeny
+	real := filepath.Join(workspace, "real")
+	if err := os.Mkdir(real, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(real, "notes.txt"), []byte("notes\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, filepath.Join(workspace, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, _, ok := approvedPath("read_file", "link/notes.txt", "read")
+	if !ok || resolved != filepath.Join(real, "notes.txt") {
+		t.Fatalf("approvedPath = %s, %t, want %s", resolved, ok, filepath.Join(real, "notes.txt"))
+	}
+
+	// Swapping the link after the approval doesn't change the file that is read
+	os.Remove(filepath.Join(workspace, "link"))
+	if err := os.Symlink("/etc", filepath.Join(workspace, "link")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := readFilePage(fileReadRequest{Path: resolved})
+	if err != nil || result.Path != resolved {
+		t.Errorf("readFilePage = %+v, %v", result, err)
+	}
+
+	if _, denied, ok := approvedPath("read_file", "link/hostname", "read"); ok || !denied.Denied {
+		t.Errorf("a link out of the workspace was approved: %+v", denied)
+	}
+}
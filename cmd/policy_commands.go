@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"aurora-agent/config"
+)
+
+// processPolicyCommand handles the policy command
+func processPolicyCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) == 0 || words[0] != "policy" {
+		return false
+	}
+
+	if len(words) == 1 || words[1] == "show" {
+		showPolicy()
+		return true
+	}
+
+	switch words[1] {
+	case "check":
+		// Show what the policy would do with a command, without running it
+		if len(words) < 3 {
+			fmt.Println("\033[31mError: Wrong format. Use: policy check <command>\033[0m")
+			return true
+		}
+		command := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "policy check"))
+		decision := evaluateCommandPolicy(command)
+		fmt.Printf("%s: %s\n", policyActionColor(decision.Action), decision.Reason)
+		fmt.Printf("Risk: %s\n", riskColor(decision.Risk))
+		printRiskReasons(decision.RiskReasons)
+
+	case "path":
+		// Show what the path policy would do with a path a file tool uses
+		if len(words) < 3 {
+			fmt.Println("\033[31mError: Wrong format. Use: policy path <path>\033[0m")
+			return true
+		}
+		target := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "policy path"))
+		decision, resolved := evaluatePathPolicy(target)
+		fmt.Printf("%s: %s\n", policyActionColor(decision.Action), decision.Reason)
+		if resolved != target {
+			fmt.Printf("\033[90mResolves to %s\033[0m\n", resolved)
+		}
+
+	case "forget":
+		// Drop commands approved with "always"
+		sessionApprovals = map[string]bool{}
+		sessionFileApprovals = map[string]bool{}
+		pathApprovalMu.Lock()
+		sessionPathApprovals = map[string]bool{}
+		pathApprovalMu.Unlock()
+		fmt.Println("\033[32mSession approvals cleared\033[0m")
+
+	default:
+		fmt.Println("\033[31mUnknown policy command. Available commands: show, check, path, forget\033[0m")
+	}
+
+	return true
+}
+
+// showPolicy displays the approval policy
+func showPolicy() {
+	policy := config.CurrentConfig.Policy
+
+	fmt.Println("\n\033[1mCommand approval policy:\033[0m")
+	fmt.Printf("  Mode: %s\n", policy.Mode)
+
+	fmt.Println("\n\033[1mRules:\033[0m")
+	if len(policy.Rules) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, rule := range policy.Rules {
+		fmt.Printf("  %s\n", ruleDescription(rule))
+	}
+
+	if len(policy.CommandRisks) > 0 {
+		fmt.Println("\n\033[1mCommand risk overrides:\033[0m")
+		commands := make([]string, 0, len(policy.CommandRisks))
+		for command := range policy.CommandRisks {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+		for _, command := range commands {
+			fmt.Printf("  %s: %s\n", command, riskColor(policy.CommandRisks[command]))
+		}
+	}
+
+	if len(sessionApprovals) > 0 {
+		fmt.Println("\n\033[1mApproved for this session:\033[0m")
+		commands := make([]string, 0, len(sessionApprovals))
+		for command := range sessionApprovals {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+		for _, command := range commands {
+			fmt.Printf("  %s\n", command)
+		}
+	}
+
+	if len(sessionFileApprovals) > 0 {
+		fmt.Println("\n\033[1mFiles approved for changes in this session:\033[0m")
+		files := make([]string, 0, len(sessionFileApprovals))
+		for file := range sessionFileApprovals {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			fmt.Printf("  %s\n", file)
+		}
+	}
+
+	fmt.Println("\n\033[1mFile tools:\033[0m")
+	fmt.Printf("  Workspace roots: %s\n", strings.Join(workspaceRoots(), ", "))
+	fmt.Printf("  Outside the roots: %s\n", policyActionColor(policy.Paths.Outside))
+	fmt.Println("  Denied paths:")
+	if len(policy.Paths.Deny) == 0 {
+		fmt.Println("    (none)")
+	}
+	for _, glob := range policy.Paths.Deny {
+		fmt.Printf("    %s\n", glob)
+	}
+
+	pathApprovalMu.Lock()
+	if len(sessionPathApprovals) > 0 {
+		fmt.Println("\n\033[1mPaths outside the workspace approved in this session:\033[0m")
+		paths := make([]string, 0, len(sessionPathApprovals))
+		for path := range sessionPathApprovals {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	pathApprovalMu.Unlock()
+
+	fmt.Println("\nRules are edited in the policy section of the configuration file.")
+	fmt.Println()
+}
+
+// policyActionColor returns the action name colored for display
+func policyActionColor(action string) string {
+	switch action {
+	case config.PolicyActionAllow:
+		return "\033[32mallow\033[0m"
+	case config.PolicyActionDeny:
+		return "\033[31mdeny\033[0m"
+	default:
+		return "\033[33mask\033[0m"
+	}
+}
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"aurora-agent/config"
+)
+
+// Limits of the high-entropy detector: tokens of at least this length with at
+// least this many bits of entropy per character look random
+const (
+	highEntropyMinLength = 32
+	highEntropyMinBits   = 4.5
+)
+
+// minSecretLength is the length below which a value next to a secret-looking
+// name is not taken for a secret, like "true" or "none"
+const minSecretLength = 5
+
+// placeholderPattern matches the placeholders that replace secrets in what the model sees
+var placeholderPattern = regexp.MustCompile(`\[REDACTED_[A-Z_]+_\d+\]`)
+
+// secretValue matches a quoted or bare value after a secret-looking name, the
+// first of its groups that matched is the secret
+const secretValue = `(?:"([^"\n]+)"|'([^'\n]+)'|([^\s'",;]+))`
+
+// secretDetector finds one kind of secret. When the pattern has groups, the
+// first group that matched is the secret, otherwise the whole match is.
+type secretDetector struct {
+	kind    string
+	pattern *regexp.Regexp
+	// check is an extra test of the secret and the text matched before it, like
+	// the name of a NAME=value pair, nil accepts every match
+	check func(name string, secret string) bool
+	// entropy marks the high-entropy detector, which can be turned off
+	entropy bool
+}
+
+// builtinSecretDetectors find the secrets Aurora hides without configuration.
+// Private keys come first so their lines aren't taken for tokens one by one.
+var builtinSecretDetectors = []secretDetector{
+	{kind: "PRIVATE_KEY", pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)},
+	{kind: "AWS_KEY", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "AWS_SECRET", pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{kind: "JWT", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`)},
+	// OpenAI and Anthropic keys, GitHub and Slack tokens, Google API keys
+	{kind: "API_KEY", pattern: regexp.MustCompile(`\b(?:sk-(?:ant-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{30,}|xox[abposr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`)},
+	{kind: "TOKEN", pattern: regexp.MustCompile(`(?i)\bauthorization:\s*(?:bearer|basic|token)\s+([^\s'"]+)`)},
+	{kind: "PASSWORD", pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`)},
+	// NAME=value, name: value and "name": "value" where the name says it is a
+	// secret, like in .env files, printenv and configuration files
+	{kind: "SECRET", pattern: regexp.MustCompile(`(?i)\b[a-z0-9_.-]*(?:password|passwd|[_.-]pwd|secret|token|(?:api|access|private|secret|client|auth|app|master|signing|encryption|license)[_-]?key|credentials?)["']?[ \t]*[=:][ \t]*` + secretValue), check: isSecretLike},
+	// --password value, --token=value and the like
+	{kind: "SECRET", pattern: regexp.MustCompile(`(?i)(?:^|\s)--?(?:password|passwd|token|secret|api-key|apikey|access-key)(?:=|[ \t]+)` + secretValue), check: isSecretLike},
+	// Long random-looking tokens. Checksums like the h1: hashes of go.sum and the
+	// sha512- integrity hashes of lock files are part of the match to be let through.
+	{kind: "TOKEN", pattern: regexp.MustCompile(`(?i:\b(?:h1|md5|sha1|sha224|sha256|sha384|sha512)[:-])?[A-Za-z0-9+/=_-]{32,}`), check: func(_ string, token string) bool { return isHighEntropy(token) }, entropy: true},
+}
+
+// secretVault keeps the secrets hidden from the model and their placeholders, so
+// the same secret always gets the same placeholder and placeholders the model
+// uses in tool calls can be replaced with the secrets again. It is only kept in
+// memory, placeholders saved in sessions of earlier runs stay as they are.
+type secretVault struct {
+	mu           sync.Mutex
+	placeholders map[string]string // secret -> placeholder
+	values       map[string]string // placeholder -> secret
+	// patterns caches the compiled user patterns, nil for invalid ones
+	patterns map[string]*regexp.Regexp
+}
+
+// secrets is the secret vault of this Aurora process
+var secrets = &secretVault{
+	placeholders: map[string]string{},
+	values:       map[string]string{},
+	patterns:     map[string]*regexp.Regexp{},
+}
+
+// detectors returns the built-in detectors, without the high-entropy one when it
+// is turned off, followed by the patterns of the user
+func (v *secretVault) detectors() []secretDetector {
+	settings := config.CurrentConfig.Redaction
+	detectors := make([]secretDetector, 0, len(builtinSecretDetectors)+len(settings.Patterns))
+	for _, detector := range builtinSecretDetectors {
+		if detector.entropy && !settings.HighEntropy {
+			continue
+		}
+		detectors = append(detectors, detector)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, pattern := range settings.Patterns {
+		re, ok := v.patterns[pattern]
+		if !ok {
+			re, _ = regexp.Compile(pattern)
+			v.patterns[pattern] = re
+		}
+		if re != nil {
+			detectors = append(detectors, secretDetector{kind: "CUSTOM", pattern: re})
+		}
+	}
+	return detectors
+}
+
+// replaceSecrets replaces every secret the detectors find in a text with what
+// replace returns for it
+func replaceSecrets(text string, detectors []secretDetector, replace func(kind string, secret string) string) string {
+	for _, detector := range detectors {
+		matches := detector.pattern.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var out strings.Builder
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			for group := 1; group*2 < len(match); group++ {
+				if match[group*2] >= 0 && match[group*2+1] > match[group*2] {
+					start, end = match[group*2], match[group*2+1]
+					break
+				}
+			}
+			secret := text[start:end]
+			if !isSecretValue(secret) || (detector.check != nil && !detector.check(text[match[0]:start], secret)) {
+				continue
+			}
+			out.WriteString(text[last:start])
+			out.WriteString(replace(detector.kind, secret))
+			last = end
+		}
+		out.WriteString(text[last:])
+		text = out.String()
+	}
+	return text
+}
+
+// isSecretValue rules out values that can't be secrets: short words, variable
+// references and placeholders of secrets already hidden
+func isSecretValue(value string) bool {
+	if len(value) < minSecretLength || strings.HasPrefix(value, "$") {
+		return false
+	}
+	return !placeholderPattern.MatchString(value)
+}
+
+// checksumPrefix matches the start of checksums, which look random but are not secret
+var checksumPrefix = regexp.MustCompile(`(?i)^(?:h1|md5|sha1|sha224|sha256|sha384|sha512)[:-]`)
+
+// identifierWords matches values made of lowercase words, like created_at_desc,
+// read-only or string, which name things rather than hide them
+var identifierWords = regexp.MustCompile(`^[a-z]+(?:[_.-][a-z]+)*$`)
+
+// maxIdentifierLength is the length from which lowercase words are taken for a
+// passphrase, like correct-horse-battery-staple
+const maxIdentifierLength = 20
+
+// secretName matches the names whose values are always secrets, however they
+// look. MYSQL_PWD is one, PWD and OLDPWD are not.
+var secretName = regexp.MustCompile(`(?i)password|passwd|[_.-]pwd|secret|token|credential|api[_-]?key|apikey`)
+
+// isSecretLike reports whether the value of a secret-looking name can be a
+// secret. Names that only hint at one, like encryption_key or license_key, can
+// hold short lowercase words like aes-gcm or trial that name things rather than
+// hide them. The values of passwords, tokens, secrets, credentials and API keys
+// are always secrets.
+func isSecretLike(name string, value string) bool {
+	if secretName.MatchString(name) {
+		return true
+	}
+	return len(value) >= maxIdentifierLength || !identifierWords.MatchString(value)
+}
+
+// isHighEntropy reports whether a token looks random: it mixes upper and lower
+// case letters and digits and has many bits of entropy per character. Checksums
+// are not taken for secrets.
+func isHighEntropy(token string) bool {
+	if len(token) < highEntropyMinLength || checksumPrefix.MatchString(token) {
+		return false
+	}
+	var upper, lower, digit bool
+	counts := map[rune]int{}
+	for _, r := range token {
+		counts[r]++
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(token))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= highEntropyMinBits
+}
+
+// placeholder returns the placeholder of a secret, making a new one for secrets
+// not seen before
+func (v *secretVault) placeholder(kind string, secret string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if placeholder, ok := v.placeholders[secret]; ok {
+		return placeholder
+	}
+	placeholder := fmt.Sprintf("[REDACTED_%s_%d]", kind, len(v.values)+1)
+	v.placeholders[secret] = placeholder
+	v.values[placeholder] = secret
+	return placeholder
+}
+
+// redact replaces the secrets in a text that goes to the model with placeholders
+// and returns the kinds of secrets it found
+func (v *secretVault) redact(text string) (string, []string) {
+	if !config.CurrentConfig.Redaction.Enabled || text == "" {
+		return text, nil
+	}
+	var kinds []string
+	// Secrets hidden before are hidden wherever they show up again
+	for _, secret := range v.knownSecrets() {
+		if strings.Contains(text, secret) {
+			placeholder := v.placeholder("", secret)
+			text = strings.ReplaceAll(text, secret, placeholder)
+			kinds = append(kinds, placeholderKind(placeholder))
+		}
+	}
+	redacted := replaceSecrets(text, v.detectors(), func(kind string, secret string) string {
+		kinds = append(kinds, kind)
+		return v.placeholder(kind, secret)
+	})
+	return redacted, kinds
+}
+
+// knownSecrets returns the secrets hidden so far, longest first so that one that
+// contains another is replaced whole
+func (v *secretVault) knownSecrets() []string {
+	v.mu.Lock()
+	known := make([]string, 0, len(v.placeholders))
+	for secret := range v.placeholders {
+		known = append(known, secret)
+	}
+	v.mu.Unlock()
+	sort.Slice(known, func(i, j int) bool { return len(known[i]) > len(known[j]) })
+	return known
+}
+
+// placeholderKind returns the kind of secret a placeholder stands for
+func placeholderKind(placeholder string) string {
+	kind := strings.TrimSuffix(strings.TrimPrefix(placeholder, "[REDACTED_"), "]")
+	return kind[:strings.LastIndex(kind, "_")]
+}
+
+// restore replaces the placeholders in a text with their secrets, encode formats
+// the secrets for the text
+func (v *secretVault) restore(text string, encode func(secret string) string) string {
+	if !strings.Contains(text, "[REDACTED_") {
+		return text
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		secret, ok := v.values[placeholder]
+		if !ok {
+			return placeholder
+		}
+		return encode(secret)
+	})
+}
+
+// restoreArguments replaces the placeholders in the JSON arguments of a tool call
+// with their secrets. Placeholders are only found inside JSON strings, so the
+// secrets are escaped as JSON string content.
+func (v *secretVault) restoreArguments(arguments string) string {
+	return v.restore(arguments, func(secret string) string {
+		encoded, _ := json.Marshal(secret)
+		return string(encoded[1 : len(encoded)-1])
+	})
+}
+
+// hide replaces the secrets the detectors find and the ones hidden from the
+// model with a fixed marker, for texts that are kept, like the audit log
+func (v *secretVault) hide(text string, marker string) string {
+	for _, secret := range v.knownSecrets() {
+		text = strings.ReplaceAll(text, secret, marker)
+	}
+	return replaceSecrets(text, v.detectors(), func(string, string) string { return marker })
+}
+
+// redactForModel hides the secrets in a text that goes to the model and tells
+// the user what was hidden
+func redactForModel(text string, what string) string {
+	redacted, kinds := secrets.redact(text)
+	reportRedaction(kinds, what)
+	return redacted
+}
+
+// redactResult hides the secrets in the outputs of a tool
+func redactResult(result FunctionCallResult) FunctionCallResult {
+	var kinds, found []string
+	result.Output, found = secrets.redact(result.Output)
+	kinds = append(kinds, found...)
+	result.Stdout, found = secrets.redact(result.Stdout)
+	kinds = append(kinds, found...)
+	result.Stderr, found = secrets.redact(result.Stderr)
+	kinds = append(kinds, found...)
+	reportRedaction(kinds, "the result of "+result.Name)
+	return result
+}
+
+// reportRedaction tells the user which kinds of secrets were hidden from the model
+func reportRedaction(kinds []string, what string) {
+	if len(kinds) > 0 {
+		fmt.Printf("\033[90mHid %d secret(s) in %s from the model: %s\033[0m\n", len(kinds), what, strings.Join(uniqueStrings(kinds), ", "))
+	}
+}
+
+// uniqueStrings returns the strings without repetitions, in their order
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
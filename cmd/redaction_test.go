@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinSecretDetectors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// hidden are the secrets that must be found, kept what must stay
+		hidden []string
+		kept   []string
+	}{
+		{"env file", "DB_PASSWORD=hunter22\nAPI_KEY='q8Zr2mXw'", []string{"hunter22", "q8Zr2mXw"}, []string{"DB_PASSWORD", "API_KEY"}},
+		{"yaml secret key", "client_secret: 9dK2!xPq\nsecret_key: Ab12Cd34Ef", []string{"9dK2!xPq", "Ab12Cd34Ef"}, nil},
+		{"json token", `{"access_token": "ya29.a0AfH6SMB"}`, []string{"ya29.a0AfH6SMB"}, nil},
+		{"passphrase", "password: correct-horse-battery-staple", []string{"correct-horse-battery-staple"}, nil},
+		{"command option", "mysql --password=s3cr3tPw -u root", []string{"s3cr3tPw"}, []string{"root"}},
+		{"sort key", "sort_key: created_at_desc\nprimary_key: id_column", nil, []string{"created_at_desc", "id_column"}},
+		{"partition key", `"partition_key": "tenant-id"`, nil, []string{"tenant-id"}},
+		{"names that only hint at a secret", "encryption_key: aes-gcm\nlicense_key: trial-edition\npassword_field: hidden\nsecret: none", nil, []string{"aes-gcm", "trial-edition", "hidden"}},
+		{"lowercase password", "DB_PASSWORD=letmein", []string{"letmein"}, []string{"DB_PASSWORD"}},
+		{"lowercase yaml password", "password: hunter", []string{"hunter"}, nil},
+		{"lowercase token", "GITHUB_TOKEN=abcdefghijklmnop", []string{"abcdefghijklmnop"}, nil},
+		{"lowercase option", "mysql --password=letmein", []string{"letmein"}, nil},
+		{"lowercase api key", `{"api_key": "staging"}`, []string{"staging"}, nil},
+		{"pwd", "MYSQL_PWD=Xy12345678\nPWD=/root/module\nOLDPWD=/root", []string{"Xy12345678"}, []string{"/root/module", "OLDPWD=/root"}},
+		{"api key prefix", "key sk-ant-REDACTED", []string{"sk-ant-REDACTED"}, nil},
+		{"random token", "value K7x9Qm2LpR4tVw8ZbN3cYf6HjD1sGk5Ea0Uo", []string{"K7x9Qm2LpR4tVw8ZbN3cYf6HjD1sGk5Ea0Uo"}, nil},
+		{
+			"go.sum",
+			"golang.org/x/text v0.14.0 h1:ScX5w1eTa3QqT8oi6+ziP7dTV1S2+ALU0bI+0zXKWiQ=\n" +
+				"golang.org/x/text v0.14.0/go.mod h1:18ZOQIKpY8NJVqYksKHtTdi31H5itFRjB5/qKTNYzSU=",
+			nil,
+			[]string{"h1:ScX5w1eTa3QqT8oi6+ziP7dTV1S2+ALU0bI+0zXKWiQ=", "h1:18ZOQIKpY8NJVqYksKHtTdi31H5itFRjB5/qKTNYzSU="},
+		},
+		{
+			"lock file integrity",
+			`"integrity": "sha512-Wk4GbbxKvkZMyZyLfQ4iX9Vq8wX9Zkh2uPn7sj0ZRmB4sQ3dEwLhZ1kCj8Nq6LtV0pTsGfHu2eRxYo5mIaJcDw=="`,
+			nil,
+			[]string{"sha512-Wk4GbbxKvkZMyZyLfQ4iX9Vq8wX9Zkh2uPn7sj0ZRmB4sQ3dEwLhZ1kCj8Nq6LtV0pTsGfHu2eRxYo5mIaJcDw=="},
+		},
+		{"hex digest", "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", nil, []string{"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			redacted := replaceSecrets(test.text, builtinSecretDetectors, func(kind string, secret string) string {
+				return "[REDACTED_" + kind + "_1]"
+			})
+			for _, secret := range test.hidden {
+				if strings.Contains(redacted, secret) {
+					t.Errorf("%q was not hidden:\n%s", secret, redacted)
+				}
+			}
+			for _, kept := range test.kept {
+				if !strings.Contains(redacted, kept) {
+					t.Errorf("%q was hidden:\n%s", kept, redacted)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSecretLike(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"encryption_key: ", "aes-gcm", false},
+		{"license_key=", "read-only", false},
+		{"signing_key: ", "config.yaml", false},
+		{"encryption_key: ", "hunter22", true},
+		{"encryption_key: ", "Secret", true},
+		{"license_key: ", "correct-horse-battery-staple", true},
+		{"DB_PASSWORD=", "letmein", true},
+		{"password: ", "hunter", true},
+		{"GITHUB_TOKEN=", "abcdefghijklmnop", true},
+		{" --password=", "letmein", true},
+		{"MYSQL_PWD=", "secretpw", true},
+		{"client_secret: ", "string", true},
+		{"aws_credentials=", "default", true},
+		{"API_KEY=", "staging", true},
+	}
+	for _, test := range tests {
+		if got := isSecretLike(test.name, test.value); got != test.want {
+			t.Errorf("isSecretLike(%q, %q) = %t, want %t", test.name, test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aurora-agent/config"
+)
+
+// Session is a conversation stored on disk so it can be resumed later
+type Session struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name,omitempty"`
+	Agent        AgentType     `json:"agent"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Conversation *Conversation `json:"conversation"`
+	// Usage adds up the tokens and cost of all requests made in the session
+	Usage UsageTotals `json:"usage"`
+}
+
+// NewSession creates an empty session for the given agent
+func NewSession(agent AgentType) *Session {
+	now := time.Now()
+	return &Session{
+		ID:           newSessionID(now),
+		Agent:        agent,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Conversation: NewConversation(config.GetSystemPrompt()),
+	}
+}
+
+// newSessionID generates a sortable, unique session ID
+func newSessionID(now time.Time) string {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return now.Format("20060102-150405")
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(buf)
+}
+
+// Title returns the session name, or the start of the first user message
+func (s *Session) Title() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	for _, message := range s.Conversation.Messages {
+		if message.Role == RoleUser && message.Content != "" && !message.Summary {
+			return shortenText(message.Content, 50)
+		}
+	}
+	return "(empty)"
+}
+
+// shortenText puts a text on one line and cuts it to at most limit characters,
+// ending it with ... when it is cut
+func shortenText(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-3]) + "..."
+}
+
+// path returns the file the session is stored in
+func (s *Session) path() string {
+	return filepath.Join(config.GetSessionsDir(), s.ID+".json")
+}
+
+// Save writes the session to disk.
+// Sessions may contain command output, so only the user can read them.
+func (s *Session) Save() error {
+	dir := config.GetSessionsDir()
+	if dir == "" {
+		return fmt.Errorf("sessions directory not available")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written session
+	tmp, err := os.CreateTemp(dir, s.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// Fork copies the session into a new session with its own ID
+func (s *Session) Fork(name string) (*Session, error) {
+	data, err := json.Marshal(s.Conversation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy conversation: %w", err)
+	}
+
+	fork := NewSession(s.Agent)
+	fork.Name = name
+	if err := json.Unmarshal(data, fork.Conversation); err != nil {
+		return nil, fmt.Errorf("failed to copy conversation: %w", err)
+	}
+	return fork, nil
+}
+
+// Delete removes the session from disk
+func (s *Session) Delete() error {
+	if err := os.Remove(s.path()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	deleteCheckpoints(s.ID)
+	return nil
+}
+
+// loadSession reads a session file
+func loadSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", filepath.Base(path), err)
+	}
+	if session.Conversation == nil {
+		session.Conversation = NewConversation(config.GetSystemPrompt())
+	}
+	return &session, nil
+}
+
+// ListSessions returns the stored sessions, most recently used first
+func ListSessions() ([]*Session, error) {
+	dir := config.GetSessionsDir()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(paths))
+	for _, path := range paths {
+		session, err := loadSession(path)
+		if err != nil {
+			fmt.Printf("\033[31mWarning: %v\033[0m\n", err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// FindSession finds a stored session by ID, unique ID prefix or name
+func FindSession(ref string) (*Session, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Session
+	for _, session := range sessions {
+		if session.ID == ref || session.Name == ref {
+			return session, nil
+		}
+		if strings.HasPrefix(session.ID, ref) {
+			matches = append(matches, session)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("session '%s' not found", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("'%s' matches %d sessions, use a longer ID", ref, len(matches))
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// processSessionCommand handles the session command
+func processSessionCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) == 0 || words[0] != "session" {
+		return false
+	}
+
+	if len(words) == 1 || words[1] == "show" {
+		showSession(AgentMgr.Session())
+		return true
+	}
+
+	// Everything after the subcommand, names may contain spaces
+	arg := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "session "+words[1]))
+
+	switch words[1] {
+	case "list":
+		listSessions()
+
+	case "new":
+		AgentMgr.SwitchSession(NewSession(AgentMgr.activeType))
+		fmt.Println("\033[32mStarted a new session\033[0m")
+
+	case "resume":
+		session, err := resumeTarget(arg)
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		AgentMgr.SwitchSession(session)
+		fmt.Printf("\033[32mResumed session %s: %s (%d messages)\033[0m\n",
+			session.ID, session.Title(), len(session.Conversation.Messages))
+
+	case "rename":
+		if arg == "" {
+			fmt.Println("\033[31mError: Wrong format. Use: session rename <name>\033[0m")
+			return true
+		}
+		session := AgentMgr.Session()
+		session.Name = arg
+		if err := session.Save(); err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		fmt.Printf("\033[32mSession %s renamed to %s\033[0m\n", session.ID, arg)
+
+	case "delete":
+		if arg == "" {
+			fmt.Println("\033[31mError: Wrong format. Use: session delete <id|name>\033[0m")
+			return true
+		}
+		session, err := FindSession(arg)
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		if err := session.Delete(); err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		fmt.Printf("\033[32mSession %s deleted\033[0m\n", session.ID)
+
+		// Don't keep writing to a deleted session
+		if session.ID == AgentMgr.Session().ID {
+			AgentMgr.SwitchSession(NewSession(AgentMgr.activeType))
+			fmt.Println("\033[32mStarted a new session\033[0m")
+		}
+
+	case "fork":
+		fork, err := AgentMgr.Session().Fork(arg)
+		if err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		if err := fork.Save(); err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return true
+		}
+		AgentMgr.SwitchSession(fork)
+		fmt.Printf("\033[32mForked into session %s, the original session is unchanged\033[0m\n", fork.ID)
+
+	default:
+		fmt.Println("\033[31mUnknown session command. Available commands: show, list, new, resume, rename, delete, fork\033[0m")
+	}
+
+	return true
+}
+
+// resumeTarget finds the session to resume, the most recent other session when ref is empty
+func resumeTarget(ref string) (*Session, error) {
+	if ref != "" {
+		return FindSession(ref)
+	}
+
+	sessions, err := ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.ID != AgentMgr.Session().ID {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("no saved session to resume")
+}
+
+// showSession displays the current session
+func showSession(session *Session) {
+	fmt.Println("\n\033[1mCurrent session:\033[0m")
+	fmt.Printf("  ID: %s\n", session.ID)
+	fmt.Printf("  Title: %s\n", session.Title())
+	fmt.Printf("  Agent: %s\n", session.Agent)
+	fmt.Printf("  Messages: %d\n", len(session.Conversation.Messages))
+	fmt.Printf("  Started: %s\n", session.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Println()
+}
+
+// listSessions displays the stored sessions, most recent first
+func listSessions() {
+	sessions, err := ListSessions()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No saved sessions")
+		return
+	}
+
+	current := AgentMgr.Session().ID
+	fmt.Println("\n\033[1mSessions:\033[0m")
+	for _, session := range sessions {
+		marker := " "
+		if session.ID == current {
+			marker = "\033[32m*\033[0m"
+		}
+		fmt.Printf("%s %s  %s  %-6s %3d msgs  %s\n", marker, session.ID,
+			session.UpdatedAt.Format("2006-01-02 15:04"), session.Agent,
+			len(session.Conversation.Messages), session.Title())
+	}
+	fmt.Println()
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestShortenText(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"  spread \n over\tlines ", 20, "spread over lines"},
+		{"exactly ten", 11, "exactly ten"},
+		{"one more than", 12, "one more ..."},
+		{strings.Repeat("ж", 60), 50, strings.Repeat("ж", 47) + "..."},
+		{strings.Repeat("日本", 30), 10, "日本日本日本日..."},
+	}
+	for _, test := range tests {
+		got := shortenText(test.text, test.limit)
+		if got != test.want {
+			t.Errorf("shortenText(%q, %d) = %q, want %q", test.text, test.limit, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("shortenText(%q, %d) is not valid UTF-8", test.text, test.limit)
+		}
+	}
+}
+
+func TestSessionTitle(t *testing.T) {
+	session := &Session{Conversation: &Conversation{Messages: []Message{
+		{Role: RoleUser, Content: "summary of earlier turns", Summary: true},
+		{Role: RoleUser, Content: strings.Repeat("привет ", 20)},
+	}}}
+	title := session.Title()
+	if !utf8.ValidString(title) || utf8.RuneCountInString(title) != 50 || !strings.HasSuffix(title, "...") {
+		t.Errorf("Title() = %q", title)
+	}
+
+	session.Name = "named"
+	if got := session.Title(); got != "named" {
+		t.Errorf("Title() = %q, want named", got)
+	}
+}
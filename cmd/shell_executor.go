@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"aurora-agent/config"
+	"aurora-agent/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shellStateArg is the hidden argument with which a shell runs Aurora to report
+// its working directory and environment
+const shellStateArg = "__aurora-shell-state"
+
+// Variables that pass the state capture to the shell, they are never carried over
+const (
+	shellExeVar   = "__AURORA_EXE"
+	shellStateVar = "__AURORA_STATE_DIR"
+)
+
+// stateCaptureScript runs before a command line: it saves the state of the shell
+// before the command and, when the shell exits, after it. The exit code is kept.
+const stateCaptureScript = `"$` + shellExeVar + `" ` + shellStateArg + ` "$` + shellStateVar + `/before"
+trap '__aurora_status=$?; "$` + shellExeVar + `" ` + shellStateArg + ` "$` + shellStateVar + `/after"; exit $__aurora_status' EXIT
+`
+
+// posixShells are the shells that understand stateCaptureScript
+var posixShells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "mksh": true, "ash": true, "yash": true,
+}
+
+// ignoredStateVars are set by shells themselves and are not carried over
+var ignoredStateVars = map[string]bool{
+	shellExeVar: true, shellStateVar: true, "_": true, "SHLVL": true, "PWD": true, "OLDPWD": true,
+}
+
+// protectedStateVars decide which programs run and how, like the sudo that gets
+// the password, or hold Aurora's own settings. Commands of the AI can't change
+// them for later commands, and they are not copied into the user's shell.
+var protectedStateVars = map[string]bool{
+	"PATH": true, "HOME": true, "SHELL": true, "IFS": true, "ENV": true, "BASH_ENV": true,
+	"ZDOTDIR": true, "PROMPT_COMMAND": true, "PS4": true, "SUDO_ASKPASS": true,
+}
+
+// protectedStateVarPrefixes start the names of protected variables: the dynamic
+// linker's, sudo's, and the ones of Aurora and the model providers
+var protectedStateVarPrefixes = []string{"LD_", "DYLD_", "SUDO_", "AURORA_", "OPENAI_", "ANTHROPIC_"}
+
+// isProtectedStateVar reports whether a variable is protected from commands of the AI
+func isProtectedStateVar(name string) bool {
+	if protectedStateVars[name] {
+		return true
+	}
+	for _, prefix := range protectedStateVarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// agentVars are the variables the commands of the AI exported, changed or unset,
+// nil for unset ones. They apply to the later commands of the AI, but not to
+// Aurora and the user's shell, so the AI can't slip an EDITOR, GIT_SSH_COMMAND
+// or NODE_OPTIONS into the commands of the user.
+var agentVars = struct {
+	sync.Mutex
+	values map[string]*string
+}{values: map[string]*string{}}
+
+// setAgentVar records a variable a command of the AI changed, nil unsets it
+func setAgentVar(name string, value *string) {
+	agentVars.Lock()
+	defer agentVars.Unlock()
+	agentVars.values[name] = value
+}
+
+// forgetAgentVar drops the change of the AI to a variable the user changed since
+func forgetAgentVar(name string) {
+	agentVars.Lock()
+	defer agentVars.Unlock()
+	delete(agentVars.values, name)
+}
+
+// agentEnviron returns env with the variables the commands of the AI changed
+func agentEnviron(env []string) []string {
+	agentVars.Lock()
+	defer agentVars.Unlock()
+	if len(agentVars.values) == 0 {
+		return env
+	}
+	result := make([]string, 0, len(env)+len(agentVars.values))
+	for _, pair := range env {
+		name, _, _ := strings.Cut(pair, "=")
+		if _, changed := agentVars.values[name]; !changed {
+			result = append(result, pair)
+		}
+	}
+	names := make([]string, 0, len(agentVars.values))
+	for name := range agentVars.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value := agentVars.values[name]; value != nil {
+			result = append(result, name+"="+*value)
+		}
+	}
+	return result
+}
+
+// shellState is the working directory and environment of a shell at one moment
+type shellState struct {
+	Dir string
+	Env []string
+}
+
+// HandleShellStateDump writes the state of the calling shell to the file given
+// after shellStateArg and reports whether Aurora was started to do so.
+// main calls it before anything else.
+func HandleShellStateDump() bool {
+	if len(os.Args) != 3 || os.Args[1] != shellStateArg {
+		return false
+	}
+
+	state := shellState{Env: os.Environ()}
+	state.Dir, _ = os.Getwd()
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = os.WriteFile(os.Args[2], data, 0600)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+	return true
+}
+
+// UserShell returns the shell of the user: the configured one, else $SHELL, else bash
+func UserShell() string {
+	if config.CurrentConfig.General.DefaultShell != "" {
+		return config.CurrentConfig.General.DefaultShell
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/bash"
+}
+
+// isPOSIXShell reports whether a shell understands sh syntax
+func isPOSIXShell(shell string) bool {
+	return posixShells[filepath.Base(shell)]
+}
+
+// agentShell returns the shell the AI's commands run in: the user's shell when it
+// speaks sh syntax, the AI writes commands for sh-like shells
+func agentShell() string {
+	if shell := UserShell(); isPOSIXShell(shell) {
+		return shell
+	}
+	return "bash"
+}
+
+// shellRun is a command line run in a shell whose working directory and exported
+// variables are applied when it ends, so that cd and export carry over to the
+// next command of the user or the AI
+type shellRun struct {
+	Cmd      *exec.Cmd
+	stateDir string
+	// agent is set for commands of the AI, they can't change protected variables
+	agent bool
+}
+
+// newShellRun prepares a command line for a shell. Interactive runs load the rc
+// files of the shell, for aliases, like a terminal would. The state is only
+// captured by shells that speak sh syntax.
+func newShellRun(ctx context.Context, shell string, commandLine string, interactive bool) *shellRun {
+	run := &shellRun{}
+	var env []string
+	script := commandLine
+	if isPOSIXShell(shell) {
+		exe, err := os.Executable()
+		var dir string
+		if err == nil {
+			dir, err = os.MkdirTemp("", "aurora-shell-")
+		}
+		if err == nil {
+			run.stateDir = dir
+			script = stateCaptureScript + commandLine
+			env = append(os.Environ(), shellExeVar+"="+exe, shellStateVar+"="+dir)
+		}
+	}
+
+	args := []string{"-c", script}
+	if interactive {
+		args = []string{"-i", "-c", script}
+	}
+	run.Cmd = exec.CommandContext(ctx, shell, args...)
+	run.Cmd.Env = env
+	return run
+}
+
+// newAgentShellRun prepares a command line of the AI for the shell the AI's
+// commands run in, with the aliases, functions and variables of the user's
+// shell session and the variables earlier commands of the AI changed
+func newAgentShellRun(ctx context.Context, commandLine string) *shellRun {
+	shell := agentShell()
+	run := newShellRun(ctx, shell, sessionPrelude(shell)+commandLine, false)
+	run.agent = true
+	if run.Cmd.Env == nil {
+		run.Cmd.Env = os.Environ()
+	}
+	run.Cmd.Env = agentEnviron(run.Cmd.Env)
+	return run
+}
+
+// finish applies the working directory and the variables the command line
+// exported, changed or removed to Aurora, or for commands of the AI to the AI's
+// later commands. It returns the protected variables a command of the AI
+// changed, which are left as they were.
+func (r *shellRun) finish() []string {
+	if r.stateDir == "" {
+		return nil
+	}
+	defer os.RemoveAll(r.stateDir)
+
+	// Shells replaced with exec or killed don't leave a state behind
+	after, err := readShellState(filepath.Join(r.stateDir, "after"))
+	if err != nil {
+		return nil
+	}
+	// Only the changes made by the command line count, not the ones of rc files
+	before, err := readShellState(filepath.Join(r.stateDir, "before"))
+	if err != nil {
+		before = shellState{Env: after.Env}
+	}
+	return applyShellState(before, after, r.agent)
+}
+
+// applyShellState applies the working directory of after and the variables that
+// changed between before and after to Aurora. With fromAgent set the variables
+// only apply to the later commands of the AI and the protected ones are left as
+// they are, the ones that changed are returned.
+func applyShellState(before shellState, after shellState, fromAgent bool) []string {
+	if current, err := os.Getwd(); after.Dir != "" && (err != nil || current != after.Dir) {
+		changeDirectory(after.Dir)
+	}
+
+	previous := envMap(before.Env)
+	next := envMap(after.Env)
+	var kept []string
+	for name, value := range next {
+		if old, ok := previous[name]; (ok && old == value) || ignoredStateVars[name] {
+			continue
+		}
+		if fromAgent && isProtectedStateVar(name) {
+			kept = append(kept, name)
+			continue
+		}
+		if fromAgent {
+			setAgentVar(name, &value)
+			continue
+		}
+		forgetAgentVar(name)
+		os.Setenv(name, value)
+	}
+	for name := range previous {
+		if _, ok := next[name]; ok || ignoredStateVars[name] {
+			continue
+		}
+		if fromAgent && isProtectedStateVar(name) {
+			kept = append(kept, name)
+			continue
+		}
+		if fromAgent {
+			setAgentVar(name, nil)
+			continue
+		}
+		forgetAgentVar(name)
+		os.Unsetenv(name)
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// readShellState reads a state written by HandleShellStateDump
+func readShellState(file string) (shellState, error) {
+	var state shellState
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// envMap converts NAME=value pairs into a map
+func envMap(env []string) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, pair := range env {
+		if name, value, ok := strings.Cut(pair, "="); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// RunShellCommand runs a command line the user typed in their shell with a PTY
+// and returns its exit code. Shells that speak sh syntax keep running between
+// commands, so everything the command line changes in the shell stays; cd and
+// export carry over to Aurora and the AI as well. Other shells start anew for
+// each command line. The command line is recorded in the audit log.
+func RunShellCommand(commandLine string) int {
+	record := newAuditRecord(auditOriginUser, commandLine)
+	started := time.Now()
+	status := runInUserShell(commandLine)
+	record.finish(&status, time.Since(started))
+	recordAudit(record)
+	lastExitStatus = status
+	return status
+}
+
+// runInUserShell runs a command line in the session of the user's shell, or in a
+// shell of its own when there is no session
+func runInUserShell(commandLine string) int {
+	shell := UserShell()
+	if isPOSIXShell(shell) {
+		session, err := currentUserSession(shell)
+		if err == nil {
+			var status int
+			status, err = session.run(commandLine)
+			if err == nil {
+				return status
+			}
+		}
+		fmt.Printf("\033[33mWarning: shell session not available, running the command on its own: %v\033[0m\n", err)
+	}
+
+	run := newShellRun(context.Background(), shell, commandLine, true)
+	utils.RunCommandWithPTY(run.Cmd)
+	run.finish()
+	if run.Cmd.ProcessState == nil {
+		return 127
+	}
+	return run.Cmd.ProcessState.ExitCode()
+}
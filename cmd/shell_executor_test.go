@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsProtectedStateVar(t *testing.T) {
+	for name, want := range map[string]bool{
+		"PATH": true, "LD_PRELOAD": true, "LD_LIBRARY_PATH": true, "DYLD_INSERT_LIBRARIES": true,
+		"SUDO_ASKPASS": true, "SUDO_EDITOR": true, "OPENAI_API_KEY": true, "ANTHROPIC_BASE_URL": true,
+		"AURORA_CONFIG": true, "BASH_ENV": true, "HOME": true,
+		"GOPATH": false, "VIRTUAL_ENV": false, "MY_PATH": false, "EDITOR": false, "NODE_ENV": false,
+	} {
+		if got := isProtectedStateVar(name); got != want {
+			t.Errorf("isProtectedStateVar(%s) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestApplyShellStateFromAgent(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("LD_PRELOAD", "")
+	os.Unsetenv("LD_PRELOAD")
+	t.Setenv("AURORA_TEST_VAR", "old")
+	t.Setenv("SUDO_ASKPASS", "/usr/bin/ssh-askpass")
+	t.Setenv("AURORA_APP_MODE", "")
+	os.Unsetenv("AURORA_APP_MODE")
+	t.Setenv("APP_MODE", "")
+	os.Unsetenv("APP_MODE")
+	useTestAgentVars(t)
+
+	before := shellState{Env: []string{"PATH=/usr/bin:/bin", "AURORA_TEST_VAR=old", "SUDO_ASKPASS=/usr/bin/ssh-askpass"}}
+	after := shellState{Env: []string{"PATH=/tmp/x:/usr/bin:/bin", "LD_PRELOAD=/tmp/x.so", "AURORA_TEST_VAR=old", "APP_MODE=test"}}
+
+	kept := applyShellState(before, after, true)
+	if strings.Join(kept, ",") != "LD_PRELOAD,PATH,SUDO_ASKPASS" {
+		t.Errorf("kept = %v", kept)
+	}
+	if os.Getenv("PATH") != "/usr/bin:/bin" {
+		t.Errorf("PATH = %s", os.Getenv("PATH"))
+	}
+	if _, ok := os.LookupEnv("LD_PRELOAD"); ok {
+		t.Error("LD_PRELOAD was carried over")
+	}
+	if os.Getenv("SUDO_ASKPASS") != "/usr/bin/ssh-askpass" {
+		t.Error("SUDO_ASKPASS was removed")
+	}
+	if _, ok := os.LookupEnv("APP_MODE"); ok {
+		t.Error("APP_MODE was carried over to Aurora")
+	}
+	if env := agentEnviron(os.Environ()); !slices.Contains(env, "APP_MODE=test") || !slices.Contains(env, "AURORA_TEST_VAR=old") {
+		t.Errorf("APP_MODE is not carried over to the AI's commands: %v", env)
+	}
+
+	// The AI unsetting a variable hides it from its later commands only
+	t.Setenv("APP_DEBUG", "1")
+	applyShellState(shellState{Env: []string{"APP_DEBUG=1"}}, shellState{}, true)
+	if env := agentEnviron(os.Environ()); slices.Contains(env, "APP_DEBUG=1") || os.Getenv("APP_DEBUG") != "1" {
+		t.Errorf("APP_DEBUG = %q, AI's environment %v", os.Getenv("APP_DEBUG"), env)
+	}
+
+	// Changes of the user's own commands carry over and replace the ones of the AI
+	after.Env[3] = "APP_MODE=prod"
+	if kept := applyShellState(before, after, false); len(kept) != 0 || os.Getenv("PATH") != "/tmp/x:/usr/bin:/bin" {
+		t.Errorf("user change: kept = %v, PATH = %s", kept, os.Getenv("PATH"))
+	}
+	if env := agentEnviron(os.Environ()); !slices.Contains(env, "APP_MODE=prod") || slices.Contains(env, "APP_MODE=test") {
+		t.Errorf("the user's APP_MODE doesn't reach the AI's commands: %v", env)
+	}
+}
+
+// useTestAgentVars forgets the variables the AI's commands changed, before and after the test
+func useTestAgentVars(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		agentVars.Lock()
+		agentVars.values = map[string]*string{}
+		agentVars.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestSyncScriptSkipsProtectedVars(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("APP_MODE", "")
+	session := &shellSession{auroraEnv: os.Environ()}
+	session.auroraDir, _ = os.Getwd()
+	session.state.Dir = session.auroraDir
+
+	os.Setenv("OPENAI_API_KEY", "sk-test")
+	os.Setenv("APP_MODE", "dev")
+	script := session.syncScript()
+	if strings.Contains(script, "OPENAI_API_KEY") {
+		t.Errorf("the API key is copied into the shell:\n%s", script)
+	}
+	if !strings.Contains(script, "export APP_MODE='dev'") {
+		t.Errorf("APP_MODE is not copied into the shell:\n%s", script)
+	}
+}
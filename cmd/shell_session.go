@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// shellStartTimeout is how long a new shell may take to load its rc files
+const shellStartTimeout = 10 * time.Second
+
+// shellVarName matches the names of variables a shell can export
+var shellVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellSession is a long-lived interactive shell on a PTY that runs the command
+// lines the user types, so exports, aliases, functions, pushd and sourced scripts
+// stay in effect between commands like in a terminal.
+//
+// Each command line is written to a file that the shell sources, followed by a
+// sentinel: the shell saves its state and prints a marker with the exit code,
+// which ends the output of the command.
+type shellSession struct {
+	shell  string
+	cmd    *exec.Cmd
+	ptmx   *os.File
+	dir    string
+	marker []byte
+
+	// statuses receives the exit code of each command, exited is closed when the shell ends
+	statuses chan int
+	exited   chan struct{}
+	running  atomic.Bool
+	// started is set once the output of the rc files is over, only the reader uses it
+	started bool
+
+	// state is the last state of the shell, auroraDir and auroraEnv the state
+	// of Aurora after the last command, to find what the AI changed since
+	state     shellState
+	auroraDir string
+	auroraEnv []string
+
+	// mu lets one command line run at a time
+	mu sync.Mutex
+}
+
+// userSession is the shell that runs the commands of the user
+var (
+	userSession   *shellSession
+	userSessionMu sync.Mutex
+)
+
+// startShellSession starts an interactive shell and waits until it has loaded
+// its rc files
+func startShellSession(shell string) (*shellSession, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "aurora-session-")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shellSession{
+		shell:    shell,
+		dir:      dir,
+		marker:   []byte("__AURORA_" + hex.EncodeToString(token)),
+		statuses: make(chan int, 1),
+		exited:   make(chan struct{}),
+	}
+	s.cmd = exec.Command(shell, "-i")
+	s.ptmx, err = pty.Start(s.cmd)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	pty.InheritSize(os.Stdin, s.ptmx)
+
+	go s.readOutput()
+	go func() {
+		s.cmd.Wait()
+		close(s.exited)
+	}()
+
+	// Turn off echo, line editing and prompts, the sentinel ends the rc file output
+	exeArg := shellQuote(exe) + " " + shellStateArg + " " + shellQuote(filepath.Join(dir, "state"))
+	s.ptmx.Write([]byte(shellSetupScript(shell) + "; __aurora_status=0; " + exeArg + "; " + s.sentinel() + "\n"))
+
+	select {
+	case <-s.statuses:
+	case <-s.exited:
+		s.close()
+		return nil, fmt.Errorf("%s exited while starting", shell)
+	case <-time.After(shellStartTimeout):
+		s.close()
+		return nil, fmt.Errorf("%s did not start within %s", shell, shellStartTimeout)
+	}
+
+	s.state, err = readShellState(filepath.Join(dir, "state"))
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.auroraDir, _ = os.Getwd()
+	s.auroraEnv = os.Environ()
+	return s, nil
+}
+
+// shellSetupScript returns the commands that make a shell quiet for Aurora: no
+// echo, no line editor, no prompts and no history of the sourced command files
+func shellSetupScript(shell string) string {
+	script := "stty -echo 2>/dev/null"
+	switch filepath.Base(shell) {
+	case "bash":
+		script += "; set +o emacs +o vi; PROMPT_COMMAND="
+	case "zsh":
+		script += "; unsetopt zle prompt_sp prompt_cr; precmd_functions=(); preexec_functions=(); RPS1="
+	case "ksh", "mksh", "yash":
+		script += "; set +o emacs +o vi 2>/dev/null"
+	}
+	return script + "; PS1=; PS2=; unset HISTFILE"
+}
+
+// sentinel returns the commands that print the marker with the exit code of the
+// previous command. The marker is split so the shell's echo can't contain it.
+func (s *shellSession) sentinel() string {
+	half := len(s.marker) / 2
+	return fmt.Sprintf(`printf '%%s%%s %%d\n' %s %s "$__aurora_status"`,
+		shellQuote(string(s.marker[:half])), shellQuote(string(s.marker[half:])))
+}
+
+// readOutput copies the output of the shell to the terminal until the shell
+// closes the PTY, and reports the exit codes printed with the marker
+func (s *shellSession) readOutput() {
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := s.ptmx.Read(buf)
+		if n > 0 {
+			pending = s.scanOutput(append(pending, buf[:n]...))
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.writeOutput(pending)
+}
+
+// scanOutput writes the output up to the markers and sends their exit codes. It
+// returns what must wait for more output: an incomplete marker line, or an end
+// that could be the start of a marker.
+func (s *shellSession) scanOutput(data []byte) []byte {
+	for {
+		i := bytes.Index(data, s.marker)
+		if i < 0 {
+			break
+		}
+		end := bytes.IndexByte(data[i:], '\n')
+		if end < 0 {
+			s.writeOutput(data[:i])
+			return data[i:]
+		}
+		s.writeOutput(data[:i])
+		status, _ := strconv.Atoi(strings.TrimSpace(string(data[i+len(s.marker) : i+end])))
+		s.started = true
+		s.statuses <- status
+		data = data[i+end+1:]
+	}
+
+	keep := min(len(data), len(s.marker)-1)
+	for keep > 0 && !bytes.HasPrefix(s.marker, data[len(data)-keep:]) {
+		keep--
+	}
+	s.writeOutput(data[:len(data)-keep])
+	return data[len(data)-keep:]
+}
+
+// writeOutput shows output of the shell on the terminal, the output of the rc
+// files and of the setup commands is dropped
+func (s *shellSession) writeOutput(data []byte) {
+	if s.started && len(data) > 0 {
+		os.Stdout.Write(data)
+	}
+}
+
+// alive reports whether the shell is still running
+func (s *shellSession) alive() bool {
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// run runs a command line in the shell and returns its exit code. When the
+// command line exits the shell, the exit code of the shell is returned.
+func (s *shellSession) run(commandLine string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commandFile := filepath.Join(s.dir, "command")
+	stateFile := filepath.Join(s.dir, "state")
+	if err := os.WriteFile(commandFile, []byte(s.syncScript()+commandLine+"\n"), 0600); err != nil {
+		return 0, err
+	}
+	os.Remove(stateFile)
+
+	// Shells other than bash and zsh abandon the whole line on a syntax error
+	// in a sourced file, which would lose the sentinel
+	source := ". " + shellQuote(commandFile)
+	if base := filepath.Base(s.shell); base != "bash" && base != "zsh" {
+		source = shellQuote(s.shell) + " -n " + shellQuote(commandFile) + " && " + source
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	line := source + "; __aurora_status=$?; " + shellQuote(exe) + " " + shellStateArg + " " + shellQuote(stateFile) +
+		"; " + s.sentinel() + "\n"
+
+	pty.InheritSize(os.Stdin, s.ptmx)
+	s.running.Store(true)
+	defer s.running.Store(false)
+	if _, err := s.ptmx.Write([]byte(line)); err != nil {
+		return 0, err
+	}
+
+	select {
+	case status := <-s.statuses:
+		s.applyState(stateFile)
+		return status, nil
+	case <-s.exited:
+		// exit or exec ended the shell, the next command starts a new one
+		s.close()
+		return s.cmd.ProcessState.ExitCode(), nil
+	}
+}
+
+// syncScript returns the commands that bring the shell up to date with the
+// directory and the variables the AI changed since the last command
+func (s *shellSession) syncScript() string {
+	var script strings.Builder
+	if dir, err := os.Getwd(); err == nil && dir != s.auroraDir && dir != s.state.Dir {
+		fmt.Fprintf(&script, "cd -- %s\n", shellQuote(dir))
+	}
+
+	previous := envMap(s.auroraEnv)
+	next := envMap(os.Environ())
+	var names []string
+	for name := range next {
+		names = append(names, name)
+	}
+	for name := range previous {
+		if _, ok := next[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ignoredStateVars[name] || isProtectedStateVar(name) || !shellVarName.MatchString(name) {
+			continue
+		}
+		value, ok := next[name]
+		if old, had := previous[name]; ok && (!had || old != value) {
+			fmt.Fprintf(&script, "export %s=%s\n", name, shellQuote(value))
+		} else if !ok {
+			fmt.Fprintf(&script, "unset %s\n", name)
+		}
+	}
+	return script.String()
+}
+
+// applyState applies what the command line changed in the shell to Aurora
+func (s *shellSession) applyState(stateFile string) {
+	state, err := readShellState(stateFile)
+	if err == nil {
+		applyShellState(s.state, state, false)
+		s.state = state
+	}
+	s.auroraDir, _ = os.Getwd()
+	s.auroraEnv = os.Environ()
+}
+
+// snapshot has the shell write its aliases, functions and shell variables to a
+// new file in the session's directory and returns the file
+func (s *shellSession) snapshot() (string, error) {
+	file, err := os.CreateTemp(s.dir, "snapshot-")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	if _, err := s.run(shellSnapshotScript(s.shell, file.Name())); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// shellSnapshotScript returns the commands that write the aliases, functions and
+// shell variables of a shell to a file, as commands that define them again. Bash
+// and zsh list the variables that aren't exported, other shells only aliases and
+// functions. Exported variables are in Aurora's environment already and are left
+// out, as are read-only variables and the ones the shell keeps itself.
+func shellSnapshotScript(shell string, file string) string {
+	var script string
+	switch filepath.Base(shell) {
+	case "bash":
+		script = `echo 'shopt -s expand_aliases'; alias -p; declare -f
+for __aurora_v in $(compgen -v); do
+  case $__aurora_v in
+    BASH*|COMP*|HIST*|EPOCH*|FUNCNAME|GROUPS|DIRSTACK|PIPESTATUS|LINENO|RANDOM|SRANDOM|SECONDS|PPID|UID|EUID|SHELLOPTS|PS1|PS2|PS4|PROMPT_COMMAND|COLUMNS|LINES|OPTIND|OPTARG|OPTERR|MAILCHECK|_|__aurora_*) continue ;;
+  esac
+  __aurora_d=$(declare -p "$__aurora_v" 2>/dev/null) || continue
+  __aurora_f=${__aurora_d#declare -}
+  case ${__aurora_f%% *} in *[xr]*) continue ;; esac
+  printf '%s\n' "$__aurora_d"
+done
+unset __aurora_v __aurora_d __aurora_f`
+	case "zsh":
+		script = `alias -L; typeset -f
+for __aurora_v in ${(k)parameters}; do
+  case $__aurora_v in
+    HIST*|EPOCH*|PS1|PS2|PS3|PS4|RPS1|RPS2|PROMPT*|RPROMPT*|SAVEHIST|LINENO|RANDOM|SECONDS|_|__aurora_*) continue ;;
+  esac
+  case ${parameters[$__aurora_v]} in *export*|*readonly*|*special*|*local*|*hide*) continue ;; esac
+  typeset -p -- $__aurora_v
+done
+unset __aurora_v`
+	default:
+		script = `alias | sed '/^alias /!s/^/alias /'; typeset -f 2>/dev/null`
+	}
+	return "{\n" + script + "\n} > " + shellQuote(file) + " 2>/dev/null"
+}
+
+// sessionPrelude returns the commands that load the aliases, functions and shell
+// variables of the user's shell session into a new shell of the same kind, so
+// the AI's commands see the shell the user sees. It is empty when there is no
+// session for the shell.
+func sessionPrelude(shell string) string {
+	if shell != UserShell() || !isPOSIXShell(shell) {
+		return ""
+	}
+	session, err := currentUserSession(shell)
+	if err != nil {
+		return ""
+	}
+	file, err := session.snapshot()
+	if err != nil {
+		return ""
+	}
+	// Aliases only apply to lines read after the one that defines them
+	return ". " + shellQuote(file) + " >/dev/null 2>&1; rm -f -- " + shellQuote(file) + "\n"
+}
+
+// interrupt sends Ctrl+C to the command that is running, like a terminal does
+func (s *shellSession) interrupt() bool {
+	if !s.running.Load() {
+		return false
+	}
+	_, err := s.ptmx.Write([]byte{0x03})
+	return err == nil
+}
+
+// close ends the shell and removes its files
+func (s *shellSession) close() {
+	if s.alive() {
+		s.cmd.Process.Signal(syscall.SIGHUP)
+		select {
+		case <-s.exited:
+		case <-time.After(time.Second):
+			s.cmd.Process.Kill()
+		}
+	}
+	s.ptmx.Close()
+	os.RemoveAll(s.dir)
+}
+
+// currentUserSession returns the session of the user's shell, starting it when
+// there is none yet, it ended, or the configured shell changed
+func currentUserSession(shell string) (*shellSession, error) {
+	userSessionMu.Lock()
+	defer userSessionMu.Unlock()
+
+	if userSession != nil && (!userSession.alive() || userSession.shell != shell) {
+		userSession.close()
+		userSession = nil
+	}
+	if userSession == nil {
+		session, err := startShellSession(shell)
+		if err != nil {
+			return nil, err
+		}
+		userSession = session
+	}
+	return userSession, nil
+}
+
+// InterruptShellCommand sends Ctrl+C to the command of the user that is running
+// in the shell session and reports whether there was one
+func InterruptShellCommand() bool {
+	userSessionMu.Lock()
+	session := userSession
+	userSessionMu.Unlock()
+	return session != nil && session.interrupt()
+}
+
+// CloseShellSession ends the shell of the user, Aurora calls it on exit
+func CloseShellSession() {
+	userSessionMu.Lock()
+	defer userSessionMu.Unlock()
+	if userSession != nil {
+		userSession.close()
+		userSession = nil
+	}
+}
+
+// shellQuote quotes a string as a single word for sh-like shells
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
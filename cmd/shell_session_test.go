@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aurora-agent/config"
+)
+
+// TestMain lets the test binary stand in for Aurora when a shell asks it for its state
+func TestMain(m *testing.M) {
+	if HandleShellStateDump() {
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// useTestSession makes a new session of shell the user's shell session
+func useTestSession(t *testing.T, shell string) *shellSession {
+	t.Helper()
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		t.Skipf("%s is not installed", shell)
+	}
+	useTestHome(t)
+	config.CurrentConfig.General.DefaultShell = path
+
+	session, err := startShellSession(path)
+	if err != nil {
+		t.Skipf("can't start a shell session: %v", err)
+	}
+	userSessionMu.Lock()
+	userSession = session
+	userSessionMu.Unlock()
+	t.Cleanup(CloseShellSession)
+	return session
+}
+
+// runAgentCommand runs a command line like the AI's commands and returns its output
+func runAgentCommand(t *testing.T, commandLine string) string {
+	t.Helper()
+	run := newAgentShellRun(context.Background(), commandLine)
+	output, err := run.Cmd.CombinedOutput()
+	run.finish()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", commandLine, err, output)
+	}
+	return string(output)
+}
+
+func TestAgentCommandsSeeSessionState(t *testing.T) {
+	session := useTestSession(t, "bash")
+	if _, err := session.run(`alias ll='echo listed'; greet() { echo "hello $1"; }; plain='two
+lines'; readonly fixed=1`); err != nil {
+		t.Fatal(err)
+	}
+
+	output := runAgentCommand(t, `ll; greet you; echo "$plain"; echo "fixed=$fixed"`)
+	want := "listed\nhello you\ntwo\nlines\nfixed=\n"
+	if output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	entries, _ := os.ReadDir(session.dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "snapshot-") {
+			t.Errorf("snapshot %s was not removed", entry.Name())
+		}
+	}
+}
+
+func TestAgentCommandsSeeSessionAliases(t *testing.T) {
+	session := useTestSession(t, "dash")
+	if _, err := session.run(`alias ll='echo listed'`); err != nil {
+		t.Fatal(err)
+	}
+	if output := runAgentCommand(t, "ll"); output != "listed\n" {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestAgentExportsStayOutOfSession(t *testing.T) {
+	session := useTestSession(t, "bash")
+	useTestAgentVars(t)
+	t.Setenv("EDITOR", "vi")
+
+	runAgentCommand(t, "export EDITOR='touch /tmp/pwned'")
+	if output := runAgentCommand(t, `echo "$EDITOR"`); output != "touch /tmp/pwned\n" {
+		t.Errorf("the AI's next command sees EDITOR=%q", output)
+	}
+	if os.Getenv("EDITOR") != "vi" {
+		t.Errorf("EDITOR of Aurora = %q", os.Getenv("EDITOR"))
+	}
+
+	file := filepath.Join(t.TempDir(), "editor")
+	if _, err := session.run(`echo "$EDITOR" > ` + shellQuote(file)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "vi\n" {
+		t.Errorf("EDITOR in the user's shell = %q", data)
+	}
+}
@@ -1,18 +1,342 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"aurora-agent/config"
+	"aurora-agent/utils"
 )
 
-// CheckSudoPassword verifies if the provided sudo password is correct
-func CheckSudoPassword(password string) bool {
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("echo %s | sudo -S -v", password))
-	err := cmd.Run()
-	return err == nil
+// sudoAskpassArg is the hidden argument with which sudo runs Aurora, through the
+// askpass script, to get the password
+const sudoAskpassArg = "__aurora-sudo-askpass"
+
+// sudoDirs are the directories sudo is looked for in. PATH is not used, a
+// command could have put a program named sudo first in it to get the password.
+var sudoDirs = []string{"/usr/bin", "/bin", "/run/wrappers/bin", "/usr/local/bin", "/usr/sbin", "/sbin"}
+
+// sudoBinary returns the absolute path of sudo
+var sudoBinary = sync.OnceValue(func() string {
+	for _, dir := range sudoDirs {
+		path := filepath.Join(dir, "sudo")
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return "/usr/bin/sudo"
+})
+
+// sudoCredential is the sudo password of the user. It is only kept in memory and
+// wiped when it hasn't been used for the configured idle timeout.
+type sudoCredential struct {
+	mu       sync.Mutex
+	password []byte
+	timer    *time.Timer
+}
+
+// sudoCred is the sudo password of this Aurora process
+var sudoCred = &sudoCredential{}
+
+// set stores a password and starts the idle timeout
+func (c *sudoCredential) set(password []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wipe()
+	c.password = append([]byte{}, password...)
+	c.touch()
+}
+
+// get returns a copy of the password and restarts the idle timeout, ok is false
+// when there is no password
+func (c *sudoCredential) get() (password []byte, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.password == nil {
+		return nil, false
+	}
+	c.touch()
+	return append([]byte{}, c.password...), true
+}
+
+// forget wipes the password
+func (c *sudoCredential) forget() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wipe()
+}
+
+// enabled reports whether a password is stored
+func (c *sudoCredential) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.password != nil
+}
+
+// touch restarts the idle timeout, c.mu must be held
+func (c *sudoCredential) touch() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if minutes := config.CurrentConfig.Sudo.IdleTimeout; minutes > 0 {
+		c.timer = time.AfterFunc(time.Duration(minutes)*time.Minute, c.forget)
+	}
+}
+
+// wipe overwrites and drops the password, c.mu must be held
+func (c *sudoCredential) wipe() {
+	for i := range c.password {
+		c.password[i] = 0
+	}
+	c.password = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// SudoEnabled reports whether Aurora holds a sudo password
+func SudoEnabled() bool {
+	return sudoCred.enabled()
+}
+
+// EnableSudo checks a sudo password and keeps it for later sudo commands
+func EnableSudo(password []byte) error {
+	if !CheckSudoPassword(password) {
+		return fmt.Errorf("incorrect sudo password")
+	}
+	sudoCred.set(password)
+	return nil
+}
+
+// ForgetSudo wipes the sudo password and the credentials sudo cached for the
+// terminal of Aurora and the one of the shell session
+func ForgetSudo() {
+	sudoCred.forget()
+	exec.Command(sudoBinary(), "-K").Run()
+
+	userSessionMu.Lock()
+	session := userSession
+	userSessionMu.Unlock()
+	if session != nil && session.alive() {
+		session.run(shellQuote(sudoBinary()) + " -K 2>/dev/null")
+	}
+}
+
+// UsesSudo reports whether a command line runs sudo anywhere in it
+func UsesSudo(commandLine string) bool {
+	segments, err := parseCommandSegments(commandLine)
+	if err != nil {
+		return strings.HasPrefix(strings.TrimSpace(commandLine), "sudo ")
+	}
+	for _, segment := range segments {
+		if len(segment.Argv) > 0 && segment.Argv[0] == "sudo" {
+			return true
+		}
+	}
+	return false
+}
+
+// ElevatingCommand returns the first command in a command line that runs others
+// with elevated privileges: sudo, doas, pkexec or su, also when they are run
+// through env, command, exec and other wrappers or in nested scripts like bash -c.
+// A command line that can't be parsed is checked word by word.
+func ElevatingCommand(commandLine string) (string, bool) {
+	if _, err := parseCommandSegments(commandLine); err != nil {
+		for _, word := range strings.Fields(commandLine) {
+			switch name := filepath.Base(strings.Trim(word, `"'();&|`)); name {
+			case "sudo", "doas", "pkexec", "su":
+				return name, true
+			}
+		}
+		return "", false
+	}
+	if elevators := classifyCommand(commandLine).Elevators; len(elevators) > 0 {
+		return elevators[0], true
+	}
+	return "", false
 }
 
-// CreateSudoCommand creates a command with sudo privileges
-func CreateSudoCommand(shell string, password string, args []string) *exec.Cmd {
-	return exec.Command(shell, "-i", "-c", fmt.Sprintf("echo %s | sudo -S -p '' %s", password, args))
+// promptSudoPassword asks the user for the sudo password, checks and keeps it
+func promptSudoPassword() bool {
+	password, err := readPassword("[sudo] Enter password: ")
+	if err != nil {
+		fmt.Println("\033[31mError: Could not read password.\033[0m")
+		return false
+	}
+	err = EnableSudo(password)
+	for i := range password {
+		password[i] = 0
+	}
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return false
+	}
+	return true
+}
+
+// CheckSudoPassword verifies a sudo password. It is written to the standard input
+// of sudo -S, so it never appears in a command line.
+func CheckSudoPassword(password []byte) bool {
+	// -k makes sudo check the password even if it has cached credentials
+	cmd := exec.Command(sudoBinary(), "-k", "-S", "-p", "", "-v")
+	cmd.Stdin = bytes.NewReader(append(append([]byte{}, password...), '\n'))
+	return cmd.Run() == nil
+}
+
+// HandleSudoAskpass prints the sudo password it gets from the Aurora process
+// listening on the socket given after sudoAskpassArg and reports whether Aurora
+// was started to do so. main calls it before anything else.
+func HandleSudoAskpass() bool {
+	if len(os.Args) != 3 || os.Args[1] != sudoAskpassArg {
+		return false
+	}
+
+	conn, err := net.Dial("unix", os.Args[2])
+	if err != nil {
+		os.Exit(1)
+	}
+	defer conn.Close()
+	if _, err := io.Copy(os.Stdout, conn); err != nil {
+		os.Exit(1)
+	}
+	return true
+}
+
+// sudoAskpass lets sudo -A get the password from Aurora while a command runs.
+// sudo runs a script in a private directory, which runs Aurora with
+// sudoAskpassArg, which reads the password from a socket next to the script.
+type sudoAskpass struct {
+	dir      string
+	listener net.Listener
+	done     chan struct{}
+}
+
+// startSudoAskpass starts serving the sudo password. Callers must close it.
+func startSudoAskpass() (*sudoAskpass, error) {
+	if !sudoCred.enabled() {
+		return nil, fmt.Errorf("sudo mode not enabled")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "aurora-sudo-")
+	if err != nil {
+		return nil, err
+	}
+
+	socket := filepath.Join(dir, "socket")
+	script := "#!/bin/sh\nexec " + shellQuote(exe) + " " + sudoAskpassArg + " " + shellQuote(socket) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "askpass"), []byte(script), 0700); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	a := &sudoAskpass{dir: dir, listener: listener, done: make(chan struct{})}
+	go a.serve()
+	return a, nil
+}
+
+// serve answers every connection with the password until the askpass is closed
+func (a *sudoAskpass) serve() {
+	defer close(a.done)
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+		if password, ok := sudoCred.get(); ok {
+			conn.Write(append(password, '\n'))
+			for i := range password {
+				password[i] = 0
+			}
+		}
+		conn.Close()
+	}
+}
+
+// script returns the path of the program to set as SUDO_ASKPASS
+func (a *sudoAskpass) script() string {
+	return filepath.Join(a.dir, "askpass")
+}
+
+// wrap returns a command line in which sudo asks Aurora for the password. It
+// only works in shells that speak sh syntax.
+func (a *sudoAskpass) wrap(commandLine string) string {
+	return "sudo() { SUDO_ASKPASS=" + shellQuote(a.script()) + " " + shellQuote(sudoBinary()) + ` -A "$@"; }` + "\n" +
+		commandLine + "\n" +
+		"__aurora_status=$?; unset -f sudo; (exit $__aurora_status)"
+}
+
+// command returns a command that runs a command line in a shell as root. sudo
+// ignores its cached credentials and asks Aurora for the password, so every
+// elevation goes through the stored password.
+func (a *sudoAskpass) command(ctx context.Context, shell string, commandLine string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, sudoBinary(), "-A", "-k", "--", shell, "-c", commandLine)
+	cmd.Env = append(os.Environ(), "SUDO_ASKPASS="+a.script())
+	return cmd
+}
+
+// close stops serving the password and removes the script and the socket
+func (a *sudoAskpass) close() {
+	a.listener.Close()
+	<-a.done
+	os.RemoveAll(a.dir)
+}
+
+// RunSudoCommand runs a command line of the user that uses sudo with the stored
+// password and returns its exit code. Without a stored password it asks for one.
+// The command line is recorded in the audit log as elevated.
+func RunSudoCommand(commandLine string) int {
+	record := newAuditRecord(auditOriginUser, commandLine)
+	record.Elevated = true
+	started := time.Now()
+	status := runSudoCommand(commandLine)
+	record.finish(&status, time.Since(started))
+	recordAudit(record)
+	lastExitStatus = status
+	return status
+}
+
+// runSudoCommand runs a command line that uses sudo in the user's shell
+func runSudoCommand(commandLine string) int {
+	if !SudoEnabled() && !promptSudoPassword() {
+		return 1
+	}
+
+	askpass, err := startSudoAskpass()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer askpass.close()
+
+	script := askpass.wrap(commandLine)
+	if isPOSIXShell(UserShell()) {
+		return runInUserShell(script)
+	}
+	// The sudo function needs sh syntax, shells like fish can't run it
+	run := newShellRun(context.Background(), agentShell(), script, false)
+	utils.RunCommandWithPTY(run.Cmd)
+	run.finish()
+	if run.Cmd.ProcessState == nil {
+		return 127
+	}
+	return run.Cmd.ProcessState.ExitCode()
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// processSudoCommand handles the sudo on and sudo forget commands, other sudo
+// command lines run in the shell
+func processSudoCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) != 2 || words[0] != "sudo" {
+		return false
+	}
+
+	switch words[1] {
+	case "on":
+		if promptSudoPassword() {
+			fmt.Println("\033[32mSudo mode activated!\033[0m")
+		}
+
+	case "forget":
+		ForgetSudo()
+		fmt.Println("\033[32mSudo password forgotten\033[0m")
+
+	default:
+		return false
+	}
+	return true
+}
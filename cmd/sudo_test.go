@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSudoIsCalledByPath(t *testing.T) {
+	if !strings.HasPrefix(sudoBinary(), "/") {
+		t.Fatalf("sudoBinary() = %s", sudoBinary())
+	}
+	askpass := &sudoAskpass{dir: "/tmp/aurora-sudo-test"}
+	script := askpass.wrap("sudo true")
+	if strings.Contains(script, "command sudo") || !strings.Contains(script, shellQuote(sudoBinary())+" -A") {
+		t.Errorf("wrap doesn't call sudo by its path:\n%s", script)
+	}
+	if cmd := askpass.command(t.Context(), "sh", "true"); cmd.Path != sudoBinary() {
+		t.Errorf("command runs %s", cmd.Path)
+	}
+}
+
+func TestElevatingCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"sudo ls", "sudo"},
+		{"/usr/bin/sudo -u root ls", "sudo"},
+		{"env sudo ls", "sudo"},
+		{"env FOO=1 doas ls", "doas"},
+		{"command sudo ls", "sudo"},
+		{"exec pkexec ls", "pkexec"},
+		{"nice -n 5 sudo make install", "sudo"},
+		{"bash -c 'sudo ls'", "sudo"},
+		{"sh -c \"bash -c 'su -c id'\"", "su"},
+		{"eval sudo ls", "sudo"},
+		{"echo $(sudo cat /etc/shadow)", "sudo"},
+		{"ls && timeout 5 su root", "su"},
+		{"find . -exec sudo rm {} ;", "sudo"},
+		{"sudo ls 'unclosed", "sudo"},
+		{"ls -la", ""},
+		{"echo sudo", ""},
+		{"grep -r sudo /etc/sudoers.d", ""},
+		{"command -v sudo", ""},
+		{"man su", ""},
+	}
+	for _, test := range tests {
+		got, found := ElevatingCommand(test.command)
+		if got != test.want || found != (test.want != "") {
+			t.Errorf("ElevatingCommand(%q) = %q, %t, want %q", test.command, got, found, test.want)
+		}
+	}
+}
+
+func TestExecuteCommandRefusesEditedElevation(t *testing.T) {
+	useTestHome(t)
+	saved := lineReader
+	t.Cleanup(func() { lineReader = saved })
+	answers := []string{"e", "sudo touch /etc/aurora-test"}
+	lineReader = func(prompt string, defaultValue string) (string, error) {
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+
+	result, err := runExecuteCommand(t.Context(), "execute_command", `{"command": "touch aurora-test"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || !strings.Contains(result.Output, "execute_privileged") {
+		t.Errorf("the edited command was not refused: %+v", result)
+	}
+
+	records, err := readAuditRecords(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Command != "sudo touch /etc/aurora-test" || records[0].Approval != auditDenied || records[0].Origin != auditOriginPolicy {
+		t.Errorf("audit records = %+v", records)
+	}
+}
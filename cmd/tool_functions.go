@@ -0,0 +1,927 @@
+package cmd
+
+import (
+	"aurora-agent/config"
+	"aurora-agent/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ToolDefinition describes a function the AI is allowed to call
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	// ReadOnly tools don't change any state and may run concurrently
+	ReadOnly bool
+}
+
+// getToolDefinitions returns the provider independent list of available functions
+func getToolDefinitions() []ToolDefinition {
+	return []ToolDefinition{
+		{
+			Name:        "execute_command",
+			Description: "Execute a shell command and return the output",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"command": map[string]interface{}{
+						"type":        "string",
+						"description": "The shell command to execute",
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seconds after which the command is killed (optional, defaults to the configured timeout, usually 120)",
+					},
+					"background": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the command in the background and return at once with a job ID, for servers, watchers and other commands that don't end by themselves (optional, defaults to false)",
+					},
+				},
+				"required": []string{"command"},
+			},
+		},
+		{
+			Name: "execute_privileged",
+			Description: "Execute a shell command as root through sudo and return the output. The user sees the exact command and must confirm every call. " +
+				"Use it only for commands that need root, execute_command can't use sudo",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"command": map[string]interface{}{
+						"type":        "string",
+						"description": "The shell command to execute as root, without sudo",
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seconds after which the command is killed (optional, defaults to the configured timeout, usually 120)",
+					},
+				},
+				"required": []string{"command"},
+			},
+		},
+		{
+			Name:        "read_job_output",
+			Description: "Read the output a background job wrote since the last read, together with whether it is still running and its exit code",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"job_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID returned when the job was started",
+					},
+					"wait_seconds": map[string]interface{}{
+						"type":        "integer",
+						"description": "Wait up to this many seconds for new output or the end of the job (optional, defaults to 0, at most 60)",
+					},
+				},
+				"required": []string{"job_id"},
+			},
+		},
+		{
+			Name:        "list_jobs",
+			Description: "List the background jobs with their status",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+				"required":   []string{},
+			},
+		},
+		{
+			Name:        "kill_job",
+			Description: "Stop a background job and the processes it started",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"job_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the job to stop",
+					},
+				},
+				"required": []string{"job_id"},
+			},
+		},
+		{
+			Name:        "pwd",
+			Description: "Print the current working directory, which the user and every command share",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+				"required":   []string{},
+			},
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a text file with line numbers, either the entire file or a specific range of lines. Large files are returned in pages, pass the continuation token of a page to read the next one",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path to the file to read",
+					},
+					"start_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "The starting line number to read (optional, 1-based indexing)",
+					},
+					"end_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ending line number to read (optional, 1-based indexing)",
+					},
+					"read_entire": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to read the entire file instead of a page, up to 1 MB (optional, defaults to false)",
+					},
+					"continuation_token": map[string]interface{}{
+						"type":        "string",
+						"description": "The token returned with the previous page, continues reading where it stopped (optional)",
+					},
+				},
+				"required": []string{"file_path"},
+			},
+		},
+		{
+			Name:        "list_dir",
+			Description: "List the files and directories of a directory as a tree, down to the given depth. Files ignored by .gitignore are left out unless include_ignored is set",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The directory to list (optional, defaults to the current directory)",
+					},
+					"depth": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many levels to descend, 1 lists only the directory itself (optional, defaults to 1, at most 10)",
+					},
+					"include_ignored": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also list files ignored by .gitignore (optional, defaults to false)",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "The number of entries to skip, returned with a partial result to get the next page (optional)",
+					},
+				},
+				"required": []string{},
+			},
+		},
+		{
+			Name:        "glob",
+			Description: "Find files whose path matches a glob pattern such as **/*.go or src/*_test.py. * matches within a directory, ** matches any number of directories",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "The glob pattern, matched against paths relative to path",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The directory to search in (optional, defaults to the current directory)",
+					},
+					"include_ignored": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also match files ignored by .gitignore (optional, defaults to false)",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "The number of files to skip, returned with a partial result to get the next page (optional)",
+					},
+				},
+				"required": []string{"pattern"},
+			},
+		},
+		{
+			Name:        "search",
+			Description: "Search the contents of text files for a regular expression (RE2 syntax) and return the matching lines as path:line:text, optionally with context lines",
+			ReadOnly:    true,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "The regular expression to search for",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The directory or file to search (optional, defaults to the current directory)",
+					},
+					"include": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search files matching this glob, e.g. *.go or src/**/*.ts (optional)",
+					},
+					"context_lines": map[string]interface{}{
+						"type":        "integer",
+						"description": "The number of lines to show before and after each match (optional, defaults to 0, at most 10)",
+					},
+					"case_insensitive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Ignore case when matching (optional, defaults to false)",
+					},
+					"include_ignored": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also search files ignored by .gitignore (optional, defaults to false)",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "The number of matches to skip, returned with a partial result to get the next page (optional)",
+					},
+				},
+				"required": []string{"pattern"},
+			},
+		},
+		{
+			Name:        "write_file",
+			Description: "Create a file or replace its entire content. Missing parent directories are created. The user sees a diff and may have to approve it",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path to the file to write",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "The complete new content of the file",
+					},
+				},
+				"required": []string{"file_path", "content"},
+			},
+		},
+		{
+			Name: "edit_file",
+			Description: "Change part of an existing file, either by replacing an exact string or by replacing a range of lines. " +
+				"The user sees a diff and may have to approve it. Read the file first, old_string must match it exactly, including indentation",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file_path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path to the file to edit",
+					},
+					"old_string": map[string]interface{}{
+						"type":        "string",
+						"description": "The exact text to replace, it must occur once in the file unless replace_all is set (optional when start_line is given)",
+					},
+					"new_string": map[string]interface{}{
+						"type":        "string",
+						"description": "The text that replaces old_string or the line range",
+					},
+					"replace_all": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace every occurrence of old_string (optional, defaults to false)",
+					},
+					"start_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "The first line to replace, used when old_string is empty (optional, 1-based indexing)",
+					},
+					"end_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "The last line to replace, set it to start_line - 1 to insert new_string before start_line without replacing lines (optional, 1-based indexing)",
+					},
+				},
+				"required": []string{"file_path", "new_string"},
+			},
+		},
+	}
+}
+
+// isReadOnlyTool reports whether the named tool is read-only
+func isReadOnlyTool(functionName string) bool {
+	for _, definition := range getToolDefinitions() {
+		if definition.Name == functionName {
+			return definition.ReadOnly
+		}
+	}
+	return false
+}
+
+// runToolFunction executes the named function and returns its result
+func runToolFunction(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	switch functionName {
+	case "execute_command":
+		return runExecuteCommand(ctx, functionName, arguments)
+	case "execute_privileged":
+		return runExecutePrivileged(ctx, functionName, arguments)
+	case "read_job_output":
+		return runReadJobOutput(ctx, functionName, arguments)
+	case "list_jobs":
+		return runListJobs(functionName, arguments)
+	case "kill_job":
+		return runKillJob(functionName, arguments)
+	case "pwd":
+		return runPwd(functionName, arguments)
+	case "read_file":
+		return runReadFile(functionName, arguments)
+	case "list_dir":
+		return runListDir(ctx, functionName, arguments)
+	case "glob":
+		return runGlob(ctx, functionName, arguments)
+	case "search":
+		return runSearch(ctx, functionName, arguments)
+	case "write_file":
+		return runWriteFile(functionName, arguments)
+	case "edit_file":
+		return runEditFile(functionName, arguments)
+	default:
+		return FunctionCallResult{
+			Name:    functionName,
+			Output:  fmt.Sprintf("Error: unknown function %s", functionName),
+			Success: false,
+		}, nil
+	}
+}
+
+// runExecuteCommand executes a shell command and prints its output
+func runExecuteCommand(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		Command    string `json:"command"`
+		Timeout    int    `json:"timeout"`
+		Background bool   `json:"background"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// sudo would ask for a password nobody can enter, elevation has a tool of its own
+	if result, refused := refuseElevation(functionName, args.Command); refused {
+		return result, nil
+	}
+
+	// Check the command against the approval policy, the user may edit it
+	approval := approveCommand(args.Command)
+	record := newAgentAuditRecord(approval, false)
+	if !approval.Approved {
+		recordAudit(record)
+		return FunctionCallResult{
+			Name:       functionName,
+			Success:    false,
+			Denied:     true,
+			DenyReason: approval.Reason,
+		}, nil
+	}
+	// The edited command may elevate where the AI's didn't
+	if result, refused := refuseElevation(functionName, approval.Command); refused {
+		return result, nil
+	}
+	args.Command = approval.Command
+
+	if args.Background {
+		// The job outlives the call, only its start is recorded
+		record.Background = true
+		recordAudit(record)
+		return startBackgroundJob(ctx, functionName, args.Command), nil
+	}
+
+	result := runCommand(ctx, functionName, args.Command, args.Timeout, false)
+	record.finish(result.ExitCode, time.Duration(result.DurationMs)*time.Millisecond)
+	recordAudit(record)
+	return result, nil
+}
+
+// refuseElevation refuses a command of execute_command that runs sudo, doas,
+// pkexec or su and records the refusal in the audit log
+func refuseElevation(functionName string, command string) (FunctionCallResult, bool) {
+	elevator, found := ElevatingCommand(command)
+	if !found {
+		return FunctionCallResult{}, false
+	}
+	err := fmt.Errorf("execute_command can't run %s, use execute_privileged with the command without %s", elevator, elevator)
+	record := newAuditRecord(auditOriginPolicy, command)
+	record.Approval = auditDenied
+	record.Reason = err.Error()
+	recordAudit(record)
+	return toolErrorResult(functionName, err), true
+}
+
+// runExecutePrivileged executes a shell command as root after the user confirmed it
+func runExecutePrivileged(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		Command string `json:"command"`
+		Timeout int    `json:"timeout"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Every elevation is confirmed by the user and recorded
+	approval := approveElevation(args.Command)
+	record := newAgentAuditRecord(approval, true)
+	if !approval.Approved {
+		recordAudit(record)
+		return FunctionCallResult{
+			Name:       functionName,
+			Success:    false,
+			Denied:     true,
+			DenyReason: approval.Reason,
+		}, nil
+	}
+
+	result := runCommand(ctx, functionName, approval.Command, args.Timeout, true)
+	record.finish(result.ExitCode, time.Duration(result.DurationMs)*time.Millisecond)
+	recordAudit(record)
+	return result, nil
+}
+
+// runCommand runs an approved command, shows its output live and returns it as a
+// structured result. Elevated commands run as root through sudo, their cd and
+// export don't carry over.
+func runCommand(ctx context.Context, functionName string, command string, timeoutSeconds int, elevated bool) FunctionCallResult {
+	// Print the command being executed
+	if elevated {
+		fmt.Printf("\n\033[31mRunning as root: %s\033[0m\n", command)
+	} else {
+		fmt.Printf("\n\033[33mRunning command: %s\033[0m\n", command)
+	}
+
+	// Save the working directory so changes made by the command can be undone
+	var scan *dirScan
+	if classifyCommand(command).Level != config.RiskReadOnly {
+		scan = checkpoints.scanBefore()
+	}
+
+	// Execute the command, Ctrl+C and the timeout kill it and its children through the context
+	timeout := commandTimeout(timeoutSeconds)
+	cmdCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var run *shellRun
+	var cmd *exec.Cmd
+	if elevated {
+		askpass, err := startSudoAskpass()
+		if err != nil {
+			return toolErrorResult(functionName, err)
+		}
+		defer askpass.close()
+		cmd = askpass.command(cmdCtx, agentShell(), command)
+	} else {
+		run = newAgentShellRun(cmdCtx, command)
+		cmd = run.Cmd
+	}
+	utils.SetProcessGroup(cmd)
+	// Don't wait for background children that keep the output open after a kill
+	cmd.WaitDelay = time.Second
+	limit := toolOutputLimit(functionName)
+	stdout := newHeadTailBuffer(limit)
+	stderr := newHeadTailBuffer(limit)
+
+	// The output is shown live with its colors, the AI gets it as plain text
+	started := time.Now()
+	err := utils.RunCommandStreaming(cmd, stdout, stderr)
+	result := FunctionCallResult{
+		Name:            functionName,
+		Success:         err == nil,
+		Stdout:          utils.StripTerminalCodes(stdout.String()),
+		Stderr:          utils.StripTerminalCodes(stderr.String()),
+		StdoutTruncated: stdout.Truncated(),
+		StderrTruncated: stderr.Truncated(),
+		DurationMs:      time.Since(started).Milliseconds(),
+		TimedOut:        ctx.Err() == nil && cmdCtx.Err() == context.DeadlineExceeded,
+		Interrupted:     ctx.Err() != nil,
+	}
+	// cd and export of the command carry over to the next one
+	if run != nil {
+		result.KeptVariables = run.finish()
+		if len(result.KeptVariables) > 0 {
+			fmt.Printf("\033[90m(Not carried over to later commands: %s)\033[0m\n", strings.Join(result.KeptVariables, ", "))
+		}
+	}
+	if workDir, err := os.Getwd(); err == nil {
+		result.WorkDir = workDir
+	}
+	if cmd.ProcessState != nil {
+		exitCode := cmd.ProcessState.ExitCode()
+		result.ExitCode = &exitCode
+	} else if err != nil {
+		// The command couldn't be started
+		result.Stderr += err.Error()
+		fmt.Print(err)
+	}
+
+	if result.Interrupted {
+		fmt.Print("\n" + interruptedNote)
+	} else if result.TimedOut {
+		fmt.Printf("\n\033[31mTimed out after %s\033[0m", timeout)
+	} else if result.ExitCode != nil && *result.ExitCode != 0 {
+		fmt.Printf("\n\033[31mExit code %d\033[0m", *result.ExitCode)
+	}
+
+	checkpoints.recordScanChanges(scan, functionName+": "+command)
+
+	// Add a newline after command output for better readability
+	fmt.Print("\n")
+
+	return result
+}
+
+// commandTimeout returns the timeout of a command, requested is the timeout in
+// seconds the AI asked for. Zero means the command has no timeout.
+func commandTimeout(requested int) time.Duration {
+	seconds := config.CurrentConfig.Tools.CommandTimeout
+	if requested > 0 {
+		seconds = requested
+	}
+	if maxSeconds := config.CurrentConfig.Tools.MaxCommandTimeout; maxSeconds > 0 && (seconds <= 0 || seconds > maxSeconds) {
+		seconds = maxSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// startBackgroundJob starts an approved command in the background and returns
+// its job ID with what it printed during the first second
+func startBackgroundJob(ctx context.Context, functionName string, command string) FunctionCallResult {
+	// Print the command being started
+	fmt.Printf("\n\033[33mStarting background job: %s\033[0m\n", command)
+
+	job, err := jobs.start(command)
+	if err != nil {
+		return toolErrorResult(functionName, err)
+	}
+	waitForOutput(ctx, job, jobStartWait)
+
+	result := job.result(functionName)
+	result.Output = formatJobOutput(job)
+	fmt.Printf("Job %d (%s)\n\n", job.ID, job.status())
+	return result
+}
+
+// runReadJobOutput returns the new output of a background job
+func runReadJobOutput(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		JobID       int `json:"job_id"`
+		WaitSeconds int `json:"wait_seconds"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Print which job is read
+	fmt.Printf("\n\033[33mReading output of background job %d\033[0m\n", args.JobID)
+
+	job, err := jobs.get(args.JobID)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	if args.WaitSeconds > 0 {
+		waitForOutput(ctx, job, min(time.Duration(args.WaitSeconds)*time.Second, maxJobWait))
+	}
+
+	result := job.result(functionName)
+	result.Output = formatJobOutput(job)
+	fmt.Printf("Job %d (%s)\n\n", job.ID, job.status())
+	return result, nil
+}
+
+// runListJobs lists the background jobs
+func runListJobs(functionName string, arguments string) (FunctionCallResult, error) {
+	// Print what is being listed
+	fmt.Printf("\n\033[33mListing background jobs\033[0m\n\n")
+
+	var out strings.Builder
+	list := jobs.list()
+	if len(list) == 0 {
+		out.WriteString("No background jobs\n")
+	}
+	for _, job := range list {
+		fmt.Fprintf(&out, "%d\t%s\t%s\n", job.ID, job.status(), job.Command)
+	}
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  out.String(),
+		Success: true,
+	}, nil
+}
+
+// runKillJob stops a background job
+func runKillJob(functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		JobID int `json:"job_id"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Print which job is stopped
+	fmt.Printf("\n\033[33mStopping background job %d\033[0m\n", args.JobID)
+
+	job, err := jobs.get(args.JobID)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	if err := job.kill(); err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	// The job ends once its processes are gone
+	select {
+	case <-job.done:
+	case <-time.After(time.Second):
+	}
+
+	result := job.result(functionName)
+	result.Output = formatJobOutput(job)
+	fmt.Printf("Job %d (%s)\n\n", job.ID, job.status())
+	return result, nil
+}
+
+// runPwd returns the working directory shared by the user and the AI
+func runPwd(functionName string, arguments string) (FunctionCallResult, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+
+	// Add a newline after command output for better readability
+	fmt.Print("\n")
+
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  workDir + "\n",
+		Success: true,
+	}, nil
+}
+
+// runReadFile reads a page of a text file with line numbers
+func runReadFile(functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		FilePath          string `json:"file_path"`
+		StartLine         int    `json:"start_line"`
+		EndLine           int    `json:"end_line"`
+		ReadEntire        bool   `json:"read_entire"`
+		ContinuationToken string `json:"continuation_token"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Print what file is being read
+	fmt.Printf("\n\033[33mReading file: %s\033[0m\n", args.FilePath)
+
+	// A continuation token carries the path when the model leaves it out
+	target := args.FilePath
+	if target == "" && args.ContinuationToken != "" {
+		if decoded, err := decodeReadToken(args.ContinuationToken); err == nil {
+			target = decoded.Path
+		}
+	}
+	if target != "" {
+		resolved, denied, ok := approvedPath(functionName, target, "read")
+		if !ok {
+			return denied, nil
+		}
+		target = resolved
+	}
+
+	result, err := readFilePage(fileReadRequest{
+		Path:      target,
+		StartLine: args.StartLine,
+		EndLine:   args.EndLine,
+		Entire:    args.ReadEntire,
+		Token:     args.ContinuationToken,
+	})
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+
+	// Print only the file info, not the content
+	if result.FirstLine == 0 {
+		fmt.Printf("File: %s (empty)\n", result.Path)
+	} else {
+		total := "?"
+		if result.TotalLines > 0 {
+			total = fmt.Sprintf("%d", result.TotalLines)
+		}
+		fmt.Printf("File: %s (lines %d-%d of %s)\n", result.Path, result.FirstLine, result.LastLine, total)
+	}
+
+	// Add a newline after output for better readability
+	fmt.Print("\n")
+
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  formatFileRead(result),
+		Success: true,
+	}, nil
+}
+
+// runListDir lists a directory as a tree
+func runListDir(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		Path           string `json:"path"`
+		Depth          int    `json:"depth"`
+		IncludeIgnored bool   `json:"include_ignored"`
+		Offset         int    `json:"offset"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+
+	// Print what directory is being listed
+	fmt.Printf("\n\033[33mListing directory: %s\033[0m\n\n", args.Path)
+
+	dir, denied, ok := approvedPath(functionName, args.Path, "list")
+	if !ok {
+		return denied, nil
+	}
+
+	output, err := listDirectory(ctx, dir, args.Depth, args.IncludeIgnored, args.Offset)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  output,
+		Success: true,
+	}, nil
+}
+
+// runGlob finds files matching a glob pattern
+func runGlob(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		Pattern        string `json:"pattern"`
+		Path           string `json:"path"`
+		IncludeIgnored bool   `json:"include_ignored"`
+		Offset         int    `json:"offset"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+
+	// Print what is being searched for
+	fmt.Printf("\n\033[33mFinding files: %s in %s\033[0m\n\n", args.Pattern, args.Path)
+
+	dir, denied, ok := approvedPath(functionName, args.Path, "list")
+	if !ok {
+		return denied, nil
+	}
+
+	output, err := globFiles(ctx, dir, args.Pattern, args.IncludeIgnored, args.Offset)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  output,
+		Success: true,
+	}, nil
+}
+
+// runSearch searches file contents for a regular expression
+func runSearch(ctx context.Context, functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		Pattern         string `json:"pattern"`
+		Path            string `json:"path"`
+		Include         string `json:"include"`
+		ContextLines    int    `json:"context_lines"`
+		CaseInsensitive bool   `json:"case_insensitive"`
+		IncludeIgnored  bool   `json:"include_ignored"`
+		Offset          int    `json:"offset"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+
+	// Print what is being searched for
+	fmt.Printf("\n\033[33mSearching: %s in %s\033[0m\n\n", args.Pattern, args.Path)
+
+	path, denied, ok := approvedPath(functionName, args.Path, "search")
+	if !ok {
+		return denied, nil
+	}
+
+	output, err := searchFiles(ctx, searchRequest{
+		Pattern:         args.Pattern,
+		Path:            path,
+		Include:         args.Include,
+		ContextLines:    args.ContextLines,
+		CaseInsensitive: args.CaseInsensitive,
+		All:             args.IncludeIgnored,
+		Offset:          args.Offset,
+	})
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  output,
+		Success: true,
+	}, nil
+}
+
+// runWriteFile creates or overwrites a file after showing the diff
+func runWriteFile(functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		FilePath string `json:"file_path"`
+		Content  string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Print what file is being written
+	fmt.Printf("\n\033[33mWriting file: %s\033[0m\n", args.FilePath)
+
+	path, denied, ok := approvedPath(functionName, args.FilePath, "write")
+	if !ok {
+		return denied, nil
+	}
+
+	file, err := loadEditableFile(path)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+
+	return applyFileChange(functionName, file, args.Content), nil
+}
+
+// runEditFile replaces a string or a range of lines of a file after showing the diff
+func runEditFile(functionName string, arguments string) (FunctionCallResult, error) {
+	// Parse the function call arguments
+	var args struct {
+		FilePath   string `json:"file_path"`
+		OldString  string `json:"old_string"`
+		NewString  string `json:"new_string"`
+		ReplaceAll bool   `json:"replace_all"`
+		StartLine  int    `json:"start_line"`
+		EndLine    *int   `json:"end_line"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return FunctionCallResult{}, fmt.Errorf("error parsing function call arguments: %v", err)
+	}
+
+	// Print what file is being edited
+	fmt.Printf("\n\033[33mEditing file: %s\033[0m\n", args.FilePath)
+
+	path, denied, ok := approvedPath(functionName, args.FilePath, "write")
+	if !ok {
+		return denied, nil
+	}
+
+	file, err := loadEditableFile(path)
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+	if !file.Exists {
+		return toolErrorResult(functionName, fmt.Errorf("file not found: %s, use write_file to create it", args.FilePath)), nil
+	}
+
+	var newText string
+	if args.OldString == "" && args.StartLine > 0 {
+		// Without end_line a single line is replaced
+		endLine := args.StartLine
+		if args.EndLine != nil {
+			endLine = *args.EndLine
+		}
+		newText, err = file.replaceLines(args.StartLine, endLine, args.NewString)
+	} else {
+		newText, err = file.replaceString(args.OldString, args.NewString, args.ReplaceAll)
+	}
+	if err != nil {
+		return toolErrorResult(functionName, err), nil
+	}
+
+	return applyFileChange(functionName, file, newText), nil
+}
+
+// toolErrorResult prints an error of a tool and returns it to the model
+func toolErrorResult(functionName string, err error) FunctionCallResult {
+	outputStr := fmt.Sprintf("Error: %v", err)
+	fmt.Printf("%s\n\n", outputStr)
+	return FunctionCallResult{
+		Name:    functionName,
+		Output:  outputStr,
+		Success: false,
+	}
+}
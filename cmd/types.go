@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"io"
 )
 
@@ -17,18 +18,41 @@ const (
 // FunctionCallResult represents the result of a function call
 type FunctionCallResult struct {
 	Name    string
-	Output  string
+	Output  string `json:",omitempty"`
 	Success bool
+	// Denied is set when the approval policy or the user refused to run the call
+	Denied     bool   `json:",omitempty"`
+	DenyReason string `json:",omitempty"`
+	// OutputTruncated is set when the middle of Output was cut to the output limit of the tool
+	OutputTruncated bool `json:",omitempty"`
+
+	// Commands report their streams separately instead of Output
+	Stdout          string `json:",omitempty"`
+	Stderr          string `json:",omitempty"`
+	StdoutTruncated bool   `json:",omitempty"`
+	StderrTruncated bool   `json:",omitempty"`
+	// ExitCode is nil when the command couldn't be started, -1 when it was killed by a signal
+	ExitCode    *int   `json:",omitempty"`
+	DurationMs  int64  `json:",omitempty"`
+	TimedOut    bool   `json:",omitempty"`
+	Interrupted bool   `json:",omitempty"`
+	WorkDir     string `json:",omitempty"`
+	// KeptVariables are protected variables the command changed that don't carry over
+	KeptVariables []string `json:",omitempty"`
+
+	// Background jobs report their ID and whether they are still running
+	JobID   int  `json:",omitempty"`
+	Running bool `json:",omitempty"`
 }
 
 // AIAgent interface for different AI providers
 type AIAgent interface {
 	// Query sends a prompt to the AI and returns the response
-	Query(prompt string) (string, error)
+	Query(ctx context.Context, prompt string) (string, error)
 	// StreamQuery sends a prompt to the AI and streams the response to the writer
-	StreamQuery(prompt string, writer io.Writer) error
+	StreamQuery(ctx context.Context, prompt string, writer io.Writer) error
 	// StreamQueryWithFunctionCalls sends a prompt to the AI, handles function calls, and streams the response
-	StreamQueryWithFunctionCalls(prompt string) error
+	StreamQueryWithFunctionCalls(ctx context.Context, prompt string) error
 	// Name returns the name of the agent
 	Name() string
 }
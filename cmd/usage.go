@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aurora-agent/config"
+)
+
+// UsageRecord is the token usage and cost of a single request
+type UsageRecord struct {
+	Time         time.Time `json:"time"`
+	Session      string    `json:"session"`
+	Agent        AgentType `json:"agent"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Cost         float64   `json:"cost"`
+	// Priced is false when the price of the model is unknown and the cost is 0
+	Priced    bool `json:"priced"`
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// UsageTotals adds up the usage of several requests
+type UsageTotals struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	// Estimated is set when some of the token counts were estimated
+	Estimated bool `json:"estimated,omitempty"`
+	// Unpriced is set when some requests used a model without a known price
+	Unpriced bool `json:"unpriced,omitempty"`
+}
+
+// newUsageRecord prices the usage of a request
+func newUsageRecord(session string, agent AgentType, model string, usage Usage) UsageRecord {
+	price, priced := config.GetModelPrice(model)
+	return UsageRecord{
+		Time:         time.Now(),
+		Session:      session,
+		Agent:        agent,
+		Model:        model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		Cost:         price.Cost(usage.InputTokens, usage.OutputTokens),
+		Priced:       priced,
+		Estimated:    usage.Estimated,
+	}
+}
+
+// Add adds a request to the totals
+func (t *UsageTotals) Add(record UsageRecord) {
+	t.Requests++
+	t.InputTokens += record.InputTokens
+	t.OutputTokens += record.OutputTokens
+	t.Cost += record.Cost
+	t.Estimated = t.Estimated || record.Estimated
+	t.Unpriced = t.Unpriced || !record.Priced
+}
+
+// String formats the totals for display
+func (t UsageTotals) String() string {
+	text := fmt.Sprintf("%d requests, %d input + %d output tokens, $%.4f",
+		t.Requests, t.InputTokens, t.OutputTokens, t.Cost)
+	if t.Estimated {
+		text += " (some tokens estimated)"
+	}
+	if t.Unpriced {
+		text += " (some models have no price)"
+	}
+	return text
+}
+
+// appendUsageRecord appends a record to the usage log
+func appendUsageRecord(record UsageRecord) error {
+	path := config.GetUsageLogPath()
+	if path == "" {
+		return fmt.Errorf("usage log not available")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create usage log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// readUsageRecords reads the records of the usage log made since the given time
+func readUsageRecords(since time.Time) ([]UsageRecord, error) {
+	file, err := os.Open(config.GetUsageLogPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Skip damaged lines, e.g. from a write that was interrupted
+			continue
+		}
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// startOfDay returns midnight of the local day of t
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// dailyUsage returns the usage totals of today
+func dailyUsage() (UsageTotals, error) {
+	records, err := readUsageRecords(startOfDay(time.Now()))
+	if err != nil {
+		return UsageTotals{}, err
+	}
+
+	var totals UsageTotals
+	for _, record := range records {
+		totals.Add(record)
+	}
+	return totals, nil
+}
+
+// checkUsageBudget returns an error when the session or daily budget is used up
+func checkUsageBudget(session *Session) error {
+	settings := config.CurrentConfig.Usage
+
+	if settings.SessionBudget > 0 && session.Usage.Cost >= settings.SessionBudget {
+		return fmt.Errorf("session budget of $%.2f reached ($%.4f spent), raise it with 'config set usage sessionbudget <usd>' or start a new session",
+			settings.SessionBudget, session.Usage.Cost)
+	}
+
+	if settings.DailyBudget > 0 {
+		today, err := dailyUsage()
+		if err != nil {
+			return err
+		}
+		if today.Cost >= settings.DailyBudget {
+			return fmt.Errorf("daily budget of $%.2f reached ($%.4f spent today), raise it with 'config set usage dailybudget <usd>'",
+				settings.DailyBudget, today.Cost)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"aurora-agent/config"
+)
+
+// processUsageCommand handles the usage command
+func processUsageCommand(input string) bool {
+	words := strings.Fields(input)
+	if len(words) == 0 || words[0] != "usage" {
+		return false
+	}
+
+	if len(words) == 1 {
+		showUsage()
+		return true
+	}
+
+	switch words[1] {
+	case "days":
+		days := 7
+		if len(words) > 2 {
+			n, err := strconv.Atoi(words[2])
+			if err != nil || n < 1 {
+				fmt.Println("\033[31mError: Wrong format. Use: usage days [number]\033[0m")
+				return true
+			}
+			days = n
+		}
+		showDailyUsage(days)
+
+	default:
+		fmt.Println("\033[31mUnknown usage command. Available commands: usage, usage days [number]\033[0m")
+	}
+
+	return true
+}
+
+// showUsage displays the usage of the last turn, the session and today
+func showUsage() {
+	fmt.Println("\n\033[1mToken usage:\033[0m")
+	fmt.Printf("  Last turn: %s\n", AgentMgr.TurnUsage())
+	fmt.Printf("  Session:   %s\n", AgentMgr.Session().Usage)
+
+	today, err := dailyUsage()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+	} else {
+		fmt.Printf("  Today:     %s\n", today)
+	}
+
+	settings := config.CurrentConfig.Usage
+	if settings.SessionBudget > 0 {
+		fmt.Printf("  Session budget: $%.2f\n", settings.SessionBudget)
+	}
+	if settings.DailyBudget > 0 {
+		fmt.Printf("  Daily budget:   $%.2f\n", settings.DailyBudget)
+	}
+	fmt.Println()
+}
+
+// showDailyUsage displays the usage per day and model for the last days
+func showDailyUsage(days int) {
+	since := startOfDay(time.Now()).AddDate(0, 0, -(days - 1))
+	records, err := readUsageRecords(since)
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No usage recorded")
+		return
+	}
+
+	// Totals per day, and per model within the day, in the order they were first used
+	type dayUsage struct {
+		date   string
+		total  UsageTotals
+		models map[string]*UsageTotals
+		order  []string
+	}
+	var usageByDay []*dayUsage
+	for _, record := range records {
+		date := record.Time.Local().Format("2006-01-02")
+		if len(usageByDay) == 0 || usageByDay[len(usageByDay)-1].date != date {
+			usageByDay = append(usageByDay, &dayUsage{date: date, models: map[string]*UsageTotals{}})
+		}
+		day := usageByDay[len(usageByDay)-1]
+		day.total.Add(record)
+		if _, ok := day.models[record.Model]; !ok {
+			day.models[record.Model] = &UsageTotals{}
+			day.order = append(day.order, record.Model)
+		}
+		day.models[record.Model].Add(record)
+	}
+
+	fmt.Println("\n\033[1mUsage per day:\033[0m")
+	for _, day := range usageByDay {
+		fmt.Printf("  \033[1m%s\033[0m  %s\n", day.date, day.total)
+		for _, model := range day.order {
+			fmt.Printf("    %-24s %s\n", model, *day.models[model])
+		}
+	}
+	fmt.Println()
+}
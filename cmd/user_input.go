@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// LineReader reads a line of input from the user. defaultValue is offered
+// for editing when the reader supports it.
+type LineReader func(prompt string, defaultValue string) (string, error)
+
+// lineReader is used for confirmations while the AI is working
+var lineReader LineReader = readLineFromStdin
+
+// SetLineReader sets the reader used to ask the user questions,
+// main uses it to route prompts through readline
+func SetLineReader(reader LineReader) {
+	lineReader = reader
+}
+
+// readLineFromStdin is the fallback reader when no readline instance is set
+func readLineFromStdin(prompt string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Printf("%s[%s] ", prompt, defaultValue)
+	} else {
+		fmt.Print(prompt)
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return defaultValue, nil
+	}
+	return line, nil
+}
+
+// PasswordReader reads a password from the user without echoing it
+type PasswordReader func(prompt string) ([]byte, error)
+
+// passwordReader is used to ask for the sudo password
+var passwordReader PasswordReader = readPasswordFromTerminal
+
+// SetPasswordReader sets the reader used to ask for passwords,
+// main uses it to route prompts through readline
+func SetPasswordReader(reader PasswordReader) {
+	passwordReader = reader
+}
+
+// readPasswordFromTerminal is the fallback reader when no readline instance is set
+func readPasswordFromTerminal(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	return password, err
+}
+
+// readPassword asks the user for a password, trailing newlines are removed
+func readPassword(prompt string) ([]byte, error) {
+	password, err := passwordReader(prompt)
+	return bytes.TrimRight(password, "\r\n"), err
+}
+
+// askUser asks the user a question and returns the trimmed answer
+func askUser(prompt string, defaultValue string) (string, error) {
+	answer, err := lineReader(prompt, defaultValue)
+	return strings.TrimSpace(answer), err
+}
@@ -12,3 +12,6 @@ package config
 // - file_operations.go: File loading and saving functions
 // - shell_commands.go: Shell command management functions
 // - system_prompt.go: System prompt handling functions
+// - openai_endpoints.go: OpenAI-compatible endpoint resolution
+// - model_context.go: Context window sizes of models
+// - model_prices.go: Model prices for usage accounting
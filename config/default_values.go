@@ -53,6 +53,207 @@ var DefaultShellCommands = []string{
 	"bash", "sh", "zsh", "fish", "dash", "tcsh", "csh", "ksh",
 }
 
+// DefaultCommandRisks - risk level of known commands, grouped like DefaultShellCommands.
+// Keys may include a subcommand ("git push"), the most specific key wins.
+// Commands that are not listed are treated as writing files.
+var DefaultCommandRisks = map[string]string{
+	// User information and system data
+	"whoami": RiskReadOnly, "id": RiskReadOnly, "uname": RiskReadOnly, "hostname": RiskReadOnly,
+	"uptime": RiskReadOnly, "w": RiskReadOnly, "who": RiskReadOnly, "groups": RiskReadOnly,
+	"whois": RiskReadOnly, "finger": RiskReadOnly, "last": RiskReadOnly, "lastlog": RiskReadOnly,
+	"lastb": RiskReadOnly, "lastcomm": RiskReadOnly, "clear": RiskReadOnly, "date": RiskReadOnly,
+	"echo": RiskReadOnly, "printf": RiskReadOnly, "true": RiskReadOnly, "false": RiskReadOnly,
+	"printenv": RiskReadOnly, "which": RiskReadOnly, "whereis": RiskReadOnly, "type": RiskReadOnly,
+	"free": RiskReadOnly, "test": RiskReadOnly, "[": RiskReadOnly, "cd": RiskReadOnly,
+
+	// User and permissions
+	"su": RiskPrivileged, "sudo": RiskPrivileged, "doas": RiskPrivileged, "pkexec": RiskPrivileged,
+	"passwd": RiskPrivileged, "useradd": RiskPrivileged, "userdel": RiskPrivileged,
+	"usermod": RiskPrivileged, "visudo": RiskPrivileged,
+	"chown": RiskWritesFiles, "chmod": RiskWritesFiles, "chgrp": RiskWritesFiles, "umask": RiskReadOnly,
+
+	// Folders and files
+	"ls": RiskReadOnly, "ll": RiskReadOnly, "la": RiskReadOnly, "pwd": RiskReadOnly,
+	"find": RiskReadOnly, "locate": RiskReadOnly, "tree": RiskReadOnly, "stat": RiskReadOnly,
+	"file": RiskReadOnly, "realpath": RiskReadOnly, "basename": RiskReadOnly, "dirname": RiskReadOnly,
+	"mkdir": RiskWritesFiles, "touch": RiskWritesFiles, "mv": RiskWritesFiles, "cp": RiskWritesFiles,
+	"ln": RiskWritesFiles, "install": RiskWritesFiles, "updatedb": RiskWritesFiles,
+	"rmdir": RiskDestructive, "rm": RiskDestructive, "shred": RiskDestructive, "truncate": RiskDestructive,
+	"unlink": RiskDestructive,
+
+	// File and text manipulation
+	"cat": RiskReadOnly, "tac": RiskReadOnly, "less": RiskReadOnly, "more": RiskReadOnly,
+	"head": RiskReadOnly, "tail": RiskReadOnly, "grep": RiskReadOnly, "egrep": RiskReadOnly,
+	"fgrep": RiskReadOnly, "rg": RiskReadOnly, "awk": RiskReadOnly, "sed": RiskReadOnly,
+	"cut": RiskReadOnly, "sort": RiskReadOnly, "uniq": RiskReadOnly, "wc": RiskReadOnly,
+	"diff": RiskReadOnly, "cmp": RiskReadOnly, "tr": RiskReadOnly, "jq": RiskReadOnly,
+	"xxd": RiskReadOnly, "od": RiskReadOnly, "md5sum": RiskReadOnly, "sha256sum": RiskReadOnly,
+	"tee": RiskWritesFiles,
+
+	// Processes and system monitoring
+	"ps": RiskReadOnly, "top": RiskReadOnly, "htop": RiskReadOnly, "jobs": RiskReadOnly,
+	"lsof": RiskReadOnly, "strace": RiskReadOnly, "ltrace": RiskReadOnly, "pgrep": RiskReadOnly,
+	"nice": RiskWritesFiles, "renice": RiskWritesFiles, "fg": RiskWritesFiles, "bg": RiskWritesFiles,
+	"kill": RiskDestructive, "pkill": RiskDestructive, "killall": RiskDestructive,
+
+	// Disk and file system
+	"df": RiskReadOnly, "du": RiskReadOnly, "lsblk": RiskReadOnly, "blkid": RiskReadOnly,
+	"sync":  RiskReadOnly,
+	"mount": RiskPrivileged, "umount": RiskPrivileged, "tune2fs": RiskPrivileged,
+	"fsck": RiskDestructive, "e2fsck": RiskDestructive, "mkfs": RiskDestructive, "fdisk": RiskDestructive,
+	"parted": RiskDestructive, "dd": RiskDestructive, "wipefs": RiskDestructive, "mkswap": RiskDestructive,
+
+	// Network and internet
+	"ping": RiskReadOnly, "traceroute": RiskReadOnly, "netstat": RiskReadOnly, "ss": RiskReadOnly,
+	"ifconfig": RiskReadOnly, "ip": RiskReadOnly, "iwconfig": RiskReadOnly, "dig": RiskReadOnly,
+	"nslookup": RiskReadOnly, "host": RiskReadOnly,
+	"curl": RiskReadOnly, "wget": RiskWritesFiles, "scp": RiskWritesFiles, "rsync": RiskWritesFiles,
+	"nc": RiskWritesFiles, "telnet": RiskWritesFiles, "ftp": RiskWritesFiles, "sftp": RiskWritesFiles,
+	"iptables": RiskPrivileged, "ufw": RiskPrivileged,
+
+	// Archiving and compression
+	"tar": RiskWritesFiles, "zip": RiskWritesFiles, "unzip": RiskWritesFiles, "gzip": RiskWritesFiles,
+	"gunzip": RiskWritesFiles, "bzip2": RiskWritesFiles, "bunzip2": RiskWritesFiles, "xz": RiskWritesFiles,
+	"7z": RiskWritesFiles, "rar": RiskWritesFiles, "unrar": RiskWritesFiles,
+	"tar -t": RiskReadOnly, "tar -tf": RiskReadOnly, "tar -tzf": RiskReadOnly, "unzip -l": RiskReadOnly,
+
+	// Software and package management
+	"apt": RiskPrivileged, "apt-get": RiskPrivileged, "yum": RiskPrivileged, "dnf": RiskPrivileged,
+	"pacman": RiskPrivileged, "zypper": RiskPrivileged, "snap": RiskPrivileged, "dpkg": RiskPrivileged,
+	"rpm": RiskPrivileged, "systemctl": RiskPrivileged, "service": RiskPrivileged,
+	"apt list": RiskReadOnly, "apt show": RiskReadOnly, "apt search": RiskReadOnly, "apt-cache": RiskReadOnly,
+	"dpkg -l": RiskReadOnly, "rpm -q": RiskReadOnly, "rpm -qa": RiskReadOnly, "systemctl status": RiskReadOnly,
+	"brew": RiskWritesFiles, "flatpak": RiskWritesFiles, "pip": RiskWritesFiles, "pip3": RiskWritesFiles,
+	"gem": RiskWritesFiles, "npm": RiskWritesFiles, "cargo": RiskWritesFiles,
+	"brew list": RiskReadOnly, "pip list": RiskReadOnly, "pip show": RiskReadOnly, "pip3 list": RiskReadOnly,
+	"pip3 show": RiskReadOnly, "pip freeze": RiskReadOnly, "npm list": RiskReadOnly, "npm ls": RiskReadOnly,
+	"npm view": RiskReadOnly,
+
+	// Docker and containers
+	"docker": RiskWritesFiles, "docker-compose": RiskWritesFiles, "podman": RiskWritesFiles,
+	"kubectl": RiskWritesFiles, "minikube": RiskWritesFiles,
+	"docker ps": RiskReadOnly, "docker images": RiskReadOnly, "docker logs": RiskReadOnly,
+	"docker inspect": RiskReadOnly, "kubectl get": RiskReadOnly, "kubectl describe": RiskReadOnly,
+	"kubectl logs": RiskReadOnly, "docker rm": RiskDestructive, "docker rmi": RiskDestructive,
+	"docker system prune": RiskDestructive, "kubectl delete": RiskDestructive,
+
+	// Git and version control
+	"git": RiskWritesFiles, "git status": RiskReadOnly, "git log": RiskReadOnly, "git diff": RiskReadOnly,
+	"git show": RiskReadOnly, "git branch": RiskReadOnly, "git remote": RiskReadOnly, "git blame": RiskReadOnly,
+	"git rev-parse": RiskReadOnly, "git ls-files": RiskReadOnly, "git grep": RiskReadOnly,
+	"git clean": RiskDestructive, "git reset --hard": RiskDestructive, "git push --force": RiskDestructive,
+	"git push -f": RiskDestructive, "git checkout --": RiskDestructive, "git restore": RiskDestructive,
+
+	// Programming languages and compilers
+	"go version": RiskReadOnly, "go env": RiskReadOnly, "go list": RiskReadOnly, "go vet": RiskReadOnly,
+	"python --version": RiskReadOnly, "python3 --version": RiskReadOnly, "node --version": RiskReadOnly,
+	"npm --version": RiskReadOnly, "java -version": RiskReadOnly, "rustc --version": RiskReadOnly,
+	"gcc --version": RiskReadOnly,
+
+	// Shell and scripts
+	"bash": RiskWritesFiles, "sh": RiskWritesFiles, "zsh": RiskWritesFiles, "fish": RiskWritesFiles,
+	"dash": RiskWritesFiles, "ksh": RiskWritesFiles, "eval": RiskWritesFiles, "source": RiskWritesFiles,
+	"exec": RiskWritesFiles, "export": RiskReadOnly, "declare": RiskReadOnly, "local": RiskReadOnly,
+	"readonly": RiskReadOnly, "typeset": RiskReadOnly, "unset": RiskReadOnly, "alias": RiskReadOnly,
+
+	// System state
+	"shutdown": RiskPrivileged, "reboot": RiskPrivileged, "halt": RiskPrivileged, "poweroff": RiskPrivileged,
+	"crontab": RiskWritesFiles, "sysctl": RiskPrivileged, "modprobe": RiskPrivileged, "insmod": RiskPrivileged,
+}
+
+// SystemPathPrefixes - directories where writing files requires root and affects the whole system
+var SystemPathPrefixes = []string{
+	"/etc", "/boot", "/usr", "/bin", "/sbin", "/lib", "/lib64", "/sys", "/proc", "/dev", "/var/lib", "/opt", "/root",
+}
+
+// DefaultDeniedPaths - paths the file tools never use, they hold keys, passwords and tokens
+var DefaultDeniedPaths = []string{
+	"**/.ssh/**", "**/.gnupg/**", "**/.aws/**", "**/.kube/config", "**/.docker/config.json",
+	"**/.netrc", "**/.pgpass", "**/.config/aurora/**", "**/*.pem", "**/*.key", "**/id_rsa*", "**/id_ecdsa*", "**/id_ed25519*",
+	".env*", "/etc/shadow*", "/etc/gshadow*", "/etc/sudoers", "/etc/sudoers.d/**",
+}
+
+// DefaultCheckpointIgnoreDirs - directories not scanned for files changed by commands, they are large or can be regenerated
+var DefaultCheckpointIgnoreDirs = []string{
+	".git", ".hg", ".svn", "node_modules", "vendor", ".venv", "venv", "__pycache__", ".cache", "target", "dist", "build",
+}
+
+// DefaultToolOutputLimits - output limits of tools, the file tools page their results themselves
+var DefaultToolOutputLimits = map[string]OutputLimit{
+	"execute_command":    {Head: 16 * 1024, Tail: 8 * 1024},
+	"execute_privileged": {Head: 16 * 1024, Tail: 8 * 1024},
+}
+
+// DefaultContextWindow - context window of models that are not listed in DefaultContextWindows
+const DefaultContextWindow = 32768
+
+// DefaultContextWindows - context window in tokens of known models, matched by the longest name prefix
+var DefaultContextWindows = map[string]int{
+	// OpenAI
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"gpt-5":         400000,
+	"o1":            200000,
+	"o3":            200000,
+	"o4-mini":       200000,
+
+	// Anthropic
+	"claude": 200000,
+
+	// Common local models
+	"llama3":    8192,
+	"llama3.1":  131072,
+	"llama3.2":  131072,
+	"qwen2.5":   32768,
+	"qwen3":     40960,
+	"mistral":   32768,
+	"gemma3":    131072,
+	"deepseek":  65536,
+	"phi4":      16384,
+	"codellama": 16384,
+}
+
+// DefaultModelPrices - price in USD per million tokens of known models, matched by the longest name prefix.
+// Prices change, override them with usage.prices in the configuration file.
+var DefaultModelPrices = map[string]ModelPrice{
+	// OpenAI
+	"gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+	"gpt-4":         {Input: 30, Output: 60},
+	"gpt-4-turbo":   {Input: 10, Output: 30},
+	"gpt-4o":        {Input: 2.50, Output: 10},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.60},
+	"gpt-4.1":       {Input: 2, Output: 8},
+	"gpt-4.1-mini":  {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":  {Input: 0.10, Output: 0.40},
+	"gpt-5":         {Input: 1.25, Output: 10},
+	"gpt-5-mini":    {Input: 0.25, Output: 2},
+	"gpt-5-nano":    {Input: 0.05, Output: 0.40},
+	"o1":            {Input: 15, Output: 60},
+	"o3":            {Input: 2, Output: 8},
+	"o3-mini":       {Input: 1.10, Output: 4.40},
+	"o4-mini":       {Input: 1.10, Output: 4.40},
+
+	// Anthropic
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4},
+	"claude-haiku-4":    {Input: 1, Output: 5},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-opus-4":     {Input: 15, Output: 75},
+}
+
+// DefaultPolicyRules - built-in approval rules for AI-executed commands
+var DefaultPolicyRules = []PolicyRule{
+	{Action: PolicyActionDeny, Regex: `\brm\s+(-[a-zA-Z]*[rf][a-zA-Z]*\s+)+(/|/\*|~|\$HOME)(\s|$)`, Reason: "recursive removal of the root or home directory"},
+	{Action: PolicyActionDeny, Regex: `:\(\)\s*\{\s*:\|:&\s*\};:`, Reason: "fork bomb"},
+	{Action: PolicyActionAsk, Argv: []string{"sudo", "**"}, Reason: "requires elevated privileges"},
+}
+
 // DefaultSystemPrompt - standart tizim prompti
 const DefaultSystemPrompt = `
 Your name is Aurora.
@@ -82,13 +283,29 @@ You have the ability to execute multiple commands in sequence autonomously. When
 5. Provide a final summary of what you accomplished
 
 This allows you to solve complex problems by breaking them down into a series of steps without requiring the user to prompt you at each stage.
+When several read-only lookups are independent of each other (for example reading multiple files), request them together in a single turn so they can run in parallel.
+
+IMPORTANT: Be proactive and decisive when executing commands. If a command fails, try alternate approaches automatically without asking the user for permission. For example, if 'python --version' fails, immediately try 'python3 --version' without asking if you should continue.
+
+Commands you execute go through an approval policy. Depending on the configuration the user may be asked to approve, edit or reject a command before it runs. When a command is refused, the result has "Denied": true and a "DenyReason". Do not try to get around a denied command with an equivalent one; explain what you wanted to do and let the user decide.
+
+The result of execute_command has the ExitCode, Stdout and Stderr of the command and its DurationMs. Check the ExitCode instead of guessing from the output. Long output is cut in the middle, which StdoutTruncated and StderrTruncated report; narrow the command down (grep, head, tail) when you need the missing part.
+
+Commands run in the user's shell, with the aliases, functions and variables of the user's shell session. The working directory and exported variables carry over from one command to the next, so a cd stays in effect until the next cd. The working directory is shared with the user, your exported variables are not: they don't reach the user's shell. Changes to PATH, HOME, LD_*, SUDO_* and a few other variables that decide which programs run only last for the command that made them.
+
+execute_command can't run sudo, doas, pkexec or su, not even through env or bash -c. For commands that need root use execute_privileged with the command without sudo; the user confirms every call, so use it only when root is really needed and say why. Its cd and export don't carry over.
+
+Commands are killed after a timeout, pass a longer timeout for slow builds or test suites. Never run commands that don't end by themselves, like servers, watchers or tail -f, in the foreground: start them with background set to true and use read_job_output to check on them, and kill_job to stop them when they are no longer needed.
+
+To explore a project use the list_dir, glob and search tools instead of ls, find or grep through execute_command. They skip files ignored by .gitignore and return large results in pages, call them again with the offset they report to see more.
+
+To change files use the write_file and edit_file tools instead of echo, sed or heredocs through execute_command. Read a file before editing it and prefer edit_file with an exact old_string for small changes. The user sees a diff of every change and may reject it.
+
+Secrets in messages and tool results, like passwords, tokens and private keys, are replaced with placeholders such as [REDACTED_TOKEN_3] before you see them. Use a placeholder as it is in tool calls when a command or file needs the secret, Aurora puts the real value back. Never ask the user to reveal a hidden secret.
 
-IMPORTANT: Be proactive and decisive when executing commands. If a command fails, try alternate approaches automatically without asking the user for permission. For example, if 'python --version' fails, immediately try 'python3 --version' without asking if you should continue. Only ask for user confirmation when:
-1. The operation might modify system state in a way that can't be undone
-2. The operation requires elevated privileges (sudo)
-3. The operation might take a very long time or use significant resources
+The file tools work inside the workspace, the working directory Aurora was started in. The user is asked before they use paths outside it, and private keys, credentials and .env files are never available to them. Don't try to reach such files another way when access is refused.
 
-Safe, read-only commands like checking versions, listing files, reading documentation, or gathering system information should be executed without asking for permission.
+Safe, read-only commands like checking versions, listing files, reading documentation, or gathering system information are usually approved automatically.
 
 {{USER_INPUT}}
 `
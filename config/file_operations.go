@@ -19,6 +19,46 @@ func GetConfigPath() string {
 	return filepath.Join(homeDir, ".config", "aurora", "config.yaml")
 }
 
+// GetSessionsDir - get the directory where conversation sessions are stored
+func GetSessionsDir() string {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(configPath), "sessions")
+}
+
+// GetUsageLogPath - get the file where the usage of every request is recorded
+func GetUsageLogPath() string {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(configPath), "usage.jsonl")
+}
+
+// GetAuditLogPath - get the file where every command of the user and the agent is recorded
+func GetAuditLogPath() string {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(configPath), "audit.jsonl")
+}
+
+// GetCheckpointsDir - get the directory where snapshots of files changed by the AI are stored
+func GetCheckpointsDir() string {
+	configPath := GetConfigPath()
+	if configPath == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(configPath), "checkpoints")
+}
+
 // LoadConfig - load configuration file or create a new one
 func LoadConfig() error {
 	configPath := GetConfigPath()
@@ -42,6 +82,19 @@ func LoadConfig() error {
 		return fmt.Errorf("failed to read configuration file: %w", err)
 	}
 
+	// Start from the defaults so sections missing from older files keep their default values
+	CurrentConfig = DefaultConfig
+	CurrentConfig.OpenAI.Headers = map[string]string{}
+	CurrentConfig.OpenAI.Endpoints = map[string]OpenAIEndpoint{}
+	CurrentConfig.Policy.CommandRisks = map[string]string{}
+	CurrentConfig.Context.ContextWindows = map[string]int{}
+	CurrentConfig.Usage.Prices = map[string]ModelPrice{}
+	// Limits of the file keep the defaults of the tools they don't mention
+	CurrentConfig.Tools.OutputLimits = map[string]OutputLimit{}
+	for tool, limit := range DefaultToolOutputLimits {
+		CurrentConfig.Tools.OutputLimits[tool] = limit
+	}
+
 	// Read YAML format
 	if err := yaml.Unmarshal(data, &CurrentConfig); err != nil {
 		return fmt.Errorf("failed to parse configuration file: %w", err)
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+)
+
+// GetContextWindow - context window of a model in tokens.
+// Configured windows win over the built-in ones, the longest matching name prefix is used.
+func GetContextWindow(model string) int {
+	model = normalizeModelName(model)
+
+	if window, ok := longestPrefixMatch(CurrentConfig.Context.ContextWindows, model); ok && window > 0 {
+		return window
+	}
+	if window, ok := longestPrefixMatch(DefaultContextWindows, model); ok {
+		return window
+	}
+	return DefaultContextWindow
+}
+
+// normalizeModelName lowercases a model name and drops provider prefixes such as "openai/"
+func normalizeModelName(model string) string {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return model
+}
+
+// longestPrefixMatch returns the value of the longest key that model starts with
+func longestPrefixMatch[V any](values map[string]V, model string) (V, bool) {
+	best := ""
+	found := false
+	for prefix := range values {
+		if strings.HasPrefix(model, strings.ToLower(prefix)) && (!found || len(prefix) > len(best)) {
+			best = prefix
+			found = true
+		}
+	}
+	return values[best], found
+}
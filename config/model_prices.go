@@ -0,0 +1,18 @@
+package config
+
+// GetModelPrice - price of a model in USD per million tokens.
+// Configured prices win over the built-in ones, the longest matching name prefix is used.
+// The second result is false when the price of the model is unknown.
+func GetModelPrice(model string) (ModelPrice, bool) {
+	model = normalizeModelName(model)
+
+	if price, ok := longestPrefixMatch(CurrentConfig.Usage.Prices, model); ok {
+		return price, true
+	}
+	return longestPrefixMatch(DefaultModelPrices, model)
+}
+
+// Cost - cost in USD of a request with the given token counts
+func (p ModelPrice) Cost(inputTokens int, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// GetOpenAIEndpoint - resolve the OpenAI-compatible endpoint the agent should use
+//
+// When a named endpoint is selected its settings are used as they are, only the
+// model falls back to the openai section. Otherwise the openai section itself
+// describes the endpoint. The OPENAI_API_KEY environment variable is only used
+// when no key is configured and no base URL is set, so the OpenAI key is never
+// sent to another server.
+func GetOpenAIEndpoint() OpenAIEndpoint {
+	openaiConfig := CurrentConfig.OpenAI
+
+	if openaiConfig.Endpoint != "" {
+		if endpoint, ok := openaiConfig.Endpoints[openaiConfig.Endpoint]; ok {
+			if endpoint.Model == "" {
+				endpoint.Model = openaiConfig.Model
+			}
+			return endpoint
+		}
+	}
+
+	apiKey := openaiConfig.APIKey
+	if apiKey == "" && openaiConfig.BaseURL == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	return OpenAIEndpoint{
+		BaseURL:     openaiConfig.BaseURL,
+		APIKey:      apiKey,
+		Model:       openaiConfig.Model,
+		Headers:     openaiConfig.Headers,
+		StreamUsage: openaiConfig.StreamUsage,
+	}
+}
+
+// WantsStreamUsage - whether token usage is asked for in streamed responses
+func (e OpenAIEndpoint) WantsStreamUsage() bool {
+	return e.StreamUsage == nil || *e.StreamUsage
+}
+
+// SetOpenAIEndpoint - select a named endpoint, "default" or an empty name selects the openai section
+func SetOpenAIEndpoint(name string) error {
+	if name == "" || name == "default" {
+		CurrentConfig.OpenAI.Endpoint = ""
+		return nil
+	}
+
+	if _, ok := CurrentConfig.OpenAI.Endpoints[name]; !ok {
+		return fmt.Errorf("'%s' endpoint not found in openai.endpoints", name)
+	}
+
+	CurrentConfig.OpenAI.Endpoint = name
+	return nil
+}
+
+// SetOpenAIModel - set the model of the active endpoint
+func SetOpenAIModel(model string) {
+	name := CurrentConfig.OpenAI.Endpoint
+	if endpoint, ok := CurrentConfig.OpenAI.Endpoints[name]; ok && name != "" {
+		endpoint.Model = model
+		CurrentConfig.OpenAI.Endpoints[name] = endpoint
+		return
+	}
+
+	CurrentConfig.OpenAI.Model = model
+}
+
+// SetOpenAIStreamUsage - set whether the active endpoint is asked for token usage in streamed responses
+func SetOpenAIStreamUsage(enabled bool) {
+	name := CurrentConfig.OpenAI.Endpoint
+	if endpoint, ok := CurrentConfig.OpenAI.Endpoints[name]; ok && name != "" {
+		endpoint.StreamUsage = &enabled
+		CurrentConfig.OpenAI.Endpoints[name] = endpoint
+		return
+	}
+
+	CurrentConfig.OpenAI.StreamUsage = &enabled
+}
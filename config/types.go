@@ -8,9 +8,17 @@ import (
 
 // AppConfig - main configuration structure
 type AppConfig struct {
-	General   GeneralConfig   `yaml:"general"`
-	OpenAI    OpenAIConfig    `yaml:"openai"`
-	Interface InterfaceConfig `yaml:"interface"`
+	General     GeneralConfig     `yaml:"general"`
+	OpenAI      OpenAIConfig      `yaml:"openai"`
+	Anthropic   AnthropicConfig   `yaml:"anthropic"`
+	Interface   InterfaceConfig   `yaml:"interface"`
+	Policy      PolicyConfig      `yaml:"policy"`
+	Context     ContextConfig     `yaml:"context"`
+	Usage       UsageConfig       `yaml:"usage"`
+	Checkpoints CheckpointsConfig `yaml:"checkpoints"`
+	Tools       ToolsConfig       `yaml:"tools"`
+	Sudo        SudoConfig        `yaml:"sudo"`
+	Redaction   RedactionConfig   `yaml:"redaction"`
 }
 
 // GeneralConfig - general configuration
@@ -23,8 +31,33 @@ type GeneralConfig struct {
 
 // OpenAIConfig - OpenAI configuration
 type OpenAIConfig struct {
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	APIKey    string                    `yaml:"api_key"`
+	Model     string                    `yaml:"model"`
+	BaseURL   string                    `yaml:"base_url"`
+	Headers   map[string]string         `yaml:"headers"`
+	Endpoint  string                    `yaml:"endpoint"`
+	Endpoints map[string]OpenAIEndpoint `yaml:"endpoints"`
+	// StreamUsage asks for token usage in streamed responses, nil asks for it
+	StreamUsage *bool `yaml:"stream_usage,omitempty"`
+}
+
+// OpenAIEndpoint - an OpenAI-compatible server (Ollama, vLLM, llama.cpp, ...)
+type OpenAIEndpoint struct {
+	BaseURL string            `yaml:"base_url"`
+	APIKey  string            `yaml:"api_key"`
+	Model   string            `yaml:"model"`
+	Headers map[string]string `yaml:"headers"`
+	// StreamUsage asks for token usage in streamed responses, nil asks for it.
+	// Some servers reject the stream_options field it is sent in.
+	StreamUsage *bool `yaml:"stream_usage,omitempty"`
+}
+
+// AnthropicConfig - Anthropic (Claude) configuration
+type AnthropicConfig struct {
+	APIKey    string `yaml:"api_key"`
+	Model     string `yaml:"model"`
+	MaxTokens int    `yaml:"max_tokens"`
+	BaseURL   string `yaml:"base_url"`
 }
 
 // InterfaceConfig - interface configuration
@@ -33,6 +66,137 @@ type InterfaceConfig struct {
 	SystemPrompt string `yaml:"system_prompt"`
 }
 
+// PolicyConfig - approval policy for commands executed by the AI
+type PolicyConfig struct {
+	Mode             string            `yaml:"mode"`
+	Rules            []PolicyRule      `yaml:"rules"`
+	ReadOnlyCommands []string          `yaml:"read_only_commands"`
+	CommandRisks     map[string]string `yaml:"command_risks"`
+	// Paths limits the paths the file tools of the AI may use
+	Paths PathPolicyConfig `yaml:"paths"`
+}
+
+// PathPolicyConfig - where the file tools of the AI may read and write
+type PathPolicyConfig struct {
+	// Roots are the workspace directories, relative ones are resolved against the directory Aurora started in
+	Roots []string `yaml:"roots"`
+	// Deny are globs of paths the file tools never use, a glob without a slash matches a file or directory name anywhere
+	Deny []string `yaml:"deny"`
+	// Outside is the action for paths outside the roots: ask, deny or allow
+	Outside string `yaml:"outside"`
+}
+
+// ContextConfig - how the conversation is kept within the model context window
+type ContextConfig struct {
+	// Budget is the number of tokens the conversation may use, 0 uses the context window of the model
+	Budget int `yaml:"budget"`
+	// CompactAt is the percentage of the budget at which the conversation is compacted
+	CompactAt int `yaml:"compact_at"`
+	// KeepTurns is the number of recent turns that are never truncated or summarized
+	KeepTurns int `yaml:"keep_turns"`
+	// ToolOutputLimit is the number of characters kept of older tool outputs
+	ToolOutputLimit int `yaml:"tool_output_limit"`
+	// Summarize replaces older turns with a summary written by the model
+	Summarize bool `yaml:"summarize"`
+	// ContextWindows overrides the context window of models, matched by name prefix
+	ContextWindows map[string]int `yaml:"context_windows"`
+}
+
+// UsageConfig - token usage accounting and spending limits
+type UsageConfig struct {
+	// SessionBudget and DailyBudget stop the agent once the cost in USD reaches them, 0 disables the limit
+	SessionBudget float64 `yaml:"session_budget"`
+	DailyBudget   float64 `yaml:"daily_budget"`
+	// Prices overrides the price of models, matched by name prefix
+	Prices map[string]ModelPrice `yaml:"prices"`
+}
+
+// CheckpointsConfig - snapshots of the files the AI changes, used to undo its changes
+type CheckpointsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxCheckpoints is the number of turns with changes kept per session
+	MaxCheckpoints int `yaml:"max_checkpoints"`
+	// ScanCommands detects files changed by execute_command by scanning the working directory
+	ScanCommands bool `yaml:"scan_commands"`
+	// MaxFiles limits the number of files a scan looks at
+	MaxFiles int `yaml:"max_files"`
+	// MaxFileSize is the size in bytes up to which the content of scanned files is kept
+	MaxFileSize int64 `yaml:"max_file_size"`
+	// IgnoreDirs are directory names the scan skips
+	IgnoreDirs []string `yaml:"ignore_dirs"`
+}
+
+// ToolsConfig - limits of the tools the AI calls
+type ToolsConfig struct {
+	// OutputLimits caps the output of tools by tool name, tools that are not listed are not capped
+	OutputLimits map[string]OutputLimit `yaml:"output_limits"`
+	// CommandTimeout is the number of seconds after which a command is killed, unless the AI asks for another timeout
+	CommandTimeout int `yaml:"command_timeout"`
+	// MaxCommandTimeout is the longest timeout in seconds the AI may ask for, longer tasks run in the background
+	MaxCommandTimeout int `yaml:"max_command_timeout"`
+}
+
+// SudoConfig - how the sudo password is kept
+type SudoConfig struct {
+	// IdleTimeout is the number of minutes after which an unused sudo password is forgotten, 0 keeps it until Aurora exits
+	IdleTimeout int `yaml:"idle_timeout"`
+}
+
+// RedactionConfig - secrets hidden from the model in prompts and tool results
+type RedactionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// HighEntropy also hides long tokens that look random, like keys without a known format
+	HighEntropy bool `yaml:"high_entropy"`
+	// Patterns are regular expressions of more secrets, the first group is hidden when there is one
+	Patterns []string `yaml:"patterns"`
+}
+
+// OutputLimit - the bytes kept of the start and the end of a tool output, the middle is cut
+type OutputLimit struct {
+	Head int `yaml:"head"`
+	Tail int `yaml:"tail"`
+}
+
+// ModelPrice - price of a model in USD per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// PolicyRule - allow, deny or ask rule matched by prefix, regex or parsed argv
+type PolicyRule struct {
+	Action string   `yaml:"action"`
+	Prefix string   `yaml:"prefix,omitempty"`
+	Regex  string   `yaml:"regex,omitempty"`
+	Argv   []string `yaml:"argv,omitempty"`
+	Reason string   `yaml:"reason,omitempty"`
+}
+
+// Policy modes
+const (
+	// PolicyModeAuto runs commands without asking unless a rule says otherwise
+	PolicyModeAuto = "auto"
+	// PolicyModeAskOnWrite asks before commands that are not known to be read-only
+	PolicyModeAskOnWrite = "ask-on-write"
+	// PolicyModeAlwaysAsk asks before every command
+	PolicyModeAlwaysAsk = "always-ask"
+)
+
+// Command risk levels assigned by the command classifier, from least to most dangerous
+const (
+	RiskReadOnly    = "read-only"
+	RiskWritesFiles = "writes-files"
+	RiskDestructive = "destructive"
+	RiskPrivileged  = "privileged"
+)
+
+// Policy rule actions
+const (
+	PolicyActionAllow = "allow"
+	PolicyActionDeny  = "deny"
+	PolicyActionAsk   = "ask"
+)
+
 // DefaultConfig - standart configuration
 var DefaultConfig = AppConfig{
 	General: GeneralConfig{
@@ -42,15 +206,76 @@ var DefaultConfig = AppConfig{
 		IgnoredCommands: []string{},
 	},
 	OpenAI: OpenAIConfig{
-		APIKey: "",
-		Model:  openai.GPT4o,
+		APIKey:    "",
+		Model:     openai.GPT4o,
+		BaseURL:   "",
+		Headers:   map[string]string{},
+		Endpoint:  "",
+		Endpoints: map[string]OpenAIEndpoint{},
+	},
+	Anthropic: AnthropicConfig{
+		APIKey:    "",
+		Model:     DefaultAnthropicModel,
+		MaxTokens: DefaultAnthropicMaxTokens,
+		BaseURL:   "",
 	},
 	Interface: InterfaceConfig{
 		Theme:        "default",
 		SystemPrompt: "default",
 	},
+	Policy: PolicyConfig{
+		Mode:             PolicyModeAskOnWrite,
+		Rules:            DefaultPolicyRules,
+		ReadOnlyCommands: []string{},
+		CommandRisks:     map[string]string{},
+		Paths: PathPolicyConfig{
+			Roots:   []string{"."},
+			Deny:    DefaultDeniedPaths,
+			Outside: PolicyActionAsk,
+		},
+	},
+	Context: ContextConfig{
+		Budget:          0,
+		CompactAt:       80,
+		KeepTurns:       4,
+		ToolOutputLimit: 2000,
+		Summarize:       true,
+		ContextWindows:  map[string]int{},
+	},
+	Usage: UsageConfig{
+		SessionBudget: 0,
+		DailyBudget:   0,
+		Prices:        map[string]ModelPrice{},
+	},
+	Checkpoints: CheckpointsConfig{
+		Enabled:        true,
+		MaxCheckpoints: 50,
+		ScanCommands:   true,
+		MaxFiles:       5000,
+		MaxFileSize:    1024 * 1024,
+		IgnoreDirs:     DefaultCheckpointIgnoreDirs,
+	},
+	Tools: ToolsConfig{
+		OutputLimits:      DefaultToolOutputLimits,
+		CommandTimeout:    120,
+		MaxCommandTimeout: 600,
+	},
+	Sudo: SudoConfig{
+		IdleTimeout: 15,
+	},
+	Redaction: RedactionConfig{
+		Enabled:     true,
+		HighEntropy: true,
+		Patterns:    []string{},
+	},
 }
 
+// Default values for the Anthropic section
+const (
+	DefaultAnthropicModel     = "claude-sonnet-4-5"
+	DefaultAnthropicMaxTokens = 4096
+)
+
 // CurrentConfig - current configuration
 var CurrentConfig AppConfig
 
@@ -5,11 +5,10 @@ go 1.24.0
 require (
 	github.com/chzyer/readline v1.5.1
 	github.com/creack/pty v1.1.24
+	github.com/sashabaranov/go-openai v1.38.0
 	golang.org/x/term v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+	mvdan.cc/sh/v3 v3.7.0
 )
 
-require (
-	github.com/sashabaranov/go-openai v1.38.0 // indirect
-	golang.org/x/sys v0.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require golang.org/x/sys v0.31.0 // indirect
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
@@ -18,13 +17,20 @@ import (
 
 // Version will be set during build time
 var Version = "dev"
-var sudoPassword string
-var sudoEnabled bool
 
 // Global signal channel
 var sigs chan os.Signal
 
 func init() {
+	// Aurora runs itself from shells to read their working directory and environment
+	if cmd.HandleShellStateDump() {
+		os.Exit(0)
+	}
+	// sudo runs Aurora to get the password of sudo mode
+	if cmd.HandleSudoAskpass() {
+		os.Exit(0)
+	}
+
 	// Create a single signal channel (to avoid multiple calls)
 	sigs = make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT)
@@ -36,6 +42,14 @@ func init() {
 				fmt.Println("\n[!] Process terminated")
 				utils.ActiveCmd.Process.Signal(syscall.SIGINT) // Only kill the active process
 			}
+			// Commands of the user run in a shell session of their own
+			if cmd.InterruptShellCommand() {
+				fmt.Println("\n[!] Process terminated")
+			}
+			// Stop the AI response and the commands it is running
+			if cmd.AgentMgr != nil {
+				cmd.AgentMgr.CancelTurn()
+			}
 		}
 	}()
 
@@ -45,17 +59,12 @@ func init() {
 		fmt.Println("Using default configuration.")
 		config.CurrentConfig = config.DefaultConfig
 	}
+
+	// Initialize AI agents after configuration is loaded so they pick up its settings
+	cmd.InitAgentManager()
 }
 
 func main() {
-	// Determine user's default shell
-	userShell := cmd.GetDefaultShell()
-
-	// If configuration has a shell, use it
-	if config.CurrentConfig.General.DefaultShell != "" {
-		userShell = config.CurrentConfig.General.DefaultShell
-	}
-
 	// Check for --sudo flag
 	if len(os.Args) > 1 && os.Args[1] == "--sudo" {
 		fmt.Print("[sudo] Enter password: ")
@@ -66,11 +75,13 @@ func main() {
 			fmt.Println("Error: Could not read password.")
 			os.Exit(1)
 		}
-		sudoPassword = strings.TrimSpace(string(bytePassword))
-		sudoEnabled = true
 
-		// Verify password
-		if !cmd.CheckSudoPassword(sudoPassword) {
+		// Verify password, it is only kept in memory
+		err = cmd.EnableSudo(bytePassword)
+		for i := range bytePassword {
+			bytePassword[i] = 0
+		}
+		if err != nil {
 			fmt.Println("Error: Incorrect password!")
 			os.Exit(1)
 		}
@@ -89,7 +100,18 @@ func main() {
 		fmt.Println("Error: Could not read terminal.")
 		os.Exit(1)
 	}
-	defer rl.Close()
+
+	// Questions asked while the AI is working (e.g. command approval) use readline too
+	cmd.SetLineReader(func(prompt string, defaultValue string) (string, error) {
+		rl.SetPrompt(prompt)
+		rl.HistoryDisable()
+		defer func() {
+			rl.HistoryEnable()
+			rl.SetPrompt(getPrompt())
+		}()
+		return rl.ReadlineWithDefault(defaultValue)
+	})
+	cmd.SetPasswordReader(rl.ReadPassword)
 
 	for {
 		input, err := rl.Readline()
@@ -108,63 +130,40 @@ func main() {
 			continue
 		}
 
-		// Process Aurora commands
-		if cmd.ProcessAuroraCommand(input) {
+		// Run cd, pushd, popd, dirs and exit in Aurora, like a shell runs its builtins
+		if handled, exit := cmd.RunBuiltin(input); handled {
+			if exit {
+				fmt.Println("Exiting program.")
+				break
+			}
+			rl.SetPrompt(getPrompt())
 			continue
 		}
 
-		if input == "exit" || input == "quit" {
-			fmt.Println("Exiting program.")
-			break
-		}
-
-		args := strings.Fields(input)
-
-		// Handle cd command specially
-		if args[0] == "cd" {
-			// Default to home directory if no argument is provided
-			if len(args) == 1 {
-				homeDir, err := os.UserHomeDir()
-				if err != nil {
-					fmt.Println("Error:", err)
-					continue
-				}
-				err = os.Chdir(homeDir)
-				if err != nil {
-					fmt.Println("Error:", err)
-				} else {
-					rl.SetPrompt(getPrompt())
-				}
-				continue
-			}
-
-			// Handle cd with path argument
-			err := os.Chdir(args[1])
-			if err != nil {
-				fmt.Println("Error:", err)
-			} else {
-				rl.SetPrompt(getPrompt())
-			}
+		// Process Aurora commands
+		if cmd.ProcessAuroraCommand(input) {
 			continue
 		}
 
-		// Check for sudo
-		if args[0] == "sudo" {
-			if !sudoEnabled {
-				fmt.Println("Sudo mode not enabled. Start the program with --sudo or enter password.")
-				continue
-			}
-
-			args = args[1:]
-			command := exec.Command(userShell, "-i", "-c", fmt.Sprintf("echo %s | sudo -S -p '' %s", sudoPassword, strings.Join(args, " ")))
-			utils.RunCommandWithPTY(command)
+		// sudo gets the password of sudo mode without it being part of the command line
+		if cmd.UsesSudo(input) {
+			cmd.RunSudoCommand(input)
+			rl.SetPrompt(getPrompt())
 			continue
 		}
 
-		// Run in shell environment (to preserve colors)
-		command := exec.Command(userShell, "-i", "-c", input)
-		utils.RunCommandWithPTY(command)
+		// Run in the shell session (to preserve colors), its state stays between commands
+		cmd.RunShellCommand(input)
+		rl.SetPrompt(getPrompt())
 	}
+
+	// Servers and watchers the AI started don't outlive Aurora
+	cmd.KillBackgroundJobs()
+	cmd.CloseShellSession()
+
+	// Exit with the code given to exit or of the last command, like a shell
+	rl.Close()
+	os.Exit(cmd.ExitStatus())
 }
 
 // getPrompt returns a prompt string with only the current directory name in color
@@ -214,6 +213,25 @@ func handleAIAgentCommands(input string) bool {
 		return true
 	}
 
+	// Check for setting Anthropic API key
+	if strings.HasPrefix(input, "set claude key") {
+		parts := strings.Fields(input)
+		if len(parts) < 4 {
+			fmt.Println("Usage: set claude key <your_api_key>")
+			return true
+		}
+
+		apiKey := parts[3]
+		// Set the API key in environment variable
+		os.Setenv("ANTHROPIC_API_KEY", apiKey)
+
+		// Recreate the agents to use the new key, the conversation is kept
+		cmd.AgentMgr.ReloadAgents()
+
+		fmt.Println("Anthropic API key set successfully")
+		return true
+	}
+
 	// Check for setting OpenAI API key
 	if strings.HasPrefix(input, "set openai key") {
 		parts := strings.Fields(input)
@@ -226,8 +244,8 @@ func handleAIAgentCommands(input string) bool {
 		// Set the API key in environment variable
 		os.Setenv("OPENAI_API_KEY", apiKey)
 
-		// Reinitialize the agent manager to use the new key
-		cmd.AgentMgr = cmd.NewAgentManager()
+		// Recreate the agents to use the new key, the conversation is kept
+		cmd.AgentMgr.ReloadAgents()
 
 		fmt.Println("OpenAI API key set successfully")
 		return true
@@ -236,6 +254,19 @@ func handleAIAgentCommands(input string) bool {
 	// Check for agent status command
 	if input == "agent status" {
 		fmt.Printf("Current AI agent: %s\n", cmd.AgentMgr.GetActiveAgentName())
+		if usage, ok := cmd.AgentMgr.ContextUsage(); ok {
+			fmt.Printf("Model: %s (context window %d tokens)\n", usage.Model, usage.Window)
+			fmt.Printf("Context: ~%d of %d tokens (%d%%), %d messages, compacted %d times\n",
+				usage.Used, usage.Budget, usage.Used*100/max(usage.Budget, 1), usage.Messages, usage.Compactions)
+		}
+		return true
+	}
+
+	// Check for context compaction command
+	if input == "agent compact" {
+		if err := cmd.AgentMgr.CompactContext(); err != nil {
+			fmt.Printf("Error compacting context: %v\n", err)
+		}
 		return true
 	}
 
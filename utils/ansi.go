@@ -3,6 +3,9 @@ package utils
 import (
 	"aurora-agent/config"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strings"
 )
 
@@ -23,25 +26,80 @@ func ProcessANSICodes(text string) string {
 
 // processAnsiBuffer processes ANSI codes in the buffer and prints them
 func ProcessAnsiBuffer(ansiBuffer string) string {
+	return processAnsiBufferTo(os.Stdout, ansiBuffer)
+}
+
+// processAnsiBufferTo processes ANSI codes in the buffer and writes them to the writer
+func processAnsiBufferTo(writer io.Writer, ansiBuffer string) string {
 	if config.AnsiPattern.MatchString(ansiBuffer) {
 		// Buffer has ANSI code, process it
 		processedBuffer := ProcessANSICodes(ansiBuffer)
-		fmt.Print(processedBuffer)
+		fmt.Fprint(writer, processedBuffer)
 		return ""
 	} else if config.AnsiStartPattern.MatchString(ansiBuffer) && len(ansiBuffer) > 100 {
 		// If buffer contains the start of an ANSI code, but not the end
 		// and buffer length is more than 100, process it
 		// This can happen when ANSI code is in incorrect format
 		processedBuffer := ProcessANSICodes(ansiBuffer)
-		fmt.Print(processedBuffer)
+		fmt.Fprint(writer, processedBuffer)
 		return ""
 	} else if len(ansiBuffer) > 80 && !config.AnsiStartPattern.MatchString(ansiBuffer) {
 		// If buffer length is more than 80 and no ANSI code start is found,
 		// process it
 		processedBuffer := ProcessANSICodes(ansiBuffer)
-		fmt.Print(processedBuffer)
+		fmt.Fprint(writer, processedBuffer)
 		return ""
 	}
 
 	return ansiBuffer
 }
+
+// AnsiStreamPrinter prints streamed text without splitting escaped ANSI codes
+type AnsiStreamPrinter struct {
+	writer io.Writer
+	buffer string
+}
+
+// NewAnsiStreamPrinter creates a printer that writes to the writer
+func NewAnsiStreamPrinter(writer io.Writer) *AnsiStreamPrinter {
+	return &AnsiStreamPrinter{writer: writer}
+}
+
+// Print adds streamed text to the buffer and prints it once it is safe to do so
+func (p *AnsiStreamPrinter) Print(text string) {
+	p.buffer += text
+	p.buffer = processAnsiBufferTo(p.writer, p.buffer)
+}
+
+// Flush prints the remaining buffer
+func (p *AnsiStreamPrinter) Flush() {
+	if p.buffer != "" {
+		fmt.Fprint(p.writer, ProcessANSICodes(p.buffer))
+		p.buffer = ""
+	}
+}
+
+// terminalEscapePattern matches the escape sequences programs write to terminals:
+// CSI sequences like colors and cursor movement, OSC sequences like window titles
+// and hyperlinks, single character escapes, and a sequence cut off at the end
+var terminalEscapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-~]|\x1b[\[\]]?[^\x1b]{0,16}$`)
+
+// StripTerminalCodes turns terminal output into plain text: escape sequences are
+// removed, CRLF line endings become LF and of lines rewritten with a carriage
+// return, like progress bars, only the last version is kept
+func StripTerminalCodes(text string) string {
+	text = terminalEscapePattern.ReplaceAllString(text, "")
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if index := strings.LastIndex(line, "\r"); index >= 0 {
+			line = line[index+1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
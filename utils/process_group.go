@@ -0,0 +1,28 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// SetProcessGroup starts a command in its own process group, so Ctrl+C in the
+// terminal doesn't reach it. Cancelling the context of a command created with
+// exec.CommandContext kills the processes it started too.
+func SetProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmd.Cancel != nil {
+		cmd.Cancel = func() error {
+			return KillProcessGroup(cmd)
+		}
+	}
+}
+
+// KillProcessGroup kills a command started with SetProcessGroup and its children
+func KillProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os/exec"
+)
+
+// SetProcessGroup does nothing on Windows, cancelling a command kills only the command
+func SetProcessGroup(cmd *exec.Cmd) {
+}
+
+// KillProcessGroup kills the command, the processes it started keep running
+func KillProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
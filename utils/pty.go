@@ -2,8 +2,11 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/creack/pty"
 )
@@ -41,3 +44,120 @@ func RunCommandWithPTY(cmd *exec.Cmd) {
 	// Clear activeCmd after process completes
 	ActiveCmd = nil
 }
+
+// outputDrainDelay is how long the output of a finished command is still read,
+// background children can keep the terminal open forever
+const outputDrainDelay = time.Second
+
+// DisablePagers makes programs like git and man print their output instead of
+// opening a pager, which would wait for key presses nobody makes
+func DisablePagers(cmd *exec.Cmd) {
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "PAGER=cat", "GIT_PAGER=cat", "MANPAGER=cat", "SYSTEMD_PAGER=")
+}
+
+// RunCommandStreaming runs a command, shows its output live on the terminal and
+// copies stdout and stderr to the given writers. Both streams are connected to
+// their own PTY so programs keep their colors; where PTYs are not available
+// pipes are used instead. Pagers are disabled.
+func RunCommandStreaming(cmd *exec.Cmd, stdout io.Writer, stderr io.Writer) error {
+	DisablePagers(cmd)
+
+	stdoutWriter := io.MultiWriter(os.Stdout, stdout)
+	stderrWriter := io.MultiWriter(os.Stderr, stderr)
+
+	outPty, outTty, err := pty.Open()
+	if err != nil {
+		cmd.Stdout = stdoutWriter
+		cmd.Stderr = stderrWriter
+		return cmd.Run()
+	}
+	defer outPty.Close()
+	errPty, errTty, err := pty.Open()
+	if err != nil {
+		outTty.Close()
+		cmd.Stdout = stdoutWriter
+		cmd.Stderr = stderrWriter
+		return cmd.Run()
+	}
+	defer errPty.Close()
+
+	// Use the size of the user's terminal so output is wrapped like it would be there
+	pty.InheritSize(os.Stdin, outPty)
+	pty.InheritSize(os.Stdin, errPty)
+
+	cmd.Stdout = outTty
+	cmd.Stderr = errTty
+	err = cmd.Start()
+	// The child has its own copies now, reads end once it closes them
+	outTty.Close()
+	errTty.Close()
+	if err != nil {
+		return err
+	}
+
+	output := &detachableWriter{}
+	var wg sync.WaitGroup
+	for _, stream := range []struct {
+		pty    *os.File
+		writer io.Writer
+	}{{outPty, stdoutWriter}, {errPty, stderrWriter}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Reading fails with EIO when the last process closes the terminal
+			io.Copy(output.to(stream.writer), stream.pty)
+		}()
+	}
+
+	err = cmd.Wait()
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(outputDrainDelay):
+		// Reads of a PTY can't be interrupted, the readers end with the
+		// background children and whatever they write is dropped
+		output.detach()
+	}
+	return err
+}
+
+// detachableWriter passes writes on until it is detached, then it drops them
+type detachableWriter struct {
+	mu       sync.Mutex
+	detached bool
+}
+
+// to returns a writer that writes to w while the detachableWriter is attached
+func (d *detachableWriter) to(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.detached {
+			return len(p), nil
+		}
+		return w.Write(p)
+	})
+}
+
+// detach drops all later writes
+func (d *detachableWriter) detach() {
+	d.mu.Lock()
+	d.detached = true
+	d.mu.Unlock()
+}
+
+// writerFunc turns a function into an io.Writer
+type writerFunc func(p []byte) (int, error)
+
+// Write implements io.Writer
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}